@@ -0,0 +1,387 @@
+package ylog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultW3CFields are the fields of the W3C extended log format logged by default.
+var DefaultW3CFields = []string{"date", "time", "c-ip", "cs-username", "cs-method", "cs-uri-stem",
+	"cs-uri-query", "sc-status", "sc-bytes", "time-taken", "cs(User-Agent)", "cs(Referer)"}
+
+// CommonLogFormat and CombinedLogFormat are the access log formats of the same names of
+// the Apache HTTP server, see AccessLogMiddlewareWithFormat.
+const (
+	CommonLogFormat   = `%h %l %u %t "%r" %>s %b`
+	CombinedLogFormat = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`
+)
+
+// AccessLogMiddleware returns an http.Handler calling next and logging each request to l
+// at INFO level once it is served, in the Common Log Format, such as
+//
+//	127.0.0.1 - bob [02/Jan/2024:15:04:05 +0800] "GET /index.html HTTP/1.1" 200 2326
+//
+// Use a logger without header, see SetFlags and Lnologlevel, to log the lines as is.
+func AccessLogMiddleware(l Logger, next http.Handler) http.Handler {
+	return AccessLogMiddlewareWithFormat(l, CommonLogFormat, next)
+}
+
+// AccessLogMiddlewareWithFormat is like AccessLogMiddleware but logs the requests in the
+// given format, whose directives are those of the LogFormat of the Apache HTTP server:
+//
+//	%h, %a	address of the client
+//	%l	remote logname, always "-"
+//	%u	user name given by the client
+//	%t	time the request was received, in the "[02/Jan/2006:15:04:05 -0700]" format
+//	%{layout}t	time the request was received, in the given time.Format layout
+//	%r	request line
+//	%m, %U, %q, %H	method, path, query string ("?" included) and protocol of the request
+//	%s, %>s	status of the response
+//	%b, %B	size of the response body, "-" or "0" if none
+//	%D, %T	time taken to serve the request, in microseconds and seconds
+//	%{Header}i, %{Header}o	header of the request and of the response
+//	%%	percent sign
+//
+// "-" is logged for the other directives and the empty values. The quotes and
+// backslashes of the values are escaped.
+func AccessLogMiddlewareWithFormat(l Logger, format string, next http.Handler) http.Handler {
+	tokens := parseAccessLogFormat(format)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &accessLogWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+		logAccess(l, tokens, &accessLogRequest{
+			r:      r,
+			header: w.Header(),
+			status: rw.code(),
+			size:   rw.size,
+			start:  start,
+			taken:  time.Since(start),
+		})
+	})
+}
+
+// RecoveryMiddleware returns an http.Handler calling next and recovering from its panics,
+// which are logged to l at ERROR level along with the stack trace and turned into an
+// Internal Server Error response if none was written yet. The http.ErrAbortHandler
+// panics aborting the responses are not recovered.
+func RecoveryMiddleware(l Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &accessLogWriter{ResponseWriter: w}
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				logPanic(l, r, v)
+				if rw.status == 0 {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// logPanic logs to l the panic v of the handler of r, with the stack trace.
+func logPanic(l Logger, r *http.Request, v interface{}) {
+	outputTo(l, 3, ERROR, fmt.Sprintf("panic serving %s %s: %v\n%s", r.Method, r.RequestURI, v, debug.Stack()))
+}
+
+// accessLogRequest is a served request logged to the access log.
+type accessLogRequest struct {
+	r      *http.Request
+	header http.Header // header of the response
+	status int
+	size   int64
+	start  time.Time
+	taken  time.Duration
+}
+
+// logAccess logs req to l at INFO level, as the line made of tokens.
+func logAccess(l Logger, tokens []accessLogToken, req *accessLogRequest) {
+	var b []byte
+	for _, token := range tokens {
+		b = token(b, req)
+	}
+	outputTo(l, 3, INFO, string(b))
+}
+
+// accessLogToken appends a part of the access log line of req to b.
+type accessLogToken func(b []byte, req *accessLogRequest) []byte
+
+// parseAccessLogFormat parses the access log format into the tokens of the lines.
+func parseAccessLogFormat(format string) []accessLogToken {
+	var tokens []accessLogToken
+	literal := func(s string) {
+		if s != "" {
+			tokens = append(tokens, func(b []byte, _ *accessLogRequest) []byte {
+				return append(b, s...)
+			})
+		}
+	}
+	value := func(f func(req *accessLogRequest) string) {
+		tokens = append(tokens, func(b []byte, req *accessLogRequest) []byte {
+			return appendAccessLogString(b, f(req))
+		})
+	}
+
+	for {
+		i := strings.IndexByte(format, '%')
+		if i < 0 || i == len(format)-1 {
+			literal(format)
+			return tokens
+		}
+		literal(format[:i])
+		format = format[i+1:]
+		var arg string
+		if format[0] == '>' || format[0] == '<' {
+			// the status of the final response is the one of the original request
+			format = format[1:]
+		} else if format[0] == '{' {
+			if j := strings.IndexByte(format, '}'); j > 0 {
+				arg, format = format[1:j], format[j+1:]
+			}
+		}
+		if format == "" {
+			return tokens
+		}
+		verb := format[0]
+		format = format[1:]
+
+		switch verb {
+		case '%':
+			literal("%")
+		case 'h', 'a':
+			value(func(req *accessLogRequest) string { return remoteHost(req.r) })
+		case 'u':
+			value(func(req *accessLogRequest) string { return username(req.r) })
+		case 't':
+			layout := "[02/Jan/2006:15:04:05 -0700]"
+			if arg != "" {
+				layout = arg
+			}
+			tokens = append(tokens, func(b []byte, req *accessLogRequest) []byte {
+				return req.start.AppendFormat(b, layout)
+			})
+		case 'r':
+			value(func(req *accessLogRequest) string {
+				return req.r.Method + " " + req.r.RequestURI + " " + req.r.Proto
+			})
+		case 'm':
+			value(func(req *accessLogRequest) string { return req.r.Method })
+		case 'U':
+			value(func(req *accessLogRequest) string { return req.r.URL.Path })
+		case 'q':
+			tokens = append(tokens, func(b []byte, req *accessLogRequest) []byte {
+				if req.r.URL.RawQuery == "" {
+					return b
+				}
+				return appendAccessLogString(append(b, '?'), req.r.URL.RawQuery)
+			})
+		case 'H':
+			value(func(req *accessLogRequest) string { return req.r.Proto })
+		case 's':
+			tokens = append(tokens, func(b []byte, req *accessLogRequest) []byte {
+				return strconv.AppendInt(b, int64(req.status), 10)
+			})
+		case 'b', 'B':
+			tokens = append(tokens, func(b []byte, req *accessLogRequest) []byte {
+				if req.size == 0 && verb == 'b' {
+					return append(b, '-')
+				}
+				return strconv.AppendInt(b, req.size, 10)
+			})
+		case 'D':
+			tokens = append(tokens, func(b []byte, req *accessLogRequest) []byte {
+				return strconv.AppendInt(b, int64(req.taken/time.Microsecond), 10)
+			})
+		case 'T':
+			tokens = append(tokens, func(b []byte, req *accessLogRequest) []byte {
+				return strconv.AppendInt(b, int64(req.taken/time.Second), 10)
+			})
+		case 'i':
+			value(func(req *accessLogRequest) string { return req.r.Header.Get(arg) })
+		case 'o':
+			value(func(req *accessLogRequest) string { return req.header.Get(arg) })
+		default:
+			literal("-")
+		}
+	}
+}
+
+// appendAccessLogString appends s to b as an access log value of
+// AccessLogMiddlewareWithFormat, escaping the quotes and backslashes.
+func appendAccessLogString(b []byte, s string) []byte {
+	s = accessLogValue(s)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b = append(b, '\\')
+		}
+		b = append(b, s[i])
+	}
+	return b
+}
+
+// W3CAccessLogMiddleware is like AccessLogMiddleware but logs the requests in the W3C
+// extended log format, with the given fields, DefaultW3CFields if nil. The supported
+// fields are date, time, c-ip, cs-username, cs-method, cs-uri, cs-uri-stem, cs-uri-query,
+// cs-version, sc-status, sc-bytes, time-taken and cs(Header) for the request headers,
+// "-" is logged for the other ones. Log to a RotateLogger whose preamble is
+// W3CPreamble(fields), so that each log file starts with the #Fields directive.
+func W3CAccessLogMiddleware(l Logger, fields []string, next http.Handler) http.Handler {
+	if fields == nil {
+		fields = DefaultW3CFields
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &accessLogWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+		taken := time.Since(start)
+
+		var b []byte
+		for i, field := range fields {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			var v string
+			switch field {
+			case "date":
+				v = start.UTC().Format("2006-01-02")
+			case "time":
+				v = start.UTC().Format("15:04:05")
+			case "c-ip":
+				v = remoteHost(r)
+			case "cs-username":
+				v = username(r)
+			case "cs-method":
+				v = r.Method
+			case "cs-uri":
+				v = r.RequestURI
+			case "cs-uri-stem":
+				v = r.URL.Path
+			case "cs-uri-query":
+				v = r.URL.RawQuery
+			case "cs-version":
+				v = r.Proto
+			case "sc-status":
+				v = strconv.Itoa(rw.code())
+			case "sc-bytes":
+				v = strconv.FormatInt(rw.size, 10)
+			case "time-taken":
+				v = strconv.FormatFloat(taken.Seconds(), 'f', 3, 64)
+			default:
+				if strings.HasPrefix(field, "cs(") && strings.HasSuffix(field, ")") {
+					v = r.Header.Get(field[3 : len(field)-1])
+				}
+			}
+			b = append(b, accessLogValue(strings.Replace(v, " ", "+", -1))...)
+		}
+		outputTo(l, 2, INFO, string(b))
+	})
+}
+
+// W3CPreamble returns a preamble of the log files, see RotateWriter.SetPreamble, made
+// of the directives of the W3C extended log format with the given fields,
+// DefaultW3CFields if nil.
+func W3CPreamble(fields []string) func(now time.Time) string {
+	if fields == nil {
+		fields = DefaultW3CFields
+	}
+	directive := "#Fields: " + strings.Join(fields, " ") + "\n"
+	return func(now time.Time) string {
+		return "#Version: 1.0\n#Date: " + now.UTC().Format("2006-01-02 15:04:05") + "\n" + directive
+	}
+}
+
+// accessLogWriter is an http.ResponseWriter recording the status and size of the response.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush flushes the response if the underlying http.ResponseWriter is an http.Flusher.
+func (w *accessLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection if the underlying http.ResponseWriter is an http.Hijacker.
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.status = http.StatusSwitchingProtocols
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http.ResponseWriter is not an http.Hijacker")
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// code returns the status of the response.
+func (w *accessLogWriter) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// remoteHost returns the address of the client without port.
+func remoteHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// username returns the user name given by the client, if any.
+func username(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	if r.URL.User != nil {
+		return r.URL.User.Username()
+	}
+	return ""
+}
+
+// accessLogValue returns v as an access log value: "-" if it is empty, without the
+// control characters which could forge lines.
+func accessLogValue(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, v)
+}
@@ -0,0 +1,97 @@
+package ylog
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lnologlevel)
+	h := AccessLogMiddleware(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "created")
+	}))
+
+	r := httptest.NewRequest("POST", "/orders?id=42", nil)
+	r.SetBasicAuth("bob", "secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	re := `^192\.0\.2\.1 - bob \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [-+]\d{4}\] "POST /orders\?id=42 HTTP/1\.1" 201 7` + "\n$"
+	if !regexp.MustCompile(re).MatchString(buf.String()) {
+		t.Fatalf("logged %q, want a Common Log Format line", buf.String())
+	}
+}
+
+func TestAccessLogMiddlewareWithFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lnologlevel)
+	format := `%a %{2006}t %m %U%q %>s %B "%{User-Agent}i" %{Content-Type}o %{X-Missing}i %x 100%%`
+	h := AccessLogMiddlewareWithFormat(l, format, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+	}))
+
+	r := httptest.NewRequest("GET", "/search?q=a", nil)
+	r.Header.Set("User-Agent", `curl "7.0"`)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	re := `^192\.0\.2\.1 \d{4} GET /search\?q=a 200 0 "curl \\"7\.0\\"" text/plain - - 100%` + "\n$"
+	if !regexp.MustCompile(re).MatchString(buf.String()) {
+		t.Fatalf("logged %q, want a line in %q", buf.String(), format)
+	}
+}
+
+func TestW3CAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lnologlevel)
+	fields := []string{"date", "c-ip", "cs-method", "cs-uri-stem", "cs-uri-query", "sc-status", "sc-bytes", "cs(User-Agent)", "s-sitename"}
+	h := W3CAccessLogMiddleware(l, fields, http.NotFoundHandler())
+
+	r := httptest.NewRequest("GET", "/missing", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 (X11)")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	re := `^\d{4}-\d{2}-\d{2} 192\.0\.2\.1 GET /missing - 404 19 Mozilla/5\.0\+\(X11\) -` + "\n$"
+	if !regexp.MustCompile(re).MatchString(buf.String()) {
+		t.Fatalf("logged %q, want a W3C extended log format line", buf.String())
+	}
+
+	preamble := W3CPreamble(fields)(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC))
+	want := "#Version: 1.0\n#Date: 2024-01-02 15:04:05\n#Fields: date c-ip cs-method cs-uri-stem cs-uri-query sc-status sc-bytes cs(User-Agent) s-sitename\n"
+	if preamble != want {
+		t.Fatalf("preamble %q, want %q", preamble, want)
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	h := RecoveryMiddleware(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(buf.String(), "ERROR|") || !strings.Contains(buf.String(), "panic serving GET /panic: boom\n") ||
+		!strings.Contains(buf.String(), "goroutine ") {
+		t.Fatalf("logged %q, want the panic and its stack trace", buf.String())
+	}
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", v)
+		}
+	}()
+	RecoveryMiddleware(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
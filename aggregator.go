@@ -0,0 +1,143 @@
+package ylog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// aggregatorFingerprints is the max number of fingerprints counted by an Aggregator in a
+// window, the others are not listed in the top.
+const aggregatorFingerprints = 1024
+
+// aggregate counts the entries of a fingerprint.
+type aggregate struct {
+	n       uint64
+	message string // message of the first entry
+}
+
+// Aggregator counts the entries of a logger by log level, and the WARN, ERROR and FATAL
+// ones by fingerprint, see AlertFingerprint, over a window and logs a summary of each
+// window at INFO level, such as
+//
+//	summary=1m0s|last 1m0s: 12034 INFO, 48 WARN, 3 ERROR (top: 40x "cache miss 12", 3x "order 42 failed")
+//
+// as a cheap health signal where there are no metrics. The entries are counted by
+// Intercept, see AddInterceptor, but the summaries, told by their "summary" field. Call
+// Close to stop it.
+type Aggregator struct {
+	l      Logger        // logger of the summaries
+	window time.Duration // duration of the windows
+	top    int           // number of fingerprints listed in the summaries
+	done   chan struct{} // closed by Close
+	once   sync.Once     // closes done
+
+	mu     sync.Mutex // protects the following fields
+	counts [statsLevels]uint64
+	prints map[string]*aggregate
+}
+
+// NewAggregator returns an Aggregator logging to l the summary of the entries counted
+// every window, with the top fingerprints.
+func NewAggregator(l Logger, window time.Duration, top int) *Aggregator {
+	a := &Aggregator{l: l, window: window, top: top, done: make(chan struct{}), prints: make(map[string]*aggregate)}
+	go a.run()
+	return a
+}
+
+func (a *Aggregator) run() {
+	ticker := time.NewTicker(a.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			a.Summarize()
+		}
+	}
+}
+
+// Intercept counts e and keeps it: it is an Interceptor, see AddInterceptor.
+func (a *Aggregator) Intercept(e *Entry) bool {
+	for _, f := range e.Fields {
+		if f.Key == "summary" {
+			return true
+		}
+	}
+	i := e.Level - noLevel
+	if i < 0 || i >= statsLevels {
+		return true
+	}
+	var fingerprint string
+	if e.Level >= WARN && e.Level != INFO {
+		fingerprint = AlertFingerprint(e)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.counts[i]++
+	if fingerprint != "" {
+		if p, ok := a.prints[fingerprint]; ok {
+			p.n++
+		} else if len(a.prints) < aggregatorFingerprints {
+			a.prints[fingerprint] = &aggregate{n: 1, message: e.Message}
+		}
+	}
+	return true
+}
+
+// Summarize logs the summary of the entries counted since the last one and resets the
+// counts. It is called every window.
+func (a *Aggregator) Summarize() {
+	a.mu.Lock()
+	counts, prints := a.counts, a.prints
+	a.counts, a.prints = [statsLevels]uint64{}, make(map[string]*aggregate)
+	a.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "last %s: ", a.window)
+	n := 0
+	for _, level := range []LogLevel{noLevel, TRACE, DEBUG, INFO, WARN, ERROR, FATAL} {
+		if c := counts[level-noLevel]; c > 0 {
+			if n > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%d %s", c, statsLevelName(level))
+			n++
+		}
+	}
+	if n == 0 {
+		b.WriteString("no entry")
+	}
+	if len(prints) > 0 && a.top > 0 {
+		top := make([]*aggregate, 0, len(prints))
+		for _, p := range prints {
+			top = append(top, p)
+		}
+		sort.Slice(top, func(i, j int) bool {
+			if top[i].n != top[j].n {
+				return top[i].n > top[j].n
+			}
+			return top[i].message < top[j].message
+		})
+		if len(top) > a.top {
+			top = top[:a.top]
+		}
+		b.WriteString(" (top: ")
+		for i, p := range top {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%dx %q", p.n, p.message)
+		}
+		b.WriteByte(')')
+	}
+	outputFieldsTo(a.l, 1, INFO, b.String(), []Field{{"summary", a.window.String()}})
+}
+
+// Close stops logging the summaries.
+func (a *Aggregator) Close() {
+	a.once.Do(func() { close(a.done) })
+}
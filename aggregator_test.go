@@ -0,0 +1,40 @@
+package ylog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregator(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+	a := NewAggregator(l, time.Hour, 1)
+	defer a.Close()
+	l.AddInterceptor(a.Intercept)
+
+	l.Info("started")
+	l.Infof("served")
+	l.Warnf("cache miss %d", 12)
+	l.Errorf("order %d failed", 42)
+	l.Errorf("order %d failed", 43)
+	buf.Reset()
+	a.Summarize()
+	a.Summarize()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		`INFO|summary=1h0m0s|last 1h0m0s: 2 INFO, 1 WARN, 2 ERROR (top: 2x "order 42 failed")`,
+		`INFO|summary=1h0m0s|last 1h0m0s: no entry`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("logged %q, want %q", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("logged %q, want %q", lines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package ylog
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AlertQueueSize is the number of alerts queued by an AlertHook, the alerts are dropped
+// while its queue is full.
+const AlertQueueSize = 64
+
+// alertFatalTimeout is the max duration an AlertHook waits for the delivery of a FATAL
+// entry, before the program exits.
+var alertFatalTimeout = 5 * time.Second
+
+// ErrRateLimited is returned by the AlertSenders dropping an alert to stay under a rate
+// limit, the AlertHook counts it as dropped rather than failed.
+var ErrRateLimited = errors.New("ylog: alert rate limited")
+
+// Alert is an entry forwarded to an alerting service by an AlertHook.
+type Alert struct {
+	Entry
+	Stack       []runtime.Frame // stack of the caller, innermost first
+	Fingerprint string          // identifies the alerts of the same condition, see AlertFingerprint
+}
+
+// AlertSender sends alerts to an alerting service, e.g. SentrySender. It is called by
+// one goroutine at a time.
+type AlertSender interface {
+	SendAlert(a *Alert) error
+}
+
+// alertItem is an alert queued by an AlertHook, or a marker closing flushed once the
+// previous alerts are sent.
+type alertItem struct {
+	alert   *Alert
+	flushed chan struct{}
+}
+
+// AlertHook forwards the entries of a logger from a log level to an AlertSender, so
+// that the errors reach an alerting service without separate instrumentation, e.g.
+//
+//	hook := ylog.NewAlertHook(sender, ylog.ERROR)
+//	l.AddInterceptor(hook.Intercept)
+//
+// The alerts are sent by a goroutine, but the FATAL ones, which Intercept waits for
+// since the program exits after them. Call Close to stop it. Send the alerts through an
+// AlertThrottle to throttle them across the senders.
+type AlertHook struct {
+	sender  AlertSender
+	level   LogLevel
+	queue   chan alertItem
+	done    chan struct{} // closed by Close
+	once    sync.Once     // closes done
+	stopped chan struct{} // closed once the goroutine returns
+	dropped uint64        // number of alerts dropped, accessed atomically
+	err     atomic.Value  // errorHolder, error of the last alert sent
+}
+
+// NewAlertHook returns an AlertHook sending the entries of level and above to sender.
+// INFO entries are never sent, being informational despite their order, and FATAL
+// entries always are.
+func NewAlertHook(sender AlertSender, level LogLevel) *AlertHook {
+	h := &AlertHook{
+		sender:  sender,
+		level:   level,
+		queue:   make(chan alertItem, AlertQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go h.run()
+	return h
+}
+
+func (h *AlertHook) run() {
+	defer close(h.stopped)
+	for {
+		select {
+		case <-h.done:
+			return
+		case item := <-h.queue:
+			if item.flushed != nil {
+				close(item.flushed)
+				continue
+			}
+			err := h.sender.SendAlert(item.alert)
+			if err == ErrRateLimited {
+				atomic.AddUint64(&h.dropped, 1)
+				err = nil
+			}
+			h.err.Store(errorHolder{err})
+		}
+	}
+}
+
+// Intercept queues an alert for e if its log level is alerted, and keeps it: it is an
+// Interceptor, see AddInterceptor. It captures the stack of the caller of the logger.
+func (h *AlertHook) Intercept(e *Entry) bool {
+	if e.Level != FATAL && (e.Level < h.level || e.Level >= INFO) {
+		return true
+	}
+	a := &Alert{Entry: *e, Stack: callerStack(e.File, e.Line), Fingerprint: AlertFingerprint(e)}
+	a.Fields = append([]Field(nil), e.Fields...)
+	select {
+	case <-h.done:
+		atomic.AddUint64(&h.dropped, 1)
+		return true
+	default:
+	}
+	if e.Level == FATAL {
+		// the program exits once the entry is written
+		timer := time.NewTimer(alertFatalTimeout)
+		defer timer.Stop()
+		select {
+		case h.queue <- alertItem{alert: a}:
+			h.flush(timer.C)
+		case <-timer.C:
+			atomic.AddUint64(&h.dropped, 1)
+		}
+		return true
+	}
+	select {
+	case h.queue <- alertItem{alert: a}:
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+	}
+	return true
+}
+
+// Flush waits for the alerts queued so far to be sent and returns the error of the last
+// one sent.
+func (h *AlertHook) Flush() error {
+	h.flush(nil)
+	return h.Err()
+}
+
+// flush waits for the alerts queued so far to be sent, or for timeout.
+func (h *AlertHook) flush(timeout <-chan time.Time) {
+	flushed := make(chan struct{})
+	select {
+	case h.queue <- alertItem{flushed: flushed}:
+	case <-h.stopped:
+		return
+	case <-timeout:
+		return
+	}
+	select {
+	case <-flushed:
+	case <-h.stopped:
+	case <-timeout:
+	}
+}
+
+// Err returns the error of the last alert sent.
+func (h *AlertHook) Err() error {
+	eh, _ := h.err.Load().(errorHolder)
+	return eh.err
+}
+
+// Dropped returns the number of alerts dropped while the queue was full, the hook was
+// closed, or by the rate limits of the sender.
+func (h *AlertHook) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// Close sends the alerts queued and stops the hook, the next alerts are dropped. More
+// calls have no effect.
+func (h *AlertHook) Close() error {
+	h.Flush()
+	h.once.Do(func() { close(h.done) })
+	<-h.stopped
+	return h.Err()
+}
+
+// AlertFingerprint returns the fingerprint of the alert of e: the value of its
+// "fingerprint" field if any, otherwise a hash of its function and its message whose
+// numbers are elided, so that the alerts of the same condition with different
+// identifiers are grouped.
+func AlertFingerprint(e *Entry) string {
+	for _, f := range e.Fields {
+		if f.Key == "fingerprint" {
+			return fmt.Sprint(f.Value)
+		}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(e.Func))
+	h.Write([]byte{0})
+	digits := false
+	for i := 0; i < len(e.Message); i++ {
+		c := e.Message[i]
+		if '0' <= c && c <= '9' {
+			if !digits {
+				h.Write([]byte{'#'})
+			}
+			digits = true
+			continue
+		}
+		digits = false
+		h.Write([]byte{c})
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// callerStack returns the stack of the current goroutine from the frame at file and line,
+// the caller of the logger, or the whole stack if it is not found.
+func callerStack(file string, line int) []runtime.Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []runtime.Frame
+	found := false
+	for {
+		f, more := frames.Next()
+		if !found && f.File == file && f.Line == line {
+			// drop the frames of the logger
+			stack, found = stack[:0], true
+		}
+		stack = append(stack, f)
+		if !more {
+			return stack
+		}
+	}
+}
+
+// alertCooldown drops the alerts of a fingerprint for a duration after one is allowed.
+type alertCooldown struct {
+	mu   sync.Mutex           // protects the following field
+	last map[string]time.Time // time of the last alert allowed by fingerprint
+}
+
+// allow reports whether the alert with fingerprint is allowed at now, for a cool-down d.
+func (c *alertCooldown) allow(fingerprint string, now time.Time, d time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.last[fingerprint]; ok && now.Sub(t) < d {
+		return false
+	}
+	if c.last == nil {
+		c.last = make(map[string]time.Time)
+	} else if len(c.last) >= 1024 {
+		// forget the fingerprints out of their cool-down
+		for k, t := range c.last {
+			if now.Sub(t) >= d {
+				delete(c.last, k)
+			}
+		}
+	}
+	c.last[fingerprint] = now
+	return true
+}
@@ -0,0 +1,65 @@
+package ylog
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// alertRecorder is an AlertSender recording the alerts.
+type alertRecorder struct {
+	mu     sync.Mutex
+	alerts []*Alert
+}
+
+func (r *alertRecorder) SendAlert(a *Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func TestAlertHook(t *testing.T) {
+	var r alertRecorder
+	hook := NewAlertHook(&r, ERROR)
+	defer hook.Close()
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	l.AddInterceptor(hook.Intercept)
+
+	l.Warn("slow")
+	l.Info("started")
+	l.Errorf("order %d failed", 42)
+	l.Errorf("order %d failed", 43)
+	if err := hook.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.alerts) != 2 {
+		t.Fatalf("sent %d alerts, want 2", len(r.alerts))
+	}
+	a := r.alerts[0]
+	if a.Level != ERROR || a.Message != "order 42 failed" {
+		t.Fatalf("sent %v %q", a.Level, a.Message)
+	}
+	if len(a.Stack) == 0 || !strings.HasSuffix(a.Stack[0].Function, ".TestAlertHook") {
+		t.Fatalf("stack starts at %+v, want the caller", a.Stack)
+	}
+	if a.Fingerprint != r.alerts[1].Fingerprint {
+		t.Fatalf("fingerprints %q and %q differ", a.Fingerprint, r.alerts[1].Fingerprint)
+	}
+}
+
+func TestAlertFingerprint(t *testing.T) {
+	e := &Entry{Func: "main.f", Message: "order 42 failed"}
+	if got, want := AlertFingerprint(e), AlertFingerprint(&Entry{Func: "main.f", Message: "order 7 failed"}); got != want {
+		t.Fatalf("fingerprints %q and %q differ", got, want)
+	}
+	if got := AlertFingerprint(&Entry{Func: "main.g", Message: "order 42 failed"}); got == AlertFingerprint(e) {
+		t.Fatal("fingerprints of different functions are equal")
+	}
+	e.Fields = []Field{{"fingerprint", "orders"}}
+	if got := AlertFingerprint(e); got != "orders" {
+		t.Fatalf("fingerprint is %q, want the field", got)
+	}
+}
@@ -0,0 +1,129 @@
+package ylog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AlertThrottle is an AlertSender throttling the alerts before passing them to another
+// one, e.g. a MultiAlertSender of all the alerting services, so that a crash loop sends
+// one actionable notification rather than thousands:
+//   - the alerts of a fingerprint are dropped for a cool-down after one is passed,
+//   - at most a number of alerts are passed per hour,
+//   - once more alerts than a threshold come in a minute, a storm alert is passed in
+//     their place and the others are dropped until a minute without storm, after which
+//     the next alert carries the number of alerts dropped in the "suppressed" field.
+//
+// The alerts dropped are reported as ErrRateLimited.
+type AlertThrottle struct {
+	Sender         AlertSender   // sender of the alerts passed
+	Cooldown       time.Duration // cool-down of the fingerprints, none if zero
+	MaxPerHour     int           // max number of alerts passed per hour, unlimited if zero
+	StormThreshold int           // number of alerts per minute from which they are a storm, none if zero
+	Clock          Clock         // source of the time, SystemClock if nil
+
+	cooldown alertCooldown
+
+	mu         sync.Mutex // protects the following fields
+	hour       time.Time  // start of the current hour of MaxPerHour
+	passed     int        // number of alerts passed in the current hour
+	minute     time.Time  // start of the current minute of StormThreshold
+	received   int        // number of alerts received in the current minute
+	storm      bool       // whether the alerts are a storm
+	suppressed int        // number of alerts dropped since the last one passed
+}
+
+// SendAlert passes a to the sender unless it is throttled.
+func (t *AlertThrottle) SendAlert(a *Alert) error {
+	clock := t.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	now := clock.Now()
+	a, ok := t.throttle(a, now)
+	if !ok {
+		return ErrRateLimited
+	}
+	return t.Sender.SendAlert(a)
+}
+
+// throttle returns the alert to pass for a at now, false to drop it.
+func (t *AlertThrottle) throttle(a *Alert, now time.Time) (*Alert, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.StormThreshold > 0 {
+		if now.Sub(t.minute) >= time.Minute {
+			if t.received <= t.StormThreshold || now.Sub(t.minute) >= 2*time.Minute {
+				t.storm = false
+			}
+			t.minute, t.received = now, 0
+		}
+		t.received++
+		if t.storm {
+			t.suppressed++
+			return nil, false
+		}
+		if t.received > t.StormThreshold {
+			t.storm = true
+			storm := &Alert{Entry: a.Entry, Stack: a.Stack, Fingerprint: "ylog-alert-storm"}
+			storm.Message = fmt.Sprintf("alert storm: more than %d alerts in a minute, the next ones are suppressed; last: %s", t.StormThreshold, a.Message)
+			return t.pass(storm, now, true), true
+		}
+	}
+	if t.Cooldown > 0 && !t.cooldown.allow(a.Fingerprint, now, t.Cooldown) {
+		t.suppressed++
+		return nil, false
+	}
+	if a = t.pass(a, now, false); a == nil {
+		t.suppressed++
+		return nil, false
+	}
+	return a, true
+}
+
+// pass returns a with the number of alerts suppressed if it is under MaxPerHour or
+// forced, otherwise nil.
+func (t *AlertThrottle) pass(a *Alert, now time.Time, force bool) *Alert {
+	if now.Sub(t.hour) >= time.Hour {
+		t.hour, t.passed = now, 0
+	}
+	if t.MaxPerHour > 0 && t.passed >= t.MaxPerHour && !force {
+		return nil
+	}
+	t.passed++
+	if t.suppressed > 0 {
+		b := *a
+		b.Fields = append(append([]Field(nil), a.Fields...), Field{"suppressed", t.suppressed})
+		a, t.suppressed = &b, 0
+	}
+	return a
+}
+
+// MultiAlertSender returns an AlertSender passing the alerts to all the senders. It
+// returns the first error but ErrRateLimited, which is returned if all of them do.
+func MultiAlertSender(senders ...AlertSender) AlertSender {
+	return multiAlertSender(append([]AlertSender(nil), senders...))
+}
+
+type multiAlertSender []AlertSender
+
+func (m multiAlertSender) SendAlert(a *Alert) error {
+	var err error
+	limited := 0
+	for _, s := range m {
+		switch e := s.SendAlert(a); e {
+		case nil:
+		case ErrRateLimited:
+			limited++
+		default:
+			if err == nil {
+				err = e
+			}
+		}
+	}
+	if err == nil && limited > 0 && limited == len(m) {
+		return ErrRateLimited
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package ylog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertThrottle(t *testing.T) {
+	var r alertRecorder
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)}
+	th := &AlertThrottle{Sender: &r, Cooldown: 10 * time.Minute, MaxPerHour: 3, Clock: clock}
+	send := func(fingerprint string) error {
+		return th.SendAlert(&Alert{Entry: Entry{Level: ERROR, Message: fingerprint}, Fingerprint: fingerprint})
+	}
+
+	if err := send("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("a"); err != ErrRateLimited {
+		t.Fatalf("passed in the cool-down: %v", err)
+	}
+	send("b")
+	send("c")
+	if err := send("d"); err != ErrRateLimited {
+		t.Fatalf("passed above the max per hour: %v", err)
+	}
+	clock.Add(time.Hour)
+	if err := send("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.alerts) != 4 {
+		t.Fatalf("passed %d alerts, want 4", len(r.alerts))
+	}
+	for _, i := range []int{1, 3} {
+		if a := r.alerts[i]; len(a.Fields) != 1 || a.Fields[0] != (Field{"suppressed", 1}) {
+			t.Fatalf("passed %+v, want the number of alerts suppressed", a.Fields)
+		}
+	}
+}
+
+func TestAlertThrottleStorm(t *testing.T) {
+	var r alertRecorder
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)}
+	th := &AlertThrottle{Sender: &r, StormThreshold: 3, Clock: clock}
+	for i := 0; i < 100; i++ {
+		th.SendAlert(&Alert{Entry: Entry{Level: FATAL, Message: fmt.Sprint("crash ", i)}, Fingerprint: fmt.Sprint(i)})
+		clock.Add(100 * time.Millisecond)
+	}
+	if len(r.alerts) != 4 || !strings.HasPrefix(r.alerts[3].Message, "alert storm:") {
+		t.Fatalf("passed %d alerts, want 3 and a storm alert", len(r.alerts))
+	}
+
+	// the storm ends after a calm minute
+	clock.Add(2 * time.Minute)
+	if err := th.SendAlert(&Alert{Entry: Entry{Level: ERROR, Message: "calm"}}); err != nil {
+		t.Fatal(err)
+	}
+	if last := r.alerts[4]; last.Message != "calm" || last.Fields[0] != (Field{"suppressed", 96}) {
+		t.Fatalf("passed %q %+v", last.Message, last.Fields)
+	}
+}
+
+func TestMultiAlertSender(t *testing.T) {
+	var r1, r2 alertRecorder
+	s := MultiAlertSender(&r1, &AlertThrottle{Sender: &r2, Cooldown: time.Hour})
+	a := &Alert{Fingerprint: "a"}
+	if err := s.SendAlert(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendAlert(a); err != nil {
+		t.Fatalf("returned %v while a sender passed the alert", err)
+	}
+	if len(r1.alerts) != 2 || len(r2.alerts) != 1 {
+		t.Fatalf("passed %d and %d alerts", len(r1.alerts), len(r2.alerts))
+	}
+}
@@ -0,0 +1,328 @@
+package ylog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrDrained is returned by the writes to a drained AsyncWriter.
+var ErrDrained = errors.New("ylog: writer drained")
+
+// asyncItem is a write, or a flush request if flushed is not nil, queued in an AsyncWriter.
+type asyncItem struct {
+	p       []byte
+	flushed chan struct{}
+	written chan struct{} // closed once the write is performed if not nil
+}
+
+// AsyncWriter is an io.WriteCloser which queues the writes and performs them
+// to another io.Writer in background, so that logging does not wait for slow
+// destinations. Once the queue is full, writes block until there is room, or
+// are dropped if SetDropOnFull is set, except the entries of the log levels set
+// by SetNonDroppable.
+type AsyncWriter struct {
+	out          io.Writer      // destination of output, only used by the background goroutine
+	queue        chan asyncItem // pending writes
+	done         chan struct{}  // closed once the background goroutine exits
+	dropped      uint64         // number of dropped writes, accessed atomically
+	drop         int32          // non zero to drop the writes when the queue is full, accessed atomically
+	nonDroppable uint32         // bit set of the non droppable log levels, accessed atomically
+	closing      int32          // non zero once Drain is called, accessed atomically
+	writeErrors  uint64         // number of failed writes, accessed atomically
+	err          atomic.Value   // errorHolder, error of the last failed write
+	highMark     int64          // max number of queued writes, accessed atomically
+	watermark    atomic.Value   // watermarkHolder, see SetWatermark
+	aboveMark    int32          // non zero while the queue is above the watermark, accessed atomically
+
+	mu      sync.RWMutex // protects the following fields
+	drained bool         // no longer accepts writes
+}
+
+// NewAsyncWriter returns an AsyncWriter writing to out with a queue of queueSize writes.
+func NewAsyncWriter(out io.Writer, queueSize int) *AsyncWriter {
+	w := &AsyncWriter{
+		out:   out,
+		queue: make(chan asyncItem, queueSize),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for item := range w.queue {
+		if item.flushed != nil {
+			if f, ok := w.out.(Flusher); ok {
+				f.Flush()
+			}
+			close(item.flushed)
+			continue
+		}
+		// record error, the caller is long gone
+		if _, err := w.out.Write(item.p); err != nil {
+			atomic.AddUint64(&w.writeErrors, 1)
+			w.err.Store(errorHolder{err})
+		}
+		putAsyncBuffer(item.p)
+		if item.written != nil {
+			close(item.written)
+		}
+		if h, _ := w.watermark.Load().(watermarkHolder); h.fn != nil && len(w.queue) < h.mark {
+			atomic.StoreInt32(&w.aboveMark, 0)
+		}
+	}
+	if f, ok := w.out.(Flusher); ok {
+		f.Flush()
+	}
+}
+
+// errorHolder holds an error in an atomic.Value, which requires a consistent concrete type.
+type errorHolder struct {
+	err error
+}
+
+// watermarkHolder holds the watermark of the queue of an AsyncWriter in an atomic.Value.
+type watermarkHolder struct {
+	mark int
+	fn   func(depth int)
+}
+
+var asyncBufferPool = sync.Pool{
+	New: func() interface{} {
+		return make([]byte, 0, DEFAULT_BUFFER_SIZE)
+	},
+}
+
+func getAsyncBuffer(p []byte) []byte {
+	b := asyncBufferPool.Get().([]byte)
+	return append(b[:0], p...)
+}
+
+func putAsyncBuffer(b []byte) {
+	// don't retain large buffers
+	if cap(b) <= DEFAULT_BUFFER_SIZE {
+		asyncBufferPool.Put(b[:0])
+	}
+}
+
+// Write queues a copy of p, it returns ErrDrained once the writer is drained.
+// The error of the underlying write is not reported, see Err.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(noLevel, p)
+}
+
+// WriteLevel is like Write for an entry with level. If level is non droppable, see
+// SetNonDroppable, it waits for the write to be performed.
+func (w *AsyncWriter) WriteLevel(level LogLevel, p []byte) (int, error) {
+	return w.WriteLevelContext(context.Background(), level, p)
+}
+
+// WriteLevelContext is like WriteLevel, but waits for room in the queue until ctx is
+// done, in which case the write is dropped and ctx.Err() is returned, so that the callers
+// on a deadline are not stalled by the backpressure. The writes of the non droppable log
+// levels still wait for room.
+func (w *AsyncWriter) WriteLevelContext(ctx context.Context, level LogLevel, p []byte) (int, error) {
+	written, _, err := w.queueLevel(ctx, level, p, true)
+	if err != nil {
+		return 0, err
+	}
+	if written != nil {
+		<-written
+	}
+	return len(p), nil
+}
+
+// queueLevel queues a copy of p for an entry with level, waiting for room until ctx is
+// done unless level is non droppable. If level is non droppable, it returns a channel
+// closed once the write is performed. If wait is false, it returns queued false rather
+// than waiting for room, and the write is not dropped.
+func (w *AsyncWriter) queueLevel(ctx context.Context, level LogLevel, p []byte, wait bool) (written <-chan struct{}, queued bool, err error) {
+	if atomic.LoadInt32(&w.closing) != 0 {
+		return nil, true, ErrDrained
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.drained {
+		return nil, true, ErrDrained
+	}
+
+	item := asyncItem{p: getAsyncBuffer(p)}
+	nonDroppable := level >= 0 && atomic.LoadUint32(&w.nonDroppable)&(1<<uint(level)) != 0
+	if nonDroppable {
+		item.written = make(chan struct{})
+	}
+	if !wait && (nonDroppable || atomic.LoadInt32(&w.drop) == 0) {
+		select {
+		case w.queue <- item:
+		default:
+			putAsyncBuffer(item.p)
+			return nil, false, nil
+		}
+		w.queued()
+		return item.written, true, nil
+	}
+	switch {
+	case nonDroppable:
+		w.queue <- item
+		w.queued()
+		return item.written, true, nil
+	case atomic.LoadInt32(&w.drop) != 0:
+		select {
+		case w.queue <- item:
+		default:
+			atomic.AddUint64(&w.dropped, 1)
+			putAsyncBuffer(item.p)
+		}
+	default:
+		select {
+		case w.queue <- item:
+		case <-ctx.Done():
+			atomic.AddUint64(&w.dropped, 1)
+			putAsyncBuffer(item.p)
+			return nil, true, ctx.Err()
+		}
+	}
+	w.queued()
+	return nil, true, nil
+}
+
+// queued updates the high watermark and calls the watermark function once the queue
+// reaches the watermark.
+func (w *AsyncWriter) queued() {
+	depth := int64(len(w.queue))
+	for {
+		high := atomic.LoadInt64(&w.highMark)
+		if depth <= high || atomic.CompareAndSwapInt64(&w.highMark, high, depth) {
+			break
+		}
+	}
+	if h, _ := w.watermark.Load().(watermarkHolder); h.fn != nil && int(depth) >= h.mark &&
+		atomic.CompareAndSwapInt32(&w.aboveMark, 0, 1) {
+		h.fn(int(depth))
+	}
+}
+
+// SetDropOnFull sets whether to drop the writes when the queue is full instead of blocking.
+func (w *AsyncWriter) SetDropOnFull(drop bool) {
+	var v int32
+	if drop {
+		v = 1
+	}
+	atomic.StoreInt32(&w.drop, v)
+}
+
+// SetNonDroppable sets the log levels whose entries are never dropped, e.g. ERROR and
+// FATAL: their writes block until there is room in the queue, then wait for the write
+// to be performed, so that they are written even if the program exits right after.
+// The writer knows the levels of the entries output by a WriterLogger, see LevelWriter.
+func (w *AsyncWriter) SetNonDroppable(levels ...LogLevel) {
+	var bits uint32
+	for _, level := range levels {
+		if level >= 0 {
+			bits |= 1 << uint(level)
+		}
+	}
+	atomic.StoreUint32(&w.nonDroppable, bits)
+}
+
+// SetWatermark sets the function called when the number of queued writes reaches mark,
+// e.g. to alert or scale before the writes are dropped. It is called once each time the
+// queue grows to mark from below, with the number of queued writes, by the goroutine
+// writing: it must return quickly and not write to w. Give nil to remove it.
+func (w *AsyncWriter) SetWatermark(mark int, fn func(depth int)) {
+	w.watermark.Store(watermarkHolder{mark, fn})
+	atomic.StoreInt32(&w.aboveMark, 0)
+}
+
+// QueueDepth returns the number of queued writes.
+func (w *AsyncWriter) QueueDepth() int {
+	return len(w.queue)
+}
+
+// QueueCapacity returns the max number of queued writes, given to NewAsyncWriter.
+func (w *AsyncWriter) QueueCapacity() int {
+	return cap(w.queue)
+}
+
+// HighWatermark returns the max number of queued writes so far.
+func (w *AsyncWriter) HighWatermark() int {
+	return int(atomic.LoadInt64(&w.highMark))
+}
+
+// Dropped returns the number of writes dropped because the queue was full, or their
+// context was done while waiting for room.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// WriteErrors returns the number of failed writes to the underlying writer.
+func (w *AsyncWriter) WriteErrors() uint64 {
+	return atomic.LoadUint64(&w.writeErrors)
+}
+
+// Err returns the error of the last failed write to the underlying writer, if any.
+func (w *AsyncWriter) Err() error {
+	h, _ := w.err.Load().(errorHolder)
+	return h.err
+}
+
+// Flush waits for the writes queued so far to be performed, then flushes the
+// underlying writer if it is a Flusher.
+func (w *AsyncWriter) Flush() error {
+	w.mu.RLock()
+	if w.drained {
+		w.mu.RUnlock()
+		<-w.done
+		return nil
+	}
+	flushed := make(chan struct{})
+	w.queue <- asyncItem{flushed: flushed}
+	w.mu.RUnlock()
+
+	<-flushed
+	return nil
+}
+
+// Drain stops accepting writes and waits for the queued ones to be performed, or for
+// ctx to be done, in which case the remaining writes are still performed in background
+// and ctx.Err() is returned.
+func (w *AsyncWriter) Drain(ctx context.Context) error {
+	// refuse the new writes at once, the queued ones may be waiting for room
+	atomic.StoreInt32(&w.closing, 1)
+	closed := make(chan struct{})
+	go func() {
+		w.mu.Lock()
+		if !w.drained {
+			w.drained = true
+			close(w.queue)
+		}
+		w.mu.Unlock()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close drains the writer, then closes the underlying writer if it is an io.Closer, but
+// for os.Stdout and os.Stderr.
+func (w *AsyncWriter) Close() error {
+	w.Drain(context.Background())
+	if c, ok := w.out.(io.Closer); ok && !isStdStream(w.out) {
+		return c.Close()
+	}
+	return nil
+}
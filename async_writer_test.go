@@ -0,0 +1,262 @@
+package ylog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter is a goroutine safe writer taking a while for each write.
+type slowWriter struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *slowWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterDrain(t *testing.T) {
+	out := &slowWriter{delay: time.Millisecond}
+	l := NewWriterLogger(NewAsyncWriter(out, 16), TRACE)
+	l.SetFlags(Lloglevel)
+
+	for i := 0; i < 10; i++ {
+		l.Info("entry")
+	}
+	if err := l.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count([]byte(out.String()), []byte("INFO|entry\n")); n != 10 {
+		t.Fatalf("%d entries written after Drain, want 10", n)
+	}
+	if err := l.Output(1, "late"); err != ErrDrained {
+		t.Fatalf("Output after Drain returned %v, want ErrDrained", err)
+	}
+}
+
+func TestAsyncWriterDrainTimeout(t *testing.T) {
+	out := &slowWriter{delay: 20 * time.Millisecond}
+	w := NewAsyncWriter(out, 16)
+	for i := 0; i < 5; i++ {
+		w.Write([]byte("entry\n"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Drain returned %v, want context.DeadlineExceeded", err)
+	}
+	w.Close()
+	if got := out.String(); got != "entry\nentry\nentry\nentry\nentry\n" {
+		t.Fatalf("written %q after Close", got)
+	}
+}
+
+func TestAsyncWriterDropOnFull(t *testing.T) {
+	out := &slowWriter{delay: 10 * time.Millisecond}
+	w := NewAsyncWriter(out, 1)
+	w.SetDropOnFull(true)
+	for i := 0; i < 10; i++ {
+		w.Write([]byte("entry\n"))
+	}
+	w.Flush()
+	if w.Dropped() == 0 {
+		t.Fatal("no write dropped with a full queue")
+	}
+	w.Close()
+}
+
+func TestAsyncWriterNonDroppable(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	out := &slowWriter{delay: time.Millisecond}
+	w := NewAsyncWriter(out, 1)
+	w.SetDropOnFull(true)
+	w.SetNonDroppable(ERROR, FATAL)
+	l := NewWriterLogger(w, TRACE)
+	l.SetFlags(Lloglevel)
+
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 5; j++ {
+			l.Debug("noise")
+		}
+		l.Errorf("failure %d", i)
+	}
+	// the errors are written before Errorf returns
+	s := out.String()
+	for i := 0; i < 20; i++ {
+		if want := fmt.Sprintf("ERROR|failure %d\n", i); !strings.Contains(s, want) {
+			t.Fatalf("%q dropped", want)
+		}
+	}
+	if w.Dropped() == 0 {
+		t.Fatal("no DEBUG entry dropped")
+	}
+	w.Close()
+}
+
+func TestAsyncWriterDrainBlockedWrites(t *testing.T) {
+	out := &slowWriter{delay: 200 * time.Millisecond}
+	w := NewAsyncWriter(out, 1)
+	defer w.Close()
+	// the writes beyond the queue and the one in progress block
+	for i := 0; i < 4; i++ {
+		go w.Write([]byte("entry\n"))
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := w.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Drain returned %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Fatalf("Drain returned after %v, past its deadline", d)
+	}
+	if _, err := w.Write([]byte("late\n")); err != ErrDrained {
+		t.Fatalf("Write during Drain returned %v, want ErrDrained", err)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestAsyncWriterErrors(t *testing.T) {
+	w := NewAsyncWriter(failingWriter{}, 16)
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("entry\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	if n, err := w.WriteErrors(), w.Err(); n != 3 || err == nil || err.Error() != "disk full" {
+		t.Fatalf("WriteErrors() = %d, Err() = %v, want 3 and disk full", n, err)
+	}
+	w.Close()
+}
+
+// gatedWriter is a writer whose writes wait for release, started receives the writes.
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	return len(p), nil
+}
+
+func TestAsyncWriterWatermark(t *testing.T) {
+	out := &gatedWriter{started: make(chan struct{}, 16), release: make(chan struct{})}
+	w := NewAsyncWriter(out, 4)
+	var depths []int
+	w.SetWatermark(3, func(depth int) {
+		depths = append(depths, depth)
+	})
+
+	w.Write([]byte("blocked\n"))
+	<-out.started
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("queued\n"))
+	}
+	if d, c, h := w.QueueDepth(), w.QueueCapacity(), w.HighWatermark(); d != 4 || c != 4 || h != 4 {
+		t.Fatalf("queue depth %d, capacity %d, high watermark %d, want 4, 4, 4", d, c, h)
+	}
+	if len(depths) != 1 || depths[0] != 3 {
+		t.Fatalf("watermark crossed at %v, want once at 3", depths)
+	}
+
+	close(out.release)
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d, h := w.QueueDepth(), w.HighWatermark(); d != 0 || h != 4 {
+		t.Fatalf("queue depth %d, high watermark %d after Drain, want 0, 4", d, h)
+	}
+}
+
+func TestWriterLoggerOutputContext(t *testing.T) {
+	out := &gatedWriter{started: make(chan struct{}, 16), release: make(chan struct{})}
+	w := NewAsyncWriter(out, 1)
+	l := NewWriterLogger(w, TRACE)
+	l.Info("blocked")
+	<-out.started
+	l.Info("queued")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.OutputContext(ctx, 1, INFO, "dropped"); err != context.DeadlineExceeded {
+		t.Fatalf("OutputContext returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := w.Dropped(); n != 1 {
+		t.Fatalf("dropped %d writes, want 1", n)
+	}
+
+	close(out.release)
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterLoggerOutputContextConcurrent(t *testing.T) {
+	out := &gatedWriter{started: make(chan struct{}, 16), release: make(chan struct{})}
+	w := NewAsyncWriter(out, 1)
+	l := NewWriterLogger(w, TRACE)
+	l.Info("blocked")
+	<-out.started
+	l.Info("queued")
+
+	// waits for room without deadline
+	waiting := make(chan struct{})
+	go func() {
+		defer close(waiting)
+		l.Info("waiting")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- l.OutputContext(ctx, 1, INFO, "dropped") }()
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("OutputContext returned %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OutputContext waited for the other caller")
+	}
+
+	close(out.release)
+	<-waiting
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the first write was started already
+	if got, want := len(out.started)+1, 3; got != want {
+		t.Fatalf("wrote %d entries, want %d", got, want)
+	}
+}
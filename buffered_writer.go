@@ -0,0 +1,187 @@
+package ylog
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultFlushThreshold is the number of buffered bytes above which a BufferedWriter
+// flushes without waiting for its flush interval.
+const DefaultFlushThreshold = 256 * 1024
+
+// BufferedWriter is an io.WriteCloser buffering the writes to another io.Writer, so that
+// logging does not perform a system call per entry. The buffered bytes are written
+// periodically, and as soon as they exceed the flush threshold, bounding both the memory
+// used and the entries lost on a crash during bursts. The entries of the log levels set
+// by SetFlushLevels, ERROR and FATAL by default, are written at once. For example, the
+// global logger may buffer its output with:
+//
+//	ylog.SetDefaultLogger(ylog.NewWriterLogger(ylog.NewBufferedWriter(os.Stderr, time.Second), ylog.TRACE))
+type BufferedWriter struct {
+	out  io.Writer     // destination of output
+	done chan struct{} // closed by Close
+	once sync.Once     // closes done
+
+	mu        sync.Mutex // ensures atomic writes; protects the following fields
+	buf       []byte     // pending bytes
+	threshold int        // flush once len(buf) exceeds it
+	levels    uint32     // bit set of the log levels flushed at once
+	sync      bool       // sync the underlying writer after flushing these levels
+	err       error      // error of the last failed background flush, returned by the next call
+}
+
+// NewBufferedWriter returns a BufferedWriter writing to out every flushInterval, and
+// once more than DefaultFlushThreshold bytes are buffered, see SetFlushThreshold.
+// Give a non positive flushInterval to flush on the threshold and Flush only.
+func NewBufferedWriter(out io.Writer, flushInterval time.Duration) *BufferedWriter {
+	w := &BufferedWriter{out: out, done: make(chan struct{}), threshold: DefaultFlushThreshold,
+		levels: 1<<uint(ERROR) | 1<<uint(FATAL)}
+	if flushInterval > 0 {
+		go w.run(flushInterval)
+	}
+	return w
+}
+
+// NewBufferedWriterLogger returns a WriterLogger writing the entries from level to out
+// through a BufferedWriter flushed every flushPeriod, so that a network connection or a
+// pipe is not written per entry. Flush writes the buffered entries at once, and Close
+// writes them before closing out. The BufferedWriter is the Writer of the logger.
+func NewBufferedWriterLogger(out io.Writer, level LogLevel, flushPeriod time.Duration) *WriterLogger {
+	return NewWriterLogger(NewBufferedWriter(out, flushPeriod), level)
+}
+
+func (w *BufferedWriter) run(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.flush(); err != nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// SetFlushThreshold sets the number of buffered bytes above which the writer flushes
+// at once. Give a non positive threshold to flush every write.
+func (w *BufferedWriter) SetFlushThreshold(threshold int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.threshold = threshold
+}
+
+// SetFlushLevels sets the log levels whose entries are written at once rather than
+// buffered, such as the last error before a crash. The writer knows the levels of the
+// entries output by a WriterLogger, see LevelWriter.
+func (w *BufferedWriter) SetFlushLevels(levels ...LogLevel) {
+	var bits uint32
+	for _, level := range levels {
+		if level >= 0 {
+			bits |= 1 << uint(level)
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.levels = bits
+}
+
+// SetSync sets whether to sync the underlying writer, if it has a Sync method such as
+// os.File, after writing the entries of the flush levels, so that they are on disk.
+func (w *BufferedWriter) SetSync(sync bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sync = sync
+}
+
+// Buffered returns the number of buffered bytes.
+func (w *BufferedWriter) Buffered() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.buf)
+}
+
+// Write buffers p, and flushes the buffer if it then exceeds the flush threshold.
+func (w *BufferedWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(noLevel, p)
+}
+
+// WriteLevel is like Write for an entry with level. If level is a flush level, see
+// SetFlushLevels, it writes the buffer at once.
+func (w *BufferedWriter) WriteLevel(level LogLevel, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf, p...)
+	if level >= 0 && w.levels&(1<<uint(level)) != 0 {
+		if err := w.flush(); err != nil {
+			return len(p), err
+		}
+		if s, ok := w.out.(interface{ Sync() error }); ok && w.sync {
+			return len(p), s.Sync()
+		}
+	} else if len(w.buf) > w.threshold {
+		if err := w.flush(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the buffered bytes, then flushes the underlying writer if it is
+// a Flusher.
+func (w *BufferedWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if f, ok := w.out.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close stops the periodic flush, flushes the writer, then closes the underlying
+// writer if it is an io.Closer, but for os.Stdout and os.Stderr.
+func (w *BufferedWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	err := w.Flush()
+	if c, ok := w.out.(io.Closer); ok && !isStdStream(w.out) {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// flush writes the buffered bytes, w.mu must be held. The bytes are dropped on error,
+// so that a failing destination does not grow the buffer.
+func (w *BufferedWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+// takeErr returns and clears the error of the last failed background flush,
+// w.mu must be held.
+func (w *BufferedWriter) takeErr() error {
+	err := w.err
+	w.err = nil
+	return err
+}
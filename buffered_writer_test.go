@@ -0,0 +1,117 @@
+package ylog
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	out := &slowWriter{}
+	w := NewBufferedWriter(out, 0)
+	w.SetFlushThreshold(10)
+
+	w.Write([]byte("0123\n"))
+	w.Write([]byte("5678\n"))
+	if got := out.String(); got != "" || w.Buffered() != 10 {
+		t.Fatalf("wrote %q with %d bytes buffered, want all 10 bytes buffered", got, w.Buffered())
+	}
+	// above the threshold
+	w.Write([]byte("x\n"))
+	if got := out.String(); got != "0123\n5678\nx\n" || w.Buffered() != 0 {
+		t.Fatalf("wrote %q with %d bytes buffered, want all written", got, w.Buffered())
+	}
+
+	w.Write([]byte("closed\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "0123\n5678\nx\nclosed\n" {
+		t.Fatalf("wrote %q after Close", got)
+	}
+}
+
+func TestBufferedWriterInterval(t *testing.T) {
+	out := &slowWriter{}
+	w := NewBufferedWriter(out, 10*time.Millisecond)
+	defer w.Close()
+
+	w.Write([]byte("entry\n"))
+	for deadline := time.Now().Add(5 * time.Second); out.String() != "entry\n"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("wrote %q, want the entry flushed periodically", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// syncWriter counts the calls of Sync.
+type syncWriter struct {
+	slowWriter
+	syncs int
+}
+
+func (w *syncWriter) Sync() error {
+	w.syncs++
+	return nil
+}
+
+func TestBufferedWriterFlushLevels(t *testing.T) {
+	out := &syncWriter{}
+	w := NewBufferedWriter(out, 0)
+	defer w.Close()
+	w.SetSync(true)
+	l := NewWriterLogger(w, TRACE)
+	l.SetFlags(Lloglevel)
+
+	l.Warn("buffered")
+	l.Info("buffered")
+	if got := out.String(); got != "" {
+		t.Fatalf("wrote %q, want the entries buffered", got)
+	}
+	l.Error("crash")
+	if got := out.String(); got != "WARN|buffered\nINFO|buffered\nERROR|crash\n" || out.syncs != 1 {
+		t.Fatalf("wrote %q with %d syncs, want all the entries synced once", got, out.syncs)
+	}
+
+	w.SetFlushLevels(WARN)
+	l.Warn("flushed")
+	l.Error("buffered")
+	if got := out.String(); !strings.HasSuffix(got, "|crash\nWARN|flushed\n") || out.syncs != 2 {
+		t.Fatalf("wrote %q with %d syncs, want the WARN entry only synced", got, out.syncs)
+	}
+}
+
+func TestBufferedWriterLogger(t *testing.T) {
+	out := &slowWriter{}
+	l := NewBufferedWriterLogger(out, TRACE, 0)
+	l.SetFlags(Lnologlevel)
+	l.Info("buffered")
+	if got := out.String(); got != "" {
+		t.Fatalf("wrote %q before Flush", got)
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("closed")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "buffered\nclosed\n"; got != want {
+		t.Fatalf("wrote %q, want %q", got, want)
+	}
+}
+
+func TestBufferedWriterLoggerStderr(t *testing.T) {
+	l := NewBufferedWriterLogger(os.Stderr, ERROR, 0)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewAsyncWriter(os.Stderr, 1).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		t.Fatalf("stderr closed: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Command ylogen generates strongly typed logging functions from a schema of events, so
+// that the field names and types of an event are the same across a codebase, e.g.
+//
+//	//go:generate ylogen -schema events.json -o events_gen.go
+//
+// with events.json
+//
+//	{
+//	  "package": "events",
+//	  "events": [
+//	    {"name": "UserLoggedIn", "level": "INFO", "message": "user logged in",
+//	     "fields": [{"key": "user_id", "type": "int64"}, {"key": "ip", "type": "string"}]}
+//	  ]
+//	}
+//
+// generates
+//
+//	// UserLoggedIn logs "user logged in" at INFO level.
+//	func UserLoggedIn(l ylog.Logger, userID int64, ip string) {
+//		...
+//	}
+//
+// called as events.UserLoggedIn(log, userID, ip). The entries are logged on behalf of
+// the callers of the functions, with the fields in the order of the schema. The types of
+// the fields are string, bool, int, int32, int64, uint, uint32, uint64, float32,
+// float64, error, time.Duration and time.Time.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yplusplus/ylog"
+)
+
+// Schema is the schema of the events of a package.
+type Schema struct {
+	Package string  `json:"package"` // name of the generated package
+	Events  []Event `json:"events"`
+}
+
+// Event is the schema of an event, logged by the function of its name.
+type Event struct {
+	Name    string  `json:"name"`    // name of the function, an exported Go identifier
+	Doc     string  `json:"doc"`     // doc comment of the function, optional
+	Level   string  `json:"level"`   // log level name
+	Message string  `json:"message"` // message of the entries
+	Fields  []Field `json:"fields"`
+}
+
+// Field is the schema of a field of an event.
+type Field struct {
+	Key  string `json:"key"`  // key of the field, e.g. "user_id"
+	Type string `json:"type"` // Go type of the values
+}
+
+// fieldTypes are the types of the fields supported.
+var fieldTypes = map[string]bool{
+	"string":        true,
+	"bool":          true,
+	"int":           true,
+	"int32":         true,
+	"int64":         true,
+	"uint":          true,
+	"uint32":        true,
+	"uint64":        true,
+	"float32":       true,
+	"float64":       true,
+	"error":         true,
+	"time.Duration": true,
+	"time.Time":     true,
+}
+
+// initialisms are the words spelled in capitals in the parameter names.
+var initialisms = map[string]bool{
+	"api": true, "http": true, "id": true, "ip": true, "json": true, "sql": true,
+	"tcp": true, "tls": true, "udp": true, "uri": true, "url": true, "uuid": true,
+}
+
+func main() {
+	schemaPath := flag.String("schema", "", "path of the JSON schema of the events")
+	out := flag.String("o", "", "path of the generated file, stdout if empty")
+	flag.Parse()
+	if *schemaPath == "" {
+		fmt.Fprintln(os.Stderr, "ylogen: -schema is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	b, err := ioutil.ReadFile(*schemaPath)
+	if err != nil {
+		fail(err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		fail(fmt.Errorf("%s: %v", *schemaPath, err))
+	}
+	src, err := generate(&schema)
+	if err != nil {
+		fail(fmt.Errorf("%s: %v", *schemaPath, err))
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "ylogen:", err)
+	os.Exit(1)
+}
+
+// generate returns the formatted source of the functions of the events of schema.
+func generate(schema *Schema) ([]byte, error) {
+	if !token.IsIdentifier(schema.Package) {
+		return nil, fmt.Errorf("invalid package name %q", schema.Package)
+	}
+	var body bytes.Buffer
+	imports := make(map[string]bool) // standard packages imported
+	names := make(map[string]bool)
+	for _, ev := range schema.Events {
+		if !token.IsIdentifier(ev.Name) || !token.IsExported(ev.Name) {
+			return nil, fmt.Errorf("invalid event name %q, want an exported identifier", ev.Name)
+		}
+		if names[ev.Name] {
+			return nil, fmt.Errorf("duplicate event %s", ev.Name)
+		}
+		names[ev.Name] = true
+		level, ok := ylog.LogLevelMap[strings.ToUpper(ev.Level)]
+		if !ok {
+			return nil, fmt.Errorf("event %s: unknown log level %q", ev.Name, ev.Level)
+		}
+		if err := generateEvent(&body, &ev, level, imports); err != nil {
+			return nil, fmt.Errorf("event %s: %v", ev.Name, err)
+		}
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by ylogen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", schema.Package)
+	for _, path := range []string{"os", "time"} {
+		if imports[path] {
+			fmt.Fprintf(&src, "\t%q\n", path)
+		}
+	}
+	if imports["os"] || imports["time"] {
+		src.WriteString("\n")
+	}
+	src.WriteString("\t\"github.com/yplusplus/ylog\"\n)\n")
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
+
+// generateEvent writes the function of ev to w, and adds the packages it imports.
+func generateEvent(w *bytes.Buffer, ev *Event, level ylog.LogLevel, imports map[string]bool) error {
+	params := []string{"l ylog.Logger"}
+	fields := make([]string, 0, len(ev.Fields))
+	seen := map[string]bool{"l": true}
+	for _, f := range ev.Fields {
+		if f.Key == "" {
+			return fmt.Errorf("empty field key")
+		}
+		if !fieldTypes[f.Type] {
+			return fmt.Errorf("field %s: unsupported type %q", f.Key, f.Type)
+		}
+		if strings.HasPrefix(f.Type, "time.") {
+			imports["time"] = true
+		}
+		name := paramName(f.Key)
+		if seen[name] {
+			return fmt.Errorf("field %s: duplicate parameter %s", f.Key, name)
+		}
+		seen[name] = true
+		params = append(params, name+" "+f.Type)
+		fields = append(fields, fmt.Sprintf("ylog.Field{Key: %s, Value: %s}", strconv.Quote(f.Key), name))
+	}
+
+	doc := ev.Doc
+	if doc == "" {
+		doc = fmt.Sprintf("%s logs %s at %s level.", ev.Name, strconv.Quote(ev.Message), level.LogLevelName())
+	}
+	fmt.Fprintln(w)
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		fmt.Fprintf(w, "// %s\n", strings.TrimSpace(line))
+	}
+	fmt.Fprintf(w, "func %s(%s) {\n", ev.Name, strings.Join(params, ", "))
+	args := append([]string{"l", "2", "ylog." + level.LogLevelName(), strconv.Quote(ev.Message)}, fields...)
+	if level == ylog.FATAL {
+		imports["os"] = true
+		fmt.Fprintf(w, "\tylog.OutputFields(%s)\n\tos.Exit(1)\n}\n", strings.Join(args, ", "))
+		return nil
+	}
+	// spare boxing the fields of the disabled entries
+	fmt.Fprintf(w, "\tif ylog.Enabled(l, ylog.%s) {\n", level.LogLevelName())
+	fmt.Fprintf(w, "\t\tylog.OutputFields(%s)\n\t}\n}\n", strings.Join(args, ", "))
+	return nil
+}
+
+// paramName returns the name of the parameter of the field key, in camel case, e.g.
+// "userID" for "user_id".
+func paramName(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	})
+	var b strings.Builder
+	for i, w := range words {
+		lower := strings.ToLower(w)
+		if w == strings.ToUpper(w) {
+			// e.g. "USER"
+			w = lower
+		}
+		switch {
+		case i == 0 && initialisms[lower]:
+			b.WriteString(lower)
+		case i == 0:
+			b.WriteString(strings.ToLower(w[:1]) + w[1:])
+		case initialisms[lower]:
+			b.WriteString(strings.ToUpper(w))
+		default:
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	name := b.String()
+	if name == "" || '0' <= name[0] && name[0] <= '9' || token.Lookup(name).IsKeyword() {
+		if name != "" {
+			name = strings.ToUpper(name[:1]) + name[1:]
+		}
+		name = "v" + name
+	}
+	return name
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	schema := &Schema{Package: "events", Events: []Event{
+		{Name: "UserLoggedIn", Level: "INFO", Message: "user logged in", Fields: []Field{{"user_id", "int64"}, {"ip", "string"}}},
+		{Name: "RequestSlow", Level: "warn", Message: "slow request", Fields: []Field{{"took", "time.Duration"}}},
+	}}
+	src, err := generate(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// Code generated by ylogen. DO NOT EDIT.
+
+package events
+
+import (
+	"time"
+
+	"github.com/yplusplus/ylog"
+)
+
+// UserLoggedIn logs "user logged in" at INFO level.
+func UserLoggedIn(l ylog.Logger, userID int64, ip string) {
+	if ylog.Enabled(l, ylog.INFO) {
+		ylog.OutputFields(l, 2, ylog.INFO, "user logged in", ylog.Field{Key: "user_id", Value: userID}, ylog.Field{Key: "ip", Value: ip})
+	}
+}
+
+// RequestSlow logs "slow request" at WARN level.
+func RequestSlow(l ylog.Logger, took time.Duration) {
+	if ylog.Enabled(l, ylog.WARN) {
+		ylog.OutputFields(l, 2, ylog.WARN, "slow request", ylog.Field{Key: "took", Value: took})
+	}
+}
+`
+	if string(src) != want {
+		t.Fatalf("generated\n%s\nwant\n%s", src, want)
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	for _, ev := range []Event{
+		{Name: "userLoggedIn", Level: "INFO"},
+		{Name: "UserLoggedIn", Level: "NOTICE"},
+		{Name: "UserLoggedIn", Level: "INFO", Fields: []Field{{"user", "User"}}},
+		{Name: "UserLoggedIn", Level: "INFO", Fields: []Field{{"user-id", "int"}, {"user_id", "int"}}},
+	} {
+		if _, err := generate(&Schema{Package: "events", Events: []Event{ev}}); err == nil {
+			t.Errorf("generated %+v", ev)
+		}
+	}
+}
+
+func TestParamName(t *testing.T) {
+	for key, want := range map[string]string{
+		"user_id":     "userID",
+		"http.status": "httpStatus",
+		"RemoteAddr":  "remoteAddr",
+		"USER_NAME":   "userName",
+		"type":        "vType",
+		"2fa":         "v2fa",
+	} {
+		if got := paramName(key); got != want {
+			t.Errorf("paramName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package ylog
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Names of the options of the default logger, used as flag names by RegisterFlags
+// and as keys by configuration libraries such as viper.
+const (
+	OptionLogLevel     = "log-level"
+	OptionLogDir       = "log-dir"
+	OptionLogSizeLimit = "log-size-limit"
+	OptionLogMaxAge    = "log-max-age"
+	OptionLogMaxFiles  = "log-max-files"
+	OptionLogCompress  = "log-compress"
+
+	OptionLogSequenceWidth = "log-sequence-width"
+	OptionLogMode          = "log-mode"
+)
+
+// EnvLogMode is the environment variable of the mode of the default logger, used if
+// Options.Mode is empty, e.g. YLOG_MODE=stdout-json.
+const EnvLogMode = "YLOG_MODE"
+
+// Modes of the default logger, see Options.Mode.
+const (
+	ModeFiles      = "files"       // log to the files of the log dir, or to stderr if none
+	ModeStdoutJSON = "stdout-json" // log to stdout as JSON lines of the latest schema, see JSONEncoder
+)
+
+// Options configures the default logger, see Configure.
+type Options struct {
+	Level        string        `mapstructure:"log-level" json:"log-level"`           // log level name
+	LogDir       string        `mapstructure:"log-dir" json:"log-dir"`               // log dir, "" logs to stderr
+	LogSizeLimit Size          `mapstructure:"log-size-limit" json:"log-size-limit"` // log file size limit (Byte)
+	MaxAge       time.Duration `mapstructure:"log-max-age" json:"log-max-age"`       // max age of rotated log files
+	MaxFiles     int           `mapstructure:"log-max-files" json:"log-max-files"`   // max number of rotated log files
+	Compress     bool          `mapstructure:"log-compress" json:"log-compress"`     // compress rotated log files
+
+	SequenceWidth int    `mapstructure:"log-sequence-width" json:"log-sequence-width"` // min number of digits of the log file ids
+	Mode          string `mapstructure:"log-mode" json:"log-mode,omitempty"`           // ModeFiles or ModeStdoutJSON, EnvLogMode if empty
+}
+
+// DefaultOptions returns the default options, logging everything to stderr.
+func DefaultOptions() Options {
+	return Options{
+		Level:        TRACE.LogLevelName(),
+		LogSizeLimit: DEFAULT_LOG_FILE_SIZE,
+	}
+}
+
+// option usages shared by the flag registrations
+const (
+	usageLogLevel     = "log level: TRACE, DEBUG, WARN, ERROR, INFO or FATAL"
+	usageLogDir       = "directory of the log files, log to stderr if empty"
+	usageLogSizeLimit = "log file size limit, in bytes or with a unit as 512MB or 1GiB, non positive to disable splitting by size"
+	usageLogMaxAge    = "max age of the rotated log files, 0 to keep them"
+	usageLogMaxFiles  = "max number of the rotated log files, 0 to keep them"
+	usageLogCompress  = "compress the rotated log files with gzip"
+
+	usageLogSequenceWidth = "min number of digits of the ids of the log files of an hour, zero padded"
+	usageLogMode          = "log mode: files, or stdout-json to log to stdout as JSON lines, $" + EnvLogMode + " if empty"
+)
+
+// flagOptions holds the options set by the flags registered by RegisterFlags.
+var flagOptions = DefaultOptions()
+
+// RegisterFlags registers the options of the default logger as flags in fs,
+// call Init once they are parsed.
+func RegisterFlags(fs *flag.FlagSet) {
+	registerFlags(fs, func(p *Size, name, usage string) { fs.Var(p, name, usage) })
+}
+
+// flagSet is the part of flag.FlagSet and pflag.FlagSet registering the flags.
+type flagSet interface {
+	StringVar(p *string, name string, value string, usage string)
+	IntVar(p *int, name string, value int, usage string)
+	BoolVar(p *bool, name string, value bool, usage string)
+	DurationVar(p *time.Duration, name string, value time.Duration, usage string)
+}
+
+// registerFlags registers the options of the default logger as flags in fs, and the
+// sizes with sizeVar, as the Var methods of flag.FlagSet and pflag.FlagSet differ.
+func registerFlags(fs flagSet, sizeVar func(p *Size, name, usage string)) {
+	fs.StringVar(&flagOptions.Level, OptionLogLevel, flagOptions.Level, usageLogLevel)
+	fs.StringVar(&flagOptions.LogDir, OptionLogDir, flagOptions.LogDir, usageLogDir)
+	sizeVar(&flagOptions.LogSizeLimit, OptionLogSizeLimit, usageLogSizeLimit)
+	fs.DurationVar(&flagOptions.MaxAge, OptionLogMaxAge, flagOptions.MaxAge, usageLogMaxAge)
+	fs.IntVar(&flagOptions.MaxFiles, OptionLogMaxFiles, flagOptions.MaxFiles, usageLogMaxFiles)
+	fs.BoolVar(&flagOptions.Compress, OptionLogCompress, flagOptions.Compress, usageLogCompress)
+	fs.IntVar(&flagOptions.SequenceWidth, OptionLogSequenceWidth, flagOptions.SequenceWidth, usageLogSequenceWidth)
+	fs.StringVar(&flagOptions.Mode, OptionLogMode, flagOptions.Mode, usageLogMode)
+}
+
+// Init configures the default logger according to the flags registered by RegisterFlags.
+func Init() error {
+	return Configure(flagOptions)
+}
+
+// NewLogger returns a logger configured by o: a RotateLogger if o.LogDir is set,
+// otherwise a WriterLogger writing to stderr. In ModeStdoutJSON, from o.Mode or the
+// environment variable EnvLogMode, it is a WriterLogger writing the entries to stdout
+// as JSON lines whatever o.LogDir, so that the same program logs to files on hosts and
+// to stdout in containers.
+func NewLogger(o Options) (LevelLogger, error) {
+	level, ok := LogLevelMap[strings.ToUpper(o.Level)]
+	if !ok {
+		return nil, fmt.Errorf("ylog: unknown log level %q", o.Level)
+	}
+	mode := o.Mode
+	if mode == "" {
+		mode = strings.TrimSpace(os.Getenv(EnvLogMode))
+	}
+	switch strings.ToLower(mode) {
+	case "", ModeFiles:
+	case ModeStdoutJSON:
+		l := NewWriterLogger(os.Stdout, level)
+		l.SetEncoder(JSONEncoder{SchemaVersion: JSONSchemaLatest})
+		return l, nil
+	default:
+		return nil, fmt.Errorf("ylog: unknown log mode %q", mode)
+	}
+	if o.LogDir == "" {
+		return NewWriterLogger(os.Stderr, level), nil
+	}
+
+	l, err := NewRotateLogger(o.LogDir, level)
+	if err != nil {
+		return nil, err
+	}
+	l.SetLogSizeLimit(int64(o.LogSizeLimit))
+	l.SetMaxAge(o.MaxAge)
+	l.SetMaxFiles(o.MaxFiles)
+	l.SetCompress(o.Compress)
+	if err := l.SetSequenceWidth(o.SequenceWidth); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Configure sets the default logger to a logger configured by o, see NewLogger. The
+// replaced default logger is closed if it is an io.Closer, so that calling Configure
+// again does not leak its files; the error of closing it is returned.
+func Configure(o Options) error {
+	l, err := NewLogger(o)
+	if err != nil {
+		return err
+	}
+	prev := DefaultLogger()
+	SetDefaultLogger(l)
+	if c, ok := prev.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
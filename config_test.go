@@ -0,0 +1,143 @@
+package ylog
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := DefaultLogger()
+	defer SetDefaultLogger(old)
+	defer func() { flagOptions = DefaultOptions() }()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs)
+	err = fs.Parse([]string{"-log-level", "warn", "-log-dir", dir, "-log-size-limit", "1KiB", "-log-max-age", "24h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, ok := DefaultLogger().(*RotateLogger)
+	if !ok {
+		t.Fatalf("default logger is %T, want *RotateLogger", DefaultLogger())
+	}
+	defer l.Close()
+	if l.LogLevel() != WARN || l.LogDir() != dir || l.LogSizeLimit() != 1024 {
+		t.Fatalf("default logger configured with level %v, dir %q, size limit %d",
+			l.LogLevel().LogLevelName(), l.LogDir(), l.LogSizeLimit())
+	}
+
+	if _, err := NewLogger(Options{Level: "VERBOSE"}); err == nil {
+		t.Fatal("NewLogger accepted an unknown log level")
+	}
+}
+
+func TestNewLoggerMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Setenv(EnvLogMode, "stdout-json")
+	l, err := NewLogger(Options{Level: "INFO", LogDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wl, ok := l.(*WriterLogger); !ok || wl.Writer() != os.Stdout {
+		t.Fatalf("NewLogger() in %s returned %T, want a WriterLogger to stdout", ModeStdoutJSON, l)
+	}
+
+	// the option takes precedence
+	l, err = NewLogger(Options{Level: "INFO", LogDir: dir, Mode: ModeFiles})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rl, ok := l.(*RotateLogger); !ok {
+		t.Fatalf("NewLogger() in %s returned %T, want a RotateLogger", ModeFiles, l)
+	} else {
+		rl.Close()
+	}
+
+	if _, err := NewLogger(Options{Level: "INFO", Mode: "stdout-xml"}); err == nil {
+		t.Fatal("NewLogger accepted an unknown mode")
+	}
+}
+
+func TestConfigureClosesReplaced(t *testing.T) {
+	defer SetDefaultLogger(DefaultLogger())
+	out := &closeBuffer{}
+	SetDefaultLogger(NewWriterLogger(out, INFO))
+	if err := Configure(Options{Level: "INFO"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.closes != 1 {
+		t.Fatalf("replaced logger closed %d times, want 1", out.closes)
+	}
+	// the logger to stderr does not close it
+	if err := Configure(Options{Level: "INFO"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		t.Fatalf("stderr closed: %v", err)
+	}
+}
+
+func TestConfigOf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rl, err := NewRotateLogger(dir, DEBUG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	rl.SetMaxAge(24 * time.Hour)
+	rl.SetCompress(true)
+	wl := NewWriterLogger(os.Stderr, WARN)
+	wl.SetFlags(Ltime | Lloglevel)
+	r := NewRouter(wl)
+	r.SetRoute("audit", rl)
+
+	want := Configuration{
+		Options: Options{
+			Level:        WARN.LogLevelName(),
+			LogDir:       dir,
+			LogSizeLimit: DEFAULT_LOG_FILE_SIZE,
+			MaxAge:       24 * time.Hour,
+			Compress:     true,
+		},
+		Flags: "Ltime|Lloglevel",
+		Sinks: []string{"stderr", "audit:files:" + dir},
+	}
+	if got := ConfigOf(r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ConfigOf returned %+v, want %+v", got, want)
+	}
+
+	rec := httptest.NewRecorder()
+	ConfigHandler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var got Configuration
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ConfigHandler responded %s, want %+v", rec.Body, want)
+	}
+}
@@ -0,0 +1,128 @@
+package ylog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// RequestIDHeader is the HTTP header carrying the request ID.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen is the max length of the request IDs accepted from the requests.
+const maxRequestIDLen = 64
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+	valuesKey
+)
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger if there is none.
+// The key/value pairs carried by ctx, see WithValues, are prefixed to the content
+// logged through the returned logger.
+func FromContext(ctx context.Context) Logger {
+	l, ok := ctx.Value(loggerKey).(Logger)
+	if !ok {
+		l = DefaultLogger()
+	}
+	if keyvals := values(ctx); len(keyvals) > 0 {
+		l = newPrefixLogger(l, formatValues(keyvals))
+	}
+	return l
+}
+
+// WithValues returns a copy of ctx carrying the key/value pairs in addition to the
+// ones already carried by ctx. Every content logged through the logger returned by
+// FromContext for the returned context or its descendants is prefixed with the pairs,
+// which correlates the content logged by layers sharing a context but not a logger.
+func WithValues(ctx context.Context, keyvals ...interface{}) context.Context {
+	if len(keyvals) == 0 {
+		return ctx
+	}
+	old := values(ctx)
+	kvs := make([]interface{}, 0, len(old)+len(keyvals))
+	kvs = append(kvs, old...)
+	kvs = append(kvs, keyvals...)
+	if len(kvs)%2 != 0 {
+		kvs = append(kvs, "")
+	}
+	return context.WithValue(ctx, valuesKey, kvs)
+}
+
+// values returns the key/value pairs carried by ctx.
+func values(ctx context.Context) []interface{} {
+	kvs, _ := ctx.Value(valuesKey).([]interface{})
+	return kvs
+}
+
+// formatValues formats key/value pairs as "key1=value1|key2=value2|".
+func formatValues(keyvals []interface{}) string {
+	var buf []byte
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		buf = append(buf, fmt.Sprint(keyvals[i])...)
+		buf = append(buf, '=')
+		buf = append(buf, fmt.Sprint(keyvals[i+1])...)
+		buf = append(buf, '|')
+	}
+	return string(buf)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// RequestIDMiddleware returns an http.Handler which stores l in the context of each
+// request before calling next, along with the "request_id" value, see FromContext.
+// The request ID is taken from the RequestIDHeader of the request or generated if
+// absent or invalid, and is also set as the RequestIDHeader of the response. A valid
+// request ID has at most 64 letters, digits and characters among "-._:".
+func RequestIDMiddleware(l Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = WithValues(NewContext(ctx, l), "request_id", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID reports whether id is a valid request ID, which cannot alter the log
+// lines and the response headers it is written to.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '.', c == '_', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random request ID of 16 hex digits.
+func newRequestID() string {
+	var b [8]byte
+	// ignore error, an all zero ID is still usable
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
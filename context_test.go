@@ -0,0 +1,70 @@
+package ylog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lshortfile | Lloglevel)
+
+	h := RequestIDMiddleware(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handled")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get(RequestIDHeader); got != "abc" {
+		t.Fatalf("response request ID = %q, want abc", got)
+	}
+	got := buf.String()
+	if !strings.HasPrefix(got, "context_test.go:") || !strings.HasSuffix(got, "|INFO|request_id=abc|handled\n") {
+		t.Fatalf("logged %q", got)
+	}
+
+	buf.Reset()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	id := rec.Header().Get(RequestIDHeader)
+	if len(id) != 16 || !strings.Contains(buf.String(), "|request_id="+id+"|") {
+		t.Fatalf("generated request ID %q, logged %q", id, buf.String())
+	}
+
+	for _, forged := range []string{"abc|level=FATAL", "abc\nforged line", strings.Repeat("a", 65)} {
+		req = httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, forged)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if id := rec.Header().Get(RequestIDHeader); id == forged || len(id) != 16 {
+			t.Errorf("request ID %q replaced by %q, want a generated one", forged, id)
+		}
+	}
+}
+
+func TestWithValues(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lloglevel)
+
+	ctx := NewContext(context.Background(), l)
+	ctx = WithValues(ctx, "user", "bob")
+	child := WithValues(ctx, "order", 42, "dangling")
+
+	FromContext(ctx).Warnf("checkout %d", 1)
+	FromContext(child).Debug("paid")
+	want := "WARN|user=bob|checkout 1\nDEBUG|user=bob|order=42|dangling=|paid\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
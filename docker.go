@@ -0,0 +1,38 @@
+package ylog
+
+import "time"
+
+// DockerEncoder is an Encoder of the entries in the schema of the json-file logging
+// driver of Docker, so that the tools built around the log files of the containers
+// consume the output of ylog unchanged, e.g.
+//
+//	{"log":"ERROR|main.go:42|payment failed\n","stream":"stdout","time":"2024-01-02T15:04:05.123456Z"}
+//
+// The log lines are the entries in the text format of Flags, or encoded by Encoder if
+// it is set, e.g. JSONEncoder. The times are in UTC, as Docker writes them.
+type DockerEncoder struct {
+	Stream  string  // stream of the lines, "stdout" if empty
+	Flags   int     // properties of the header of the text format, e.g. Lloglevel|Lshortfile
+	Encoder Encoder // encoder of the log lines, the text format of Flags if nil
+}
+
+// Encode appends e to buf as a line of a json-file log.
+func (enc *DockerEncoder) Encode(buf []byte, e *Entry) []byte {
+	var line []byte
+	if enc.Encoder != nil {
+		line = enc.Encoder.Encode(nil, e)
+	} else {
+		formatEntry(&line, &textFormat{flags: enc.Flags}, e)
+	}
+	stream := enc.Stream
+	if stream == "" {
+		stream = "stdout"
+	}
+	buf = append(buf, `{"log":`...)
+	buf = appendJSONString(buf, string(line))
+	buf = append(buf, `,"stream":`...)
+	buf = appendJSONString(buf, stream)
+	buf = append(buf, `,"time":"`...)
+	buf = e.Time.UTC().AppendFormat(buf, time.RFC3339Nano)
+	return append(buf, "\"}\n"...)
+}
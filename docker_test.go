@@ -0,0 +1,41 @@
+package ylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDockerEncoder(t *testing.T) {
+	e := &Entry{
+		Time:    time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.FixedZone("CST", 8*3600)),
+		Level:   ERROR,
+		File:    "/src/app/main.go",
+		Line:    42,
+		Message: `payment "failed"`,
+		Fields:  []Field{{"order", 42}},
+	}
+	enc := &DockerEncoder{Flags: Lloglevel | Lshortfile}
+	want := `{"log":"main.go:42|ERROR|order=42|payment \"failed\"\n","stream":"stdout","time":"2024-01-02T07:04:05.123456Z"}` + "\n"
+	if got := string(enc.Encode(nil, e)); got != want {
+		t.Errorf("encoded %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetEncoder(&DockerEncoder{Stream: "stderr", Encoder: JSONEncoder{}})
+	l.Warn("disk almost full")
+	var line struct {
+		Log    string    `json:"log"`
+		Stream string    `json:"stream"`
+		Time   time.Time `json:"time"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	var inner entryJSON
+	if err := json.Unmarshal([]byte(line.Log), &inner); err != nil || inner.Message != "disk almost full" || line.Stream != "stderr" {
+		t.Errorf("logged %s, want a JSON entry on stderr", buf.Bytes())
+	}
+}
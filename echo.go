@@ -0,0 +1,64 @@
+//go:build ylog_echo
+// +build ylog_echo
+
+package ylog
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EchoAccessLog returns an echo middleware logging each request to l at INFO level once
+// it is served, in the given format, CommonLogFormat if empty, as
+// AccessLogMiddlewareWithFormat. The errors of the next handlers are given to the
+// HTTPErrorHandler before, so that the logged status is the one of the error response.
+//
+// It requires the ylog_echo build tag.
+func EchoAccessLog(l Logger, format string) echo.MiddlewareFunc {
+	if format == "" {
+		format = CommonLogFormat
+	}
+	tokens := parseAccessLogFormat(format)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			res := c.Response()
+			logAccess(l, tokens, &accessLogRequest{
+				r:      c.Request(),
+				header: res.Header(),
+				status: res.Status,
+				size:   res.Size,
+				start:  start,
+				taken:  time.Since(start),
+			})
+			return nil
+		}
+	}
+}
+
+// EchoRecovery returns an echo middleware recovering from the panics of the next
+// handlers, as RecoveryMiddleware, which are returned as errors to the HTTPErrorHandler.
+//
+// It requires the ylog_echo build tag.
+func EchoRecovery(l Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					if v == http.ErrAbortHandler {
+						panic(v)
+					}
+					logPanic(l, c.Request(), v)
+					err = fmt.Errorf("panic: %v", v)
+				}
+			}()
+			return next(c)
+		}
+	}
+}
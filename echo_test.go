@@ -0,0 +1,47 @@
+//go:build ylog_echo
+// +build ylog_echo
+
+package ylog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestEchoMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	e := echo.New()
+	e.Use(EchoAccessLog(l, `"%r" %>s`), EchoRecovery(l))
+	e.GET("/ok", func(c echo.Context) error { return c.String(http.StatusOK, "hello") })
+	e.GET("/missing", func(c echo.Context) error { return echo.ErrNotFound })
+	e.GET("/panic", func(c echo.Context) error { panic("boom") })
+
+	for _, tc := range []struct {
+		path   string
+		status int
+	}{
+		{"/ok", http.StatusOK},
+		{"/missing", http.StatusNotFound},
+		{"/panic", http.StatusInternalServerError},
+	} {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != tc.status {
+			t.Fatalf("%s: status %d, want %d", tc.path, w.Code, tc.status)
+		}
+		if want := fmt.Sprintf(`|"GET %s HTTP/1.1" %d`+"\n", tc.path, tc.status); !strings.HasSuffix(buf.String(), want) {
+			t.Fatalf("%s: logged %q, want the access log line", tc.path, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "panic serving GET /panic: boom") {
+		t.Fatalf("logged %q, want the panic", buf.String())
+	}
+}
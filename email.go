@@ -0,0 +1,113 @@
+package ylog
+
+import (
+	"bytes"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEmailInterval is the interval of the digests of an EmailSender by default.
+const DefaultEmailInterval = 5 * time.Minute
+
+// smtpSendMail sends the emails of the EmailSenders.
+var smtpSendMail = smtp.SendMail
+
+// EmailSender is an AlertSender of the alerts by email through an SMTP server, for the
+// internal tools without alerting stack. The alerts are batched into a digest sent an
+// interval after the first one, but the FATAL ones which send the digest at once, since
+// the program exits after them.
+type EmailSender struct {
+	Addr     string        // address of the SMTP server, e.g. "smtp.example.com:587"
+	Auth     smtp.Auth     // authentication to the server, none if nil
+	From     string        // sender address
+	To       []string      // recipient addresses
+	Subject  string        // prefix of the subjects, "[ylog]" if empty
+	Interval time.Duration // interval of the digests, DefaultEmailInterval if zero
+
+	mu      sync.Mutex  // protects the following fields
+	pending []*Alert    // alerts of the next digest
+	timer   *time.Timer // sends the next digest, nil if none is pending
+	err     error       // error of the last digest sent
+}
+
+// SendAlert adds a to the next digest, and sends it if a is FATAL.
+func (s *EmailSender) SendAlert(a *Alert) error {
+	s.mu.Lock()
+	s.pending = append(s.pending, a)
+	if a.Level != FATAL {
+		if s.timer == nil {
+			interval := s.Interval
+			if interval <= 0 {
+				interval = DefaultEmailInterval
+			}
+			s.timer = time.AfterFunc(interval, func() { s.Flush() })
+		}
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+	return s.Flush()
+}
+
+// Flush sends the pending alerts as a digest, if any, and returns the error of the last
+// digest sent.
+func (s *EmailSender) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.pending) == 0 {
+		return s.err
+	}
+	msg := s.digest(s.pending)
+	s.pending = nil
+	s.err = smtpSendMail(s.Addr, s.Auth, s.From, s.To, msg)
+	return s.err
+}
+
+// Err returns the error of the last digest sent.
+func (s *EmailSender) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// digest returns the email of the digest of alerts.
+func (s *EmailSender) digest(alerts []*Alert) []byte {
+	subject := s.Subject
+	if subject == "" {
+		subject = "[ylog]"
+	}
+	if len(alerts) == 1 {
+		subject += fmt.Sprintf(" %s %s", alerts[0].Level.LogLevelName(), alerts[0].Message)
+	} else {
+		subject += fmt.Sprintf(" %d alerts, first %s %s", len(alerts), alerts[0].Level.LogLevelName(), alerts[0].Message)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	for i, a := range alerts {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		fmt.Fprintf(&b, "%s %s %s\r\n", a.Time.Format("2006-01-02 15:04:05.000000"), a.Level.LogLevelName(), a.Message)
+		fmt.Fprintf(&b, "  at %s (%s:%d)\r\n", a.Func, a.File, a.Line)
+		for _, f := range a.Fields {
+			fmt.Fprintf(&b, "  %s=%v\r\n", f.Key, f.Value)
+		}
+		for _, f := range a.Stack {
+			fmt.Fprintf(&b, "    %s\r\n      %s:%d\r\n", f.Function, f.File, f.Line)
+		}
+	}
+	return b.Bytes()
+}
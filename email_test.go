@@ -0,0 +1,41 @@
+package ylog
+
+import (
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailSender(t *testing.T) {
+	sent := make(chan string, 2)
+	defer func(f func(string, smtp.Auth, string, []string, []byte) error) { smtpSendMail = f }(smtpSendMail)
+	smtpSendMail = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		sent <- string(msg)
+		return nil
+	}
+
+	s := &EmailSender{Addr: "smtp:25", From: "app@example.com", To: []string{"ops@example.com"}, Interval: 20 * time.Millisecond}
+	s.SendAlert(&Alert{Entry: Entry{Level: ERROR, Message: "order 42 failed"}})
+	s.SendAlert(&Alert{Entry: Entry{Level: ERROR, Message: "order 43 failed"}})
+	select {
+	case msg := <-sent:
+		if !strings.Contains(msg, "Subject: [ylog] 2 alerts, first ERROR order 42 failed\r\n") || !strings.Contains(msg, "order 43 failed") {
+			t.Fatalf("sent %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("digest not sent")
+	}
+
+	if err := s.SendAlert(&Alert{Entry: Entry{Level: FATAL, Message: "out of disk"}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-sent:
+		if !strings.Contains(msg, "Subject: [ylog] FATAL out of disk\r\n") {
+			t.Fatalf("sent %q", msg)
+		}
+	default:
+		t.Fatal("FATAL alert not sent at once")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !plan9
+// +build !plan9
+
+package ylog
+
+import (
+	"os"
+	"syscall"
+)
+
+// isDiskFull reports whether err is caused by the lack of space on the device.
+func isDiskFull(err error) bool {
+	return writeErrno(err) == syscall.ENOSPC
+}
+
+// isTransient reports whether err is caused by an interrupted or non-blocking write
+// worth retrying at once.
+func isTransient(err error) bool {
+	errno := writeErrno(err)
+	return errno == syscall.EINTR || errno == syscall.EAGAIN
+}
+
+// writeErrno returns the system error number of err, 0 if it has none.
+func writeErrno(err error) syscall.Errno {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+	errno, _ := err.(syscall.Errno)
+	return errno
+}
@@ -0,0 +1,72 @@
+//go:build !plan9
+// +build !plan9
+
+package ylog
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotateWriterRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var failures []error // errors of the next writes
+	defer func(write func(*os.File, []byte) (int, error)) { fileWrite = write }(fileWrite)
+	fileWrite = func(f *os.File, p []byte) (int, error) {
+		if len(failures) == 0 {
+			return f.Write(p)
+		}
+		err := failures[0]
+		failures = failures[1:]
+		if err == io.ErrShortWrite {
+			// write half of p
+			n, _ := f.Write(p[:len(p)/2])
+			return n, nil
+		}
+		return 0, &os.PathError{Op: "write", Path: f.Name(), Err: err}
+	}
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)}
+	w, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reported []error
+	w.SetErrorHandler(func(err error) { reported = append(reported, err) })
+
+	failures = []error{syscall.EINTR, syscall.EAGAIN, io.ErrShortWrite}
+	if n, err := w.Write([]byte("first\n")); n != 6 || err != nil {
+		t.Errorf("Write() = %d, %v, want 6, nil", n, err)
+	}
+	failures = []error{syscall.ENOSPC}
+	if n, err := w.Write([]byte("second\n")); n != 7 || err != nil {
+		t.Errorf("Write() on full disk = %d, %v, want 7, nil", n, err)
+	}
+	w.Write([]byte("third\n")) // held back
+	clock.Add(time.Second)
+	failures = []error{syscall.ENOSPC}
+	w.Write([]byte("fourth\n")) // retried and held back again
+	clock.Add(time.Second)
+	w.Write([]byte("fifth\n")) // in backoff
+	clock.Add(time.Second)
+	w.Write([]byte("sixth\n"))
+	w.Close()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "2024010215.log"))
+	if want := "first\nsecond\nthird\nfourth\nfifth\nsixth\n"; string(b) != want || err != nil {
+		t.Errorf("log file contains %q, %v, want %q", b, err, want)
+	}
+	if len(reported) != 2 || !isDiskFull(reported[0]) {
+		t.Errorf("reported errors %v, want 2 ENOSPC", reported)
+	}
+}
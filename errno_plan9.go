@@ -0,0 +1,21 @@
+//go:build plan9
+// +build plan9
+
+package ylog
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isDiskFull reports whether err is caused by the lack of space on the device, which
+// the file servers of Plan 9 report with their own messages only.
+func isDiskFull(err error) bool {
+	return false
+}
+
+// isTransient reports whether err is caused by an interrupted write worth retrying at
+// once.
+func isTransient(err error) bool {
+	return errors.Is(err, syscall.EINTR)
+}
@@ -0,0 +1,155 @@
+package ylog
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// BytesFieldLimit is the max number of bytes kept by the Bytes fields, the following ones
+// are cut.
+const BytesFieldLimit = 256
+
+// The constructors of the fields of the common types, e.g.
+//
+//	ylog.OutputFields(l, 1, ylog.INFO, "served", ylog.String("path", path), ylog.Duration("latency", d))
+//
+// whose values are written by the text format and JSONEncoder without reflection nor
+// allocation, but for their boxing in the Field and the String form of time.Duration.
+
+// String returns a Field of a string.
+func String(key, value string) Field {
+	return Field{key, value}
+}
+
+// Int returns a Field of an int.
+func Int(key string, value int) Field {
+	return Field{key, value}
+}
+
+// Int64 returns a Field of an int64.
+func Int64(key string, value int64) Field {
+	return Field{key, value}
+}
+
+// Uint64 returns a Field of a uint64.
+func Uint64(key string, value uint64) Field {
+	return Field{key, value}
+}
+
+// Float64 returns a Field of a float64.
+func Float64(key string, value float64) Field {
+	return Field{key, value}
+}
+
+// Bool returns a Field of a bool.
+func Bool(key string, value bool) Field {
+	return Field{key, value}
+}
+
+// Duration returns a Field of a time.Duration, written as by its String method, e.g.
+// "1.5s", but by JSONEncoder, as nanoseconds.
+func Duration(key string, value time.Duration) Field {
+	return Field{key, value}
+}
+
+// Time returns a Field of a time.Time, written in the RFC 3339 format with nanoseconds,
+// e.g. "2024-01-02T15:04:05.5Z".
+func Time(key string, value time.Time) Field {
+	return Field{key, value}
+}
+
+// Err returns a Field of err keyed "error", written as err.Error().
+func Err(err error) Field {
+	return Field{"error", err}
+}
+
+// Bytes returns a Field of a copy of the first BytesFieldLimit bytes of value, since the
+// entries may outlive the call, written as a string followed by "...(N bytes)" if value
+// is longer, N being its length.
+func Bytes(key string, value []byte) Field {
+	n := len(value)
+	if n > BytesFieldLimit {
+		value = value[:BytesFieldLimit]
+	}
+	return Field{key, bytesValue{s: string(value), n: n}}
+}
+
+// bytesValue is the value of a Bytes field: the bytes kept and the length of the original
+// bytes.
+type bytesValue struct {
+	s string
+	n int
+}
+
+func (v bytesValue) String() string {
+	return string(v.appendTo(nil))
+}
+
+// MarshalText marshals v as its String form, e.g. for encoding/json.
+func (v bytesValue) MarshalText() ([]byte, error) {
+	return v.appendTo(nil), nil
+}
+
+// appendTo appends the String form of v to buf.
+func (v bytesValue) appendTo(buf []byte) []byte {
+	buf = append(buf, v.s...)
+	return v.appendCut(buf)
+}
+
+// appendCut appends "...(N bytes)" to buf if bytes were cut.
+func (v bytesValue) appendCut(buf []byte) []byte {
+	if v.n > len(v.s) {
+		buf = append(buf, "...("...)
+		buf = strconv.AppendInt(buf, int64(v.n), 10)
+		buf = append(buf, " bytes)"...)
+	}
+	return buf
+}
+
+// appendFieldValue appends v to buf as fmt.Sprint does, but for time.Time written in the
+// RFC 3339 format with nanoseconds, without allocation for strings, booleans, integers,
+// floats, times, the Bytes fields and the SecretValues.
+func appendFieldValue(buf []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(buf, v...)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case float32:
+		return strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case time.Duration:
+		return append(buf, v.String()...)
+	case time.Time:
+		return v.AppendFormat(buf, time.RFC3339Nano)
+	case error:
+		return append(buf, v.Error()...)
+	case bytesValue:
+		return v.appendTo(buf)
+	case SecretValue:
+		return v.appendTo(buf)
+	}
+	return append(buf, fmt.Sprint(v)...)
+}
@@ -0,0 +1,76 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldConstructors(t *testing.T) {
+	d := 1500 * time.Millisecond
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 500000000, time.UTC)
+	fields := []Field{
+		String("path", "/"), Int("status", 200), Int64("size", -1), Uint64("id", 7),
+		Float64("ratio", 0.25), Bool("cached", true), Duration("latency", d),
+		Err(errors.New("timeout")), Bytes("payload", []byte("GET /")),
+		Field{"float32", float32(0.1)}, Field{"other", []int{1, 2}},
+	}
+	// as fmt.Sprint
+	for _, f := range fields {
+		if got, want := string(appendFieldValue(nil, f.Value)), fmt.Sprint(f.Value); got != want {
+			t.Errorf("%s written %q, want %q", f.Key, got, want)
+		}
+	}
+
+	// but times, in RFC 3339
+	if got, want := string(appendFieldValue(nil, Time("at", ts).Value)), "2024-01-02T15:04:05.5Z"; got != want {
+		t.Errorf("at written %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lnologlevel)
+	OutputFields(l, 1, INFO, "served", append(fields[:9:9], Time("at", ts))...)
+	if got, want := buf.String(), "path=/|status=200|size=-1|id=7|ratio=0.25|cached=true|latency=1.5s|error=timeout|payload=GET /|at=2024-01-02T15:04:05.5Z|served\n"; got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	l.SetEncoder(JSONEncoder{})
+	OutputFields(l, 1, INFO, "served", Duration("latency", d), Bytes("payload", []byte("GET /")))
+	if got, want := buf.String(), `"fields":{"latency":1500000000,"payload":"GET /"}}`+"\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("logged %q, want the suffix %q", got, want)
+	}
+}
+
+func TestBytesField(t *testing.T) {
+	b := []byte(strings.Repeat("x", BytesFieldLimit+10))
+	f := Bytes("payload", b)
+	b[0] = 'y' // the field keeps a copy
+	want := strings.Repeat("x", BytesFieldLimit) + fmt.Sprintf("...(%d bytes)", BytesFieldLimit+10)
+	if got := fmt.Sprint(f.Value); got != want {
+		t.Fatalf("Bytes field written %q, want %q", got, want)
+	}
+	if got := string(JSONEncoder{}.Encode(nil, &Entry{Fields: []Field{f}})); !strings.Contains(got, `"payload":"`+want+`"`) {
+		t.Fatalf("Bytes field encoded %s, want %q", got, want)
+	}
+}
+
+func TestFieldAllocs(t *testing.T) {
+	f := []Field{
+		String("path", "/"), Int("status", 200), Float64("ratio", 0.25), Bytes("payload", []byte("GET /")),
+		Time("at", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)),
+	}
+	e := &Entry{Level: INFO, Message: "served", Fields: f}
+	format := &textFormat{}
+	buf := make([]byte, 0, 1024)
+	if n := testing.AllocsPerRun(100, func() { formatEntry(&buf, format, e); buf = buf[:0] }); n != 0 {
+		t.Errorf("formatEntry allocates %v times, want 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { buf = JSONEncoder{}.Encode(buf[:0], e) }); n != 0 {
+		t.Errorf("Encode allocates %v times, want 0", n)
+	}
+}
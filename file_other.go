@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package ylog
+
+import (
+	"os"
+)
+
+// openLogFile opens the log file at path for appending, creating it if needed.
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// fileLocked reports whether err is caused by another process holding the file open
+// without sharing it, which does not happen on POSIX systems.
+func fileLocked(err error) bool {
+	return false
+}
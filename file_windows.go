@@ -0,0 +1,50 @@
+//go:build windows
+// +build windows
+
+package ylog
+
+import (
+	"os"
+	"syscall"
+)
+
+// Windows constants missing from package syscall.
+const (
+	fileReadAttributes    = 0x80              // FILE_READ_ATTRIBUTES
+	errorSharingViolation = syscall.Errno(32) // ERROR_SHARING_VIOLATION
+	errorLockViolation    = syscall.Errno(33) // ERROR_LOCK_VIOLATION
+)
+
+// openLogFile opens the log file at path for appending, creating it if needed. It is
+// shared for deletion too, unlike by os.OpenFile, so that the log file can be renamed
+// and removed while it is open, as on POSIX systems.
+func openLogFile(path string) (*os.File, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	access := uint32(syscall.FILE_APPEND_DATA | syscall.FILE_WRITE_ATTRIBUTES | fileReadAttributes |
+		syscall.STANDARD_RIGHTS_WRITE | syscall.SYNCHRONIZE)
+	share := uint32(syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE | syscall.FILE_SHARE_DELETE)
+	h, err := syscall.CreateFile(p, access, share, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
+
+// fileLocked reports whether err is caused by another process holding the file open
+// without sharing it, typically an antivirus scanner or an indexer, which lasts a moment.
+func fileLocked(err error) bool {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	}
+	switch err {
+	case syscall.ERROR_ACCESS_DENIED, errorSharingViolation, errorLockViolation:
+		return true
+	}
+	return false
+}
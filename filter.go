@@ -0,0 +1,206 @@
+package ylog
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// messageFilter holds the compiled message filters, nil means no filter.
+type messageFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// filters holds the filters deciding which entries are output by a logger.
+// It is embedded by loggers to provide the filter accessors.
+type filters struct {
+	message      atomic.Value // *messageFilter
+	caller       atomic.Value // *callerFilter
+	interceptors atomic.Value // []Interceptor, copy on write
+
+	mu sync.Mutex // serializes the changes of interceptors
+}
+
+// Interceptor is called for each entry about to be output by a logger, after the filters.
+// It may modify the entry, e.g. rewrite the message, add fields or change the log level,
+// and returns false to drop it. An entry whose log level is changed below the log level
+// of the logger is dropped too. FATAL entries are never dropped and keep their log level,
+// since the program exits after them.
+type Interceptor func(e *Entry) bool
+
+// AddInterceptor appends i to the interceptors of the logger, they are called in order.
+func (f *filters) AddInterceptor(i Interceptor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	old, _ := f.interceptors.Load().([]Interceptor)
+	is := make([]Interceptor, 0, len(old)+1)
+	is = append(is, old...)
+	is = append(is, i)
+	f.interceptors.Store(is)
+}
+
+// ClearInterceptors removes all interceptors of the logger.
+func (f *filters) ClearInterceptors() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.interceptors.Store([]Interceptor(nil))
+}
+
+// readMessage reports whether the filters read the messages of the entries: there is a
+// message filter or an interceptor.
+func (f *filters) readMessage() bool {
+	is, _ := f.interceptors.Load().([]Interceptor)
+	mf, _ := f.message.Load().(*messageFilter)
+	return len(is) > 0 || mf != nil
+}
+
+// intercept calls the interceptors for e, it returns the modified entry and whether to output it.
+func (f *filters) intercept(e Entry) (Entry, bool) {
+	is, _ := f.interceptors.Load().([]Interceptor)
+	if len(is) == 0 {
+		return e, true
+	}
+	pe := new(Entry)
+	*pe = e
+	for _, i := range is {
+		if !i(pe) && e.Level != FATAL {
+			return e, false
+		}
+		if e.Level == FATAL {
+			pe.Level = FATAL
+		}
+	}
+	return *pe, true
+}
+
+// SetMessageFilter sets the regular expressions filtering messages, the header is not
+// part of the message. Only messages matching include and not matching exclude are
+// output. Give "" to disable either of them. FATAL entries are never filtered, since
+// the program exits after them.
+func (f *filters) SetMessageFilter(include, exclude string) error {
+	mf := &messageFilter{}
+	var err error
+	if include != "" {
+		if mf.include, err = regexp.Compile(include); err != nil {
+			return err
+		}
+	}
+	if exclude != "" {
+		if mf.exclude, err = regexp.Compile(exclude); err != nil {
+			return err
+		}
+	}
+	f.message.Store(mf)
+	return nil
+}
+
+// MessageFilter returns the regular expressions filtering messages.
+func (f *filters) MessageFilter() (include, exclude string) {
+	mf, _ := f.message.Load().(*messageFilter)
+	if mf == nil {
+		return "", ""
+	}
+	if mf.include != nil {
+		include = mf.include.String()
+	}
+	if mf.exclude != nil {
+		exclude = mf.exclude.String()
+	}
+	return include, exclude
+}
+
+// allowMessage reports whether the message filters allow s to be output.
+func (f *filters) allowMessage(s string) bool {
+	mf, _ := f.message.Load().(*messageFilter)
+	if mf == nil {
+		return true
+	}
+	if mf.include != nil && !mf.include.MatchString(s) {
+		return false
+	}
+	if mf.exclude != nil && mf.exclude.MatchString(s) {
+		return false
+	}
+	return true
+}
+
+// callerFilter holds the caller filters.
+type callerFilter struct {
+	include []string
+	exclude []string
+}
+
+// SetCallerFilter sets the patterns filtering entries by their caller. A pattern matches
+// a caller if it matches whole path elements of either the package path or the file path
+// of the caller, e.g. "pkg/server" matches "github.com/us/app/pkg/server" and
+// "/src/app/pkg/server/handler.go". Only entries from callers matching one of include,
+// if any, and none of exclude are output. FATAL entries are never filtered, since the
+// program exits after them.
+func (f *filters) SetCallerFilter(include, exclude []string) {
+	cf := &callerFilter{
+		include: append([]string(nil), include...),
+		exclude: append([]string(nil), exclude...),
+	}
+	f.caller.Store(cf)
+}
+
+// CallerFilter returns the patterns filtering entries by their caller.
+func (f *filters) CallerFilter() (include, exclude []string) {
+	cf, _ := f.caller.Load().(*callerFilter)
+	if cf == nil {
+		return nil, nil
+	}
+	return append([]string(nil), cf.include...), append([]string(nil), cf.exclude...)
+}
+
+// allowCaller reports whether the caller filters allow the entry from file and function fn.
+func (f *filters) allowCaller(file, fn string) bool {
+	cf, _ := f.caller.Load().(*callerFilter)
+	if cf == nil {
+		return true
+	}
+	pkg := pkgPath(fn)
+	matchAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if matchPath(pkg, pattern) || matchPath(file, pattern) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(cf.include) > 0 && !matchAny(cf.include) {
+		return false
+	}
+	return !matchAny(cf.exclude)
+}
+
+// pkgPath returns the package path of the fully qualified function name,
+// e.g. "github.com/us/app/pkg/server" for "github.com/us/app/pkg/server.(*Handler).ServeHTTP".
+func pkgPath(fn string) string {
+	slash := strings.LastIndexByte(fn, '/')
+	if dot := strings.IndexByte(fn[slash+1:], '.'); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
+
+// matchPath reports whether pattern matches whole path elements of path.
+func matchPath(path, pattern string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(path[i:], pattern)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(pattern)
+		if (start == 0 || path[start-1] == '/') && (end == len(path) || path[end] == '/') {
+			return true
+		}
+		i = start + 1
+	}
+}
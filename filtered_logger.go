@@ -0,0 +1,37 @@
+package ylog
+
+// filteredLogger drops the entries below a log level before passing the others to
+// another Logger.
+type filteredLogger struct {
+	loggerMethods
+	inner Logger
+	level LogLevel
+}
+
+// WithMinLevel returns a Logger passing the entries logged through it to inner, but the
+// ones below level, so that components with different verbosities share inner. As for
+// the other loggers, INFO and FATAL entries are never dropped, and inner still drops the
+// entries below its own log level.
+func WithMinLevel(inner Logger, level LogLevel) Logger {
+	l := &filteredLogger{inner: inner, level: level}
+	l.loggerMethods = loggerMethods{l}
+	return l
+}
+
+func (l *filteredLogger) lowestLevel() LogLevel {
+	if level := logLevelOf(l.inner); level > l.level {
+		return level
+	}
+	return l.level
+}
+
+func (l *filteredLogger) output(skipdepth int, level LogLevel, s string) error {
+	return l.outputFields(skipdepth+1, level, s, nil)
+}
+
+func (l *filteredLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	if level >= TRACE && level < INFO && level < l.level {
+		return nil
+	}
+	return outputFieldsTo(l.inner, skipdepth+1, level, s, fields)
+}
@@ -0,0 +1,34 @@
+package ylog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithMinLevel(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var buf bytes.Buffer
+	inner := NewWriterLogger(&buf, DEBUG)
+	inner.SetFlags(Lshortfile | Lloglevel)
+	l := WithMinLevel(inner, WARN)
+
+	l.Debug("polling")
+	l.Warn("slow poll")
+	l.Info("started")
+	inner.Debug("inner polling")
+	WithMinLevel(inner, TRACE).Trace("tracing")
+
+	want := "filtered_logger_test.go:18|WARN|slow poll\nfiltered_logger_test.go:19|INFO|started\nfiltered_logger_test.go:20|DEBUG|inner polling\n"
+	if buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWithMinLevelConfig(t *testing.T) {
+	inner := NewWriterLogger(&bytes.Buffer{}, DEBUG)
+	if c := ConfigOf(WithMinLevel(inner, WARN)); c.Level != "WARN" || c.Flags != flagNames(inner.Flags()) {
+		t.Fatalf("configuration %+v, want the one of the inner logger at WARN", c)
+	}
+}
@@ -1,6 +1,9 @@
 package ylog
 
 import (
+	"fmt"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -18,15 +21,38 @@ const (
 	Ltime                     // the time in the local time zone: 01:23:23
 	Lmicroseconds             // microsecond resolution: 01:23:23.123123.  assumes Ltime.
 	Llongfile                 // full file name and line number: /a/b/c/d.go:23
-	Lshortfile                // final file name element and line number: d.go:23. overrides Llongfile
+	Lshortfile                // final file name element and line number: d.go:23, see SetCallerRoot. overrides Llongfile
 	LUTC                      // if Ldate or Ltime is set, use UTC rather than the local time zone
 	Lfuncname                 // the name of function outputs log
-	Lloglevel                 // the log level name TODO
+	Lloglevel                 // the log level name: DEBUG, printed unless Lnologlevel is set
 	LallFlags     = (1 << iota) - 1
 
 	LdefaultFlags = Ldate | Ltime | Lmicroseconds | Lshortfile | Lloglevel
 )
 
+// Lnologlevel omits the log level name, which is printed regardless of the other flags.
+const Lnologlevel = LallFlags + 1
+
+// These flags add to the header after Lnologlevel.
+const (
+	Lshortfunc = Lnologlevel << (iota + 1) // function name without the package path: Handler.ServeHTTP. overrides Lfuncname
+	Lsequence                              // sequence number of the entry in the logger: 42
+	Lpid                                   // process ID: 1234
+	Lhostname                              // host name, as of the start of the program: web-1
+)
+
+// pid is the process ID written by Lpid.
+var pid = strconv.Itoa(os.Getpid())
+
+// hostname is the host name written by Lhostname, "localhost" if unknown.
+var hostname = func() string {
+	name, _ := os.Hostname()
+	if name == "" {
+		name = "localhost"
+	}
+	return name
+}()
+
 // Cheap integer to fixed-width decimal ASCII. Give a negative width to avoid zero-padding.
 func itoa(buf *[]byte, i int, wid int) {
 	// Assemble decimal in reverse order.
@@ -44,12 +70,45 @@ func itoa(buf *[]byte, i int, wid int) {
 	*buf = append(*buf, b[bp:]...)
 }
 
+// textFormat is the text format of the entries of a WriterLogger.
+type textFormat struct {
+	flags int    // properties
+	root  string // root of the file names of Lshortfile, see SetCallerRoot
+	sep   byte   // separator, see SetSeparator; 0 for '|' without escaping
+}
+
+// separator returns the separator of the header properties, the fields and the message.
+func (f *textFormat) separator() byte {
+	if f.sep == 0 {
+		return '|'
+	}
+	return f.sep
+}
+
+// appendEscaped appends s to buf, escaping sep and '\\' with '\\' unless sep is 0.
+func appendEscaped(buf *[]byte, s string, sep byte) {
+	if sep == 0 || strings.IndexByte(s, sep) < 0 && strings.IndexByte(s, '\\') < 0 {
+		*buf = append(*buf, s...)
+		return
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == sep || s[i] == '\\' {
+			*buf = append(*buf, '\\')
+		}
+		*buf = append(*buf, s[i])
+	}
+}
+
 // formatHeader writers log header to buf in following order:
 //   * date and/or time (if corresponding flags are provided),
+//   * host name (if Lhostname is provided),
+//   * process ID (if Lpid is provided),
+//   * sequence number (if Lsequence is provided),
 //   * file and line number (if corresponding flags are provided),
 //   * function name (if corresponding flags are provided),
-//   * log level (if corresponding flags are provided).
-func formatHeader(buf *[]byte, flag int, t time.Time, file string, line int, fn string) {
+//   * log level (unless Lnologlevel is provided).
+func formatHeader(buf *[]byte, f *textFormat, t time.Time, seq uint64, file string, line int, fn string, level LogLevel) {
+	flag, sep := f.flags, f.separator()
 	// set date and time
 	if flag&(Ldate|Ltime|Lmicroseconds) != 0 {
 		if flag&LUTC != 0 {
@@ -60,7 +119,10 @@ func formatHeader(buf *[]byte, flag int, t time.Time, file string, line int, fn
 			itoa(buf, year, 4)
 			itoa(buf, int(month), 2)
 			itoa(buf, day, 2)
-			*buf = append(*buf, ' ')
+			// with the separator ' ', the date and the time are two properties
+			if f.sep != ' ' || flag&(Ltime|Lmicroseconds) != 0 {
+				*buf = append(*buf, ' ')
+			}
 		}
 		if flag&(Ltime|Lmicroseconds) != 0 {
 			hour, min, sec := t.Clock()
@@ -74,22 +136,100 @@ func formatHeader(buf *[]byte, flag int, t time.Time, file string, line int, fn
 				itoa(buf, t.Nanosecond()/1e3, 6)
 			}
 		}
-		*buf = append(*buf, '|')
+		*buf = append(*buf, sep)
+	}
+	// set host name
+	if flag&Lhostname != 0 {
+		*buf = append(*buf, hostname...)
+		*buf = append(*buf, sep)
+	}
+	// set process ID
+	if flag&Lpid != 0 {
+		*buf = append(*buf, pid...)
+		*buf = append(*buf, sep)
+	}
+	// set sequence number
+	if flag&Lsequence != 0 {
+		*buf = strconv.AppendUint(*buf, seq, 10)
+		*buf = append(*buf, sep)
 	}
 	// set file and line number
 	if flag&(Llongfile|Lshortfile) != 0 {
 		if flag&Lshortfile != 0 {
-			offset := strings.LastIndexByte(file, '/')
-			file = file[offset+1:]
+			if rel := relFile(file, f.root); rel != "" {
+				file = rel
+			} else {
+				offset := strings.LastIndexByte(file, '/')
+				file = file[offset+1:]
+			}
 		}
-		*buf = append(*buf, file...)
+		appendEscaped(buf, file, f.sep)
 		*buf = append(*buf, ':')
 		itoa(buf, line, -1)
-		*buf = append(*buf, '|')
+		*buf = append(*buf, sep)
 	}
 	// set function name
-	if flag&Lfuncname != 0 {
-		*buf = append(*buf, fn...)
-		*buf = append(*buf, '|')
+	if flag&(Lfuncname|Lshortfunc) != 0 {
+		if flag&Lshortfunc != 0 {
+			fn = shortFuncName(fn)
+		}
+		appendEscaped(buf, fn, f.sep)
+		*buf = append(*buf, sep)
+	}
+	// set log level
+	if flag&Lnologlevel == 0 && level != noLevel {
+		*buf = append(*buf, level.LogLevelName()...)
+		*buf = append(*buf, sep)
+	}
+}
+
+// formatEntry writes the entry to buf: the header, the fields as "key=value|" and the message
+// followed by a newline, with the separator of f.
+func formatEntry(buf *[]byte, f *textFormat, e *Entry) {
+	formatHeader(buf, f, e.Time, e.Seq, e.File, e.Line, e.Func, e.Level)
+	sep := f.separator()
+	for _, field := range e.Fields {
+		appendEscaped(buf, field.Key, f.sep)
+		*buf = append(*buf, '=')
+		if f.sep == 0 {
+			*buf = appendFieldValue(*buf, field.Value)
+		} else {
+			appendEscaped(buf, fmt.Sprint(field.Value), f.sep)
+		}
+		*buf = append(*buf, sep)
+	}
+	appendEscaped(buf, e.Message, f.sep)
+	*buf = append(*buf, '\n')
+}
+
+// relFile returns the path of file relative to the last occurrence of the path elements
+// of root in it, e.g. "pkg/server/handler.go" for "/src/app/pkg/server/handler.go" and
+// "app" or "/src/app", or "" if root is empty or not found.
+func relFile(file, root string) string {
+	root = strings.Trim(root, "/")
+	if root == "" {
+		return ""
+	}
+	for i := len(file) - len(root) - 1; i >= 0; i-- {
+		if file[i+len(root)] == '/' && (i == 0 || file[i-1] == '/') && file[i:i+len(root)] == root {
+			return file[i+len(root)+1:]
+		}
+	}
+	return ""
+}
+
+// shortFuncName returns the fully qualified function name fn without the package path
+// and the pointer receiver parentheses, e.g. "Handler.ServeHTTP" for
+// "github.com/org/repo/pkg/server.(*Handler).ServeHTTP".
+func shortFuncName(fn string) string {
+	fn = fn[strings.LastIndexByte(fn, '/')+1:]
+	if dot := strings.IndexByte(fn, '.'); dot >= 0 {
+		fn = fn[dot+1:]
+	}
+	if strings.HasPrefix(fn, "(*") {
+		if end := strings.IndexByte(fn, ')'); end >= 0 {
+			fn = fn[2:end] + fn[end+1:]
+		}
 	}
+	return fn
 }
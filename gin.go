@@ -0,0 +1,62 @@
+//go:build ylog_gin
+// +build ylog_gin
+
+package ylog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinAccessLog returns a gin middleware logging each request to l at INFO level once it
+// is served, in the given format, CommonLogFormat if empty, as AccessLogMiddlewareWithFormat.
+//
+// It requires the ylog_gin build tag.
+func GinAccessLog(l Logger, format string) gin.HandlerFunc {
+	if format == "" {
+		format = CommonLogFormat
+	}
+	tokens := parseAccessLogFormat(format)
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		size := c.Writer.Size()
+		if size < 0 {
+			// nothing written
+			size = 0
+		}
+		logAccess(l, tokens, &accessLogRequest{
+			r:      c.Request,
+			header: c.Writer.Header(),
+			status: c.Writer.Status(),
+			size:   int64(size),
+			start:  start,
+			taken:  time.Since(start),
+		})
+	}
+}
+
+// GinRecovery returns a gin middleware recovering from the panics of the next handlers,
+// as RecoveryMiddleware.
+//
+// It requires the ylog_gin build tag.
+func GinRecovery(l Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				logPanic(l, c.Request, v)
+				if c.Writer.Written() {
+					c.Abort()
+				} else {
+					c.AbortWithStatus(http.StatusInternalServerError)
+				}
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+//go:build ylog_gin
+// +build ylog_gin
+
+package ylog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGinMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinAccessLog(l, `"%r" %>s %b`), GinRecovery(l))
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	if !strings.HasSuffix(buf.String(), `|"GET /ok HTTP/1.1" 200 5`+"\n") {
+		t.Fatalf("logged %q, want the access log line", buf.String())
+	}
+
+	buf.Reset()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(buf.String(), "ERROR|") || !strings.Contains(buf.String(), "panic serving GET /panic: boom") ||
+		!strings.HasSuffix(buf.String(), `|"GET /panic HTTP/1.1" 500 -`+"\n") {
+		t.Fatalf("logged %q, want the panic and the access log line", buf.String())
+	}
+}
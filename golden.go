@@ -0,0 +1,83 @@
+package ylog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// EnvUpdateGolden is the environment variable which, set to a non empty value, makes
+// AssertGolden write the golden files rather than compare them, e.g.
+//
+//	YLOG_UPDATE_GOLDEN=1 go test ./...
+const EnvUpdateGolden = "YLOG_UPDATE_GOLDEN"
+
+// goldenRules are the volatile parts of the log output replaced by NormalizeLog.
+var goldenRules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\d{8} \d{2}:\d{2}:\d{2}(\.\d+)?`), "<time>"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(\.\d+)?\b`), "<time>"},
+	{regexp.MustCompile(`(\.go):\d+\b`), "$1:<line>"},
+	{regexp.MustCompile(`\bgoroutine \d+\b`), "goroutine <id>"},
+}
+
+// NormalizeLog returns the log output b with its volatile parts replaced by
+// placeholders, so that it can be compared across runs and changes of the code: the
+// dates and times of the headers and of RFC 3339 as "<time>", the line numbers of the
+// Go files as "<line>", e.g. "main.go:<line>", and the goroutine IDs of the stacks as
+// "goroutine <id>".
+func NormalizeLog(b []byte) []byte {
+	for _, rule := range goldenRules {
+		b = rule.re.ReplaceAll(b, []byte(rule.repl))
+	}
+	return b
+}
+
+// AssertGolden marks t failed unless the log output got, normalized by NormalizeLog,
+// equals the content of the golden file at path, e.g. "testdata/usage.log", and reports
+// whether it does. It reports the first line which differs. If the environment variable
+// EnvUpdateGolden is set, it writes the normalized output to the golden file instead,
+// making its directory if needed.
+func AssertGolden(t TB, path string, got []byte) bool {
+	t.Helper()
+	got = NormalizeLog(got)
+	if os.Getenv(EnvUpdateGolden) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Error(err)
+			return false
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Error(err)
+			return false
+		}
+		return true
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(fmt.Sprintf("%v, set %s=1 to write it", err, EnvUpdateGolden))
+		return false
+	}
+	if bytes.Equal(got, want) {
+		return true
+	}
+	gotLines, wantLines := bytes.Split(got, []byte("\n")), bytes.Split(want, []byte("\n"))
+	i := 0
+	for i < len(gotLines) && i < len(wantLines) && bytes.Equal(gotLines[i], wantLines[i]) {
+		i++
+	}
+	line := func(lines [][]byte) string {
+		if i < len(lines) {
+			return fmt.Sprintf("%q", lines[i])
+		}
+		return "end of output"
+	}
+	t.Error(fmt.Sprintf("log output differs from %s at line %d:\n\tgot:  %s\n\twant: %s",
+		path, i+1, line(gotLines), line(wantLines)))
+	return false
+}
@@ -0,0 +1,60 @@
+package ylog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLog(t *testing.T) {
+	in := "20240102 15:04:05.123456|main.go:42|INFO|started\n" +
+		"15:04:05|pkg/server/handler.go:7|served at 2024-01-02T15:04:05.123Z\n" +
+		"goroutine 18 [running]:\n\t/src/app/main.go:123 +0x1d\n"
+	want := "<time>|main.go:<line>|INFO|started\n" +
+		"<time>|pkg/server/handler.go:<line>|served at <time>\n" +
+		"goroutine <id> [running]:\n\t/src/app/main.go:<line> +0x1d\n"
+	if got := string(NormalizeLog([]byte(in))); got != want {
+		t.Errorf("NormalizeLog() = %q, want %q", got, want)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "testdata", "usage.log")
+
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.Info("started")
+	l.Warn("disk almost full")
+
+	tb := &recordingTB{}
+	if AssertGolden(tb, path, buf.Bytes()) || len(tb.calls) != 1 {
+		t.Errorf("AssertGolden passed without golden file, reported %q", tb.calls)
+	}
+	t.Setenv(EnvUpdateGolden, "1")
+	if !AssertGolden(tb, path, buf.Bytes()) {
+		t.Fatalf("AssertGolden failed to write the golden file: %q", tb.calls)
+	}
+	t.Setenv(EnvUpdateGolden, "")
+
+	// in another run
+	buf.Reset()
+	l.Info("started")
+	l.Warn("disk almost full")
+	tb.calls = nil
+	if !AssertGolden(tb, path, buf.Bytes()) {
+		t.Errorf("AssertGolden failed on the same output: %q", tb.calls)
+	}
+	l.Error("disk full")
+	want := []string{"Error log output differs from " + path + " at line 3:\n\tgot:  \"<time>|golden_test.go:<line>|ERROR|disk full\"\n\twant: \"\""}
+	if AssertGolden(tb, path, buf.Bytes()) || !reflect.DeepEqual(tb.calls, want) {
+		t.Errorf("AssertGolden reported %q, want %q", tb.calls, want)
+	}
+}
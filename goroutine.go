@@ -0,0 +1,50 @@
+package ylog
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+// PanicError is the error of a function run by GoErr which panicked.
+type PanicError struct {
+	Value interface{} // value given to panic
+	Stack []byte      // stack of the goroutine when it panicked
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Go runs f in a goroutine. If f panics, the panic is logged to l at FATAL level with the
+// stack of the goroutine and the program exits with status 2, as for an unrecovered
+// panic, but logged rather than printed on stderr, e.g. for the background workers.
+func Go(l Logger, f func()) {
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				outputTo(l, 2, FATAL, fmt.Sprintf("panic: %v\n%s", v, debug.Stack()))
+				os.Exit(2)
+			}
+		}()
+		f()
+	}()
+}
+
+// GoErr runs f in a goroutine and returns a channel receiving its error. If f panics, the
+// panic is logged to l at ERROR level with the stack of the goroutine, and the channel
+// receives a *PanicError, so that the program goes on.
+func GoErr(l Logger, f func() error) <-chan error {
+	c := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				err := &PanicError{Value: v, Stack: debug.Stack()}
+				outputTo(l, 2, ERROR, fmt.Sprintf("%v\n%s", err, err.Stack))
+				c <- err
+			}
+		}()
+		c <- f()
+	}()
+	return c
+}
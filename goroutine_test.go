@@ -0,0 +1,30 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGoErr(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+
+	want := errors.New("closed")
+	if err := <-GoErr(l, func() error { return want }); err != want {
+		t.Fatalf("received %v, want %v", err, want)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("logged %q", buf.String())
+	}
+
+	err := <-GoErr(l, func() error { panic("out of range") })
+	if pe, ok := err.(*PanicError); !ok || pe.Value != "out of range" {
+		t.Fatalf("received %v, want a *PanicError", err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "ERROR|panic: out of range\n") || !strings.Contains(got, "TestGoErr") {
+		t.Fatalf("logged %q", got)
+	}
+}
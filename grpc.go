@@ -0,0 +1,140 @@
+//go:build ylog_grpc
+// +build ylog_grpc
+
+package ylog
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// LogFilter selects the entries streamed by the LogStream service: the lowest log
+// level name and a regular expression matching the message, both optional.
+type LogFilter struct {
+	Level string `json:"level,omitempty"`
+	Match string `json:"match,omitempty"`
+}
+
+// LogEntry is an entry streamed by the LogStream service.
+type LogEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level,omitempty"`
+	File    string                 `json:"file"`
+	Line    int                    `json:"line"`
+	Func    string                 `json:"func"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// jsonCodec encodes the messages of the LogStream service as JSON, so that the
+// service needs no generated code. Its name is specific to ylog, so that it does not
+// replace a "json" codec of the program.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "ylog-json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// logStreamDesc describes the service
+//
+//	service ylog.LogStream {
+//	  rpc Watch(LogFilter) returns (stream LogEntry);
+//	}
+//
+// whose messages are encoded as JSON, with the "ylog-json" content subtype.
+var logStreamDesc = grpc.ServiceDesc{
+	ServiceName: "ylog.LogStream",
+	HandlerType: (*Subscribable)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Watch",
+		Handler:       watchHandler,
+		ServerStreams: true,
+	}},
+}
+
+// RegisterLogStreamServer registers in s the LogStream service streaming the entries
+// of l to the clients, see WatchLogs. The interceptors of s, e.g. for authentication,
+// apply to the service like to any other.
+//
+// It requires the ylog_grpc build tag.
+func RegisterLogStreamServer(s grpc.ServiceRegistrar, l Subscribable) {
+	s.RegisterService(&logStreamDesc, l)
+}
+
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	var req LogFilter
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+	filter, err := parseStreamFilter(req.Level, req.Match)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	entries, cancel := srv.(Subscribable).Subscribe()
+	defer cancel()
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-entries:
+			if !ok {
+				return nil
+			}
+			if !filter.allow(&e) {
+				continue
+			}
+			b, err := marshalEntry(&e)
+			if err != nil {
+				continue
+			}
+			if err = stream.SendMsg(json.RawMessage(b)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// LogWatcher receives the entries streamed by a LogStream service.
+type LogWatcher struct {
+	stream grpc.ClientStream
+}
+
+// WatchLogs starts streaming the entries selected by filter from the LogStream service
+// reached by cc, until ctx is done.
+//
+// It requires the ylog_grpc build tag.
+func WatchLogs(ctx context.Context, cc grpc.ClientConnInterface, filter LogFilter) (*LogWatcher, error) {
+	stream, err := cc.NewStream(ctx, &logStreamDesc.Streams[0], "/ylog.LogStream/Watch",
+		grpc.CallContentSubtype(jsonCodec{}.Name()))
+	if err != nil {
+		return nil, err
+	}
+	if err = stream.SendMsg(&filter); err != nil {
+		return nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &LogWatcher{stream: stream}, nil
+}
+
+// Recv returns the next entry, or io.EOF once the server ends the stream.
+func (w *LogWatcher) Recv() (*LogEntry, error) {
+	e := new(LogEntry)
+	if err := w.stream.RecvMsg(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
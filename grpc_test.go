@@ -0,0 +1,62 @@
+//go:build ylog_grpc
+// +build ylog_grpc
+
+package ylog
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestLogStream(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterLogStreamServer(s, l)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	cc, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w, err := WatchLogs(ctx, cc, LogFilter{Level: "warn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wait for the subscription
+	for atomic.LoadInt32(&l.subscribers.n) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Debug("cache miss")
+	l.Warn("disk almost full")
+	e, err := w.Recv()
+	if err != nil || e.Level != "WARN" || e.Message != "disk almost full" {
+		t.Fatalf("received %+v, %v, want the WARN entry", e, err)
+	}
+
+	w, err = WatchLogs(ctx, cc, LogFilter{Level: "loud"})
+	if err == nil {
+		_, err = w.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid filter failed with %v, want InvalidArgument", err)
+	}
+}
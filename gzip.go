@@ -0,0 +1,61 @@
+package ylog
+
+import (
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// GzipWriter compresses the entries written to it with gzip into an io.Writer, such as a
+// file or a network connection, which it closes with itself, so that a WriterLogger owns
+// both, e.g.
+//
+//	l := ylog.NewWriterLogger(ylog.NewGzipWriter(f), ylog.INFO)
+//	defer l.Close()
+//
+// Flush, called by the Flush of the logger, writes out the entries compressed so far.
+type GzipWriter struct {
+	mu  sync.Mutex   // serializes the writes; protects the following field
+	zw  *gzip.Writer // compressor
+	out io.Writer    // destination of the compressed entries
+}
+
+// NewGzipWriter returns a GzipWriter compressing to out at the default compression level.
+func NewGzipWriter(out io.Writer) *GzipWriter {
+	return &GzipWriter{zw: gzip.NewWriter(out), out: out}
+}
+
+// Write compresses p.
+func (w *GzipWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.zw.Write(p)
+}
+
+// Flush writes out the data compressed so far, then flushes the destination if it
+// buffers.
+func (w *GzipWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.zw.Flush(); err != nil {
+		return err
+	}
+	if f, ok := w.out.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close writes the gzip footer, then closes the destination if it is an io.Closer, but
+// for os.Stdout and os.Stderr.
+func (w *GzipWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.zw.Close()
+	if c, ok := w.out.(io.Closer); ok && !isStdStream(w.out) {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package ylog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+)
+
+// closeBuffer is a bytes.Buffer counting its Close calls.
+type closeBuffer struct {
+	bytes.Buffer
+	closes int
+}
+
+func (b *closeBuffer) Close() error {
+	b.closes++
+	return nil
+}
+
+func TestGzipWriter(t *testing.T) {
+	out := &closeBuffer{}
+	l := NewWriterLogger(NewGzipWriter(out), TRACE)
+	l.SetFlags(Lnologlevel)
+	l.Info("first")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() == 0 {
+		t.Fatal("nothing written by Flush")
+	}
+	l.Info("second")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil || out.closes != 1 {
+		t.Fatalf("closed the destination %d times, want once", out.closes)
+	}
+
+	zr, err := gzip.NewReader(&out.Buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "first\nsecond\n"; got != want {
+		t.Fatalf("decompressed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package ylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LevelHandler returns an http.Handler which reports and changes the log level of l.
+//
+// GET responds with the current log level name.
+// PUT and POST set the log level given by the "level" form value, e.g. level=DEBUG.
+// If a "duration" form value such as "10m" is also given, the log level is
+// restored after that duration, see SetLogLevelFor. A "vmodule" form value sets the
+// vmodule spec of l, see SetVModule, the log level is then optional.
+func LevelHandler(l LevelLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "HEAD":
+		case "PUT", "POST":
+			spec := r.FormValue("vmodule")
+			_, hasVModule := r.Form["vmodule"]
+			name := strings.ToUpper(r.FormValue("level"))
+			level, ok := LogLevelMap[name]
+			if !ok && !(hasVModule && name == "") {
+				http.Error(w, fmt.Sprintf("unknown log level %q", name), http.StatusBadRequest)
+				return
+			}
+			var d time.Duration
+			if s := r.FormValue("duration"); s != "" && ok {
+				var err error
+				if d, err = time.ParseDuration(s); err != nil || d <= 0 {
+					http.Error(w, fmt.Sprintf("invalid duration %q", s), http.StatusBadRequest)
+					return
+				}
+			}
+			if hasVModule {
+				vl, vok := l.(interface{ SetVModule(spec string) error })
+				if !vok {
+					http.Error(w, "vmodule not supported", http.StatusBadRequest)
+					return
+				}
+				if err := vl.SetVModule(spec); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			switch {
+			case !ok:
+			case d > 0:
+				l.SetLogLevelFor(level, d)
+			default:
+				l.SetLogLevel(level)
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, l.LogLevel().LogLevelName())
+	})
+}
+
+// ConfigHandler returns an http.Handler which responds with the effective configuration
+// of l in JSON, see ConfigOf.
+func ConfigHandler(l Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.MarshalIndent(ConfigOf(l), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(b, '\n'))
+	})
+}
+
+// StatsHandler returns an http.Handler which responds with the counters of l in JSON.
+func StatsHandler(l StatsReporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.MarshalIndent(l.Stats(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(b, '\n'))
+	})
+}
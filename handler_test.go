@@ -0,0 +1,108 @@
+package ylog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelHandler(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, WARN)
+	h := LevelHandler(l)
+	do := func(method string, form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("GET", nil); w.Code != http.StatusOK || w.Body.String() != "WARN\n" {
+		t.Fatalf("GET responded %d %q, want WARN", w.Code, w.Body)
+	}
+	if w := do("PUT", url.Values{"level": {"debug"}}); w.Code != http.StatusOK || w.Body.String() != "DEBUG\n" {
+		t.Fatalf("PUT responded %d %q, want DEBUG", w.Code, w.Body)
+	}
+	if l.LogLevel() != DEBUG {
+		t.Fatalf("log level is %v after PUT, want DEBUG", l.LogLevel().LogLevelName())
+	}
+
+	if w := do("POST", url.Values{"level": {"TRACE"}, "duration": {"20ms"}}); w.Code != http.StatusOK || l.LogLevel() != TRACE {
+		t.Fatalf("POST with duration responded %d %q, log level %v", w.Code, w.Body, l.LogLevel().LogLevelName())
+	}
+	for deadline := time.Now().Add(5 * time.Second); l.LogLevel() != DEBUG; {
+		if time.Now().After(deadline) {
+			t.Fatal("log level not restored after the duration")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, tt := range []struct {
+		method string
+		form   url.Values
+		code   int
+	}{
+		{"PUT", url.Values{"level": {"VERBOSE"}}, http.StatusBadRequest},
+		{"PUT", url.Values{"level": {"INFO"}, "duration": {"soon"}}, http.StatusBadRequest},
+		{"PUT", url.Values{"level": {"INFO"}, "duration": {"-1s"}}, http.StatusBadRequest},
+		{"DELETE", nil, http.StatusMethodNotAllowed},
+	} {
+		if w := do(tt.method, tt.form); w.Code != tt.code {
+			t.Errorf("%s %v responded %d, want %d", tt.method, tt.form, w.Code, tt.code)
+		}
+	}
+	if l.LogLevel() != DEBUG {
+		t.Fatalf("log level is %v after invalid requests, want DEBUG", l.LogLevel().LogLevelName())
+	}
+}
+
+func TestLevelHandlerVModule(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, WARN)
+	h := LevelHandler(l)
+	do := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do(url.Values{"vmodule": {"handler=TRACE"}}); w.Code != http.StatusOK || l.VModule() != "handler=TRACE" || l.LogLevel() != WARN {
+		t.Fatalf("PUT vmodule responded %d %q, vmodule %q", w.Code, w.Body, l.VModule())
+	}
+	if w := do(url.Values{"vmodule": {"handler"}, "level": {"DEBUG"}}); w.Code != http.StatusBadRequest || l.VModule() != "handler=TRACE" || l.LogLevel() != WARN {
+		t.Fatalf("PUT invalid vmodule responded %d %q, vmodule %q", w.Code, w.Body, l.VModule())
+	}
+	if w := do(url.Values{"vmodule": {""}, "level": {"DEBUG"}}); w.Code != http.StatusOK || l.VModule() != "" || l.LogLevel() != DEBUG {
+		t.Fatalf("PUT responded %d %q, vmodule %q", w.Code, w.Body, l.VModule())
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	// the seconds before 1970 are negative
+	clock := &fakeClock{now: time.Date(1969, 12, 31, 23, 59, 55, 0, time.UTC)}
+	l := NewWriterLogger(ioutil.Discard, DEBUG)
+	l.SetClock(clock)
+	for i := 0; i < 10; i++ {
+		l.Warn("slow query")
+		clock.Add(time.Second)
+	}
+
+	w := httptest.NewRecorder()
+	StatsHandler(l).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if ct := w.Header().Get("Content-Type"); w.Code != http.StatusOK || ct != "application/json" {
+		t.Fatalf("GET responded %d with Content-Type %q", w.Code, ct)
+	}
+	var st Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Entries["WARN"] != 10 || st.Rates["10s"]["WARN"] != 0.9 {
+		t.Fatalf("stats %+v, want 10 warnings, 9 in the last 10s", st)
+	}
+}
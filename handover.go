@@ -0,0 +1,103 @@
+package ylog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RotateState is the state of the rotation of a RotateWriter handed over to another
+// process, see Handover. It is marshalable as JSON, e.g. to pass it in an environment
+// variable.
+type RotateState struct {
+	LogDir        string `json:"log_dir"`        // log dir
+	Name          string `json:"name"`           // log file name of the hour, e.g. "2024010215.log"
+	ID            int32  `json:"id"`             // id of the current log file
+	SequenceWidth int    `json:"sequence_width"` // min number of digits of the ids, see SetSequenceWidth
+}
+
+// Handover prepares the graceful restart of the process, such as by tableflip, handing
+// the current log file over to the process taking over: it returns the current log file,
+// to be passed to the new process, e.g. in cmd.ExtraFiles, and the state of the rotation,
+// from which the new process resumes the writes, see NewRotateWriterFromHandover.
+//
+// The log file is still owned by w, which goes on writing to it without rotating it
+// until it is closed, so that the processes never rotate or truncate it concurrently
+// during the handover. The returned file must not be closed by the caller. It returns
+// an error if no log file is open.
+func (w *RotateWriter) Handover() (*os.File, RotateState, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return nil, RotateState{}, errors.New("ylog: no log file to hand over")
+	}
+	w.handedOver = true
+	state := RotateState{
+		LogDir:        w.logDir,
+		Name:          w.fname,
+		ID:            w.fid,
+		SequenceWidth: w.seqWidth,
+	}
+	return w.f, state, nil
+}
+
+// NewRotateWriterFromHandover returns a RotateWriter writing to f, the log file handed
+// over by the RotateWriter of the previous process with state, see Handover. It rotates
+// f as the previous writer would, by the time and size of the log file. The options of
+// the writer, e.g. the size limit and the compression, are not handed over and are
+// set as for NewRotateWriter. It returns an error if f is not the log file of state.
+func NewRotateWriterFromHandover(f *os.File, state RotateState) (*RotateWriter, error) {
+	return NewRotateWriterFromHandoverWithClock(f, state, SystemClock)
+}
+
+// NewRotateWriterFromHandoverWithClock is like NewRotateWriterFromHandover but takes the
+// current time from clock.
+func NewRotateWriterFromHandoverWithClock(f *os.File, state RotateState, clock Clock) (*RotateWriter, error) {
+	t, id, compressed, ok := parseLogFileName(state.Name)
+	if !ok || id != 0 || compressed || state.ID < 0 {
+		return nil, fmt.Errorf("ylog: invalid handover state %+v", state)
+	}
+	filePath := filepath.Join(state.LogDir, getLogFileName(t, state.ID, state.SequenceWidth))
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pathStat, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !os.SameFile(stat, pathStat) {
+		return nil, fmt.Errorf("ylog: handed over file is not %s", filePath)
+	}
+
+	w := &RotateWriter{
+		clock:        clock,
+		logDir:       state.LogDir,
+		logSizeLimit: DEFAULT_LOG_FILE_SIZE,
+		seqWidth:     state.SequenceWidth,
+		f:            f,
+		fpath:        filePath,
+		fname:        state.Name,
+		fid:          state.ID,
+		nbytes:       stat.Size(),
+	}
+	return w, nil
+}
+
+// NewRotateLoggerFromHandover returns a RotateLogger writing to the log file handed over
+// by the previous process, see NewRotateWriterFromHandover.
+func NewRotateLoggerFromHandover(f *os.File, state RotateState, level LogLevel) (*RotateLogger, error) {
+	w, err := NewRotateWriterFromHandover(f, state)
+	if err != nil {
+		return nil, err
+	}
+	return &RotateLogger{WriterLogger: NewWriterLogger(w, level), w: w}, nil
+}
+
+// Handover hands the current log file over to the process taking over, see
+// RotateWriter.Handover.
+func (l *RotateLogger) Handover() (*os.File, RotateState, error) {
+	return l.RotateWriter().Handover()
+}
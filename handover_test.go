@@ -0,0 +1,79 @@
+package ylog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateWriterHandover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)}
+	parent, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent.SetLogSizeLimit(10)
+	parent.SetTrailer(true)
+	parent.Write([]byte("0123456789\n"))
+	parent.Write([]byte("0123456789\n")) // too large
+	f, state, err := parent.Handover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(state)
+	var child RotateState
+	if err := json.Unmarshal(b, &child); err != nil || child != state {
+		t.Fatalf("state %s unmarshaled as %+v, %v", b, child, err)
+	}
+	if want := (RotateState{LogDir: dir, Name: "2024010215.log", ID: 1}); state != want {
+		t.Errorf("Handover() state = %+v, want %+v", state, want)
+	}
+
+	// the descriptor inherited by the child process
+	inherited, err := os.OpenFile(f.Name(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRotateWriterFromHandoverWithClock(inherited, child, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetLogSizeLimit(15)
+	w.Write([]byte("child\n"))
+	parent.Write([]byte("parent\n")) // no longer rotates
+	parent.Write([]byte("parent\n"))
+	parent.Rotate()
+	w.Write([]byte("child\n")) // too large
+	if err := parent.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	for name, want := range map[string]string{
+		"2024010215.log.1": "0123456789\nchild\nparent\nparent\n",
+		"2024010215.log.2": "child\n",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != want {
+			t.Errorf("log file %s contains %q, %v, want %q", name, b, err, want)
+		}
+	}
+
+	other, err := os.Create(filepath.Join(dir, "other.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err := NewRotateWriterFromHandover(other, state); err == nil {
+		t.Error("NewRotateWriterFromHandover accepted another file")
+	}
+}
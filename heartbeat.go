@@ -0,0 +1,56 @@
+package ylog
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Heartbeat logs a heartbeat entry with basic process stats at an interval, such as
+//
+//	INFO|uptime=1h0m0s|goroutines=12|heap_bytes=4194304|heartbeat
+//
+// so that the log based monitoring tells a quiet service from a dead one or a broken
+// logging. Call Close to stop it.
+type Heartbeat struct {
+	l    Logger
+	done chan struct{} // closed by Close
+	once sync.Once     // closes done
+}
+
+// NewHeartbeat returns a Heartbeat logging to l every interval, from now on.
+func NewHeartbeat(l Logger, interval time.Duration) *Heartbeat {
+	h := &Heartbeat{l: l, done: make(chan struct{})}
+	go h.run(interval)
+	return h
+}
+
+func (h *Heartbeat) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			h.Beat()
+		}
+	}
+}
+
+// Beat logs a heartbeat entry at INFO level, which is never dropped by the log level. It
+// is called every interval.
+func (h *Heartbeat) Beat() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	outputFieldsTo(h.l, 1, INFO, "heartbeat", []Field{
+		{"uptime", time.Since(processStart).Round(time.Second)},
+		{"goroutines", runtime.NumGoroutine()},
+		{"heap_bytes", ms.HeapAlloc},
+	})
+}
+
+// Close stops logging the heartbeats.
+func (h *Heartbeat) Close() {
+	h.once.Do(func() { close(h.done) })
+}
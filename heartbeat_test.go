@@ -0,0 +1,20 @@
+package ylog
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, ERROR)
+	l.SetFlags(0)
+	h := NewHeartbeat(l, time.Hour)
+	defer h.Close()
+	h.Beat()
+	if got := buf.String(); !regexp.MustCompile(`^INFO\|uptime=\w+\|goroutines=\d+\|heap_bytes=\d+\|heartbeat\n$`).MatchString(got) {
+		t.Fatalf("logged %q", got)
+	}
+}
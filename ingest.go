@@ -0,0 +1,74 @@
+package ylog
+
+import (
+	"io"
+	"strings"
+)
+
+// levelAliases are the other names of the log levels recognized by DetectLevel.
+var levelAliases = map[string]LogLevel{
+	"WARNING":  WARN,
+	"ERR":      ERROR,
+	"CRITICAL": FATAL,
+}
+
+// CopyFrom logs each line read from r to l at level, as LineWriter, until the end of r,
+// e.g. the content of a pipe or a socket, or the output of a legacy component. It returns
+// the error reading r, if not io.EOF.
+func CopyFrom(l Logger, level LogLevel, r io.Reader) error {
+	return copyFrom(&LineWriter{l: l, level: level}, r)
+}
+
+// CopyFromDetect is like CopyFrom but the lines starting with a log level, see
+// DetectLevel, are logged at that level without it.
+func CopyFromDetect(l Logger, level LogLevel, r io.Reader) error {
+	return copyFrom(&LineWriter{l: l, level: level, detect: true}, r)
+}
+
+func copyFrom(w *LineWriter, r io.Reader) error {
+	_, err := io.Copy(w, r)
+	w.Close()
+	return err
+}
+
+// DetectLevel returns the log level line starts with and the rest of line. The log
+// level is a log level name, in any case, or one of WARNING, ERR and CRITICAL, possibly
+// within brackets, followed by '|', ':' or a space, e.g. "ERROR|", "[warn] " or "info: ".
+// A space only follows the upper case or bracketed names, so that the lines starting
+// with a word such as "Error" are not stripped of it. ok is false if line does not start
+// with a log level.
+func DetectLevel(line string) (level LogLevel, rest string, ok bool) {
+	s := line
+	bracket := strings.HasPrefix(s, "[")
+	if bracket {
+		s = s[1:]
+	}
+	i := 0
+	for i < len(s) && ('a' <= s[i] && s[i] <= 'z' || 'A' <= s[i] && s[i] <= 'Z') {
+		i++
+	}
+	name := strings.ToUpper(s[:i])
+	upper := name == s[:i]
+	if level, ok = LogLevelMap[name]; !ok {
+		if level, ok = levelAliases[name]; !ok {
+			return 0, line, false
+		}
+	}
+	s = s[i:]
+	if bracket {
+		if !strings.HasPrefix(s, "]") {
+			return 0, line, false
+		}
+		s = s[1:]
+	}
+	switch {
+	case s == "":
+	case s[0] == '|' || s[0] == ':':
+		s = strings.TrimLeft(s[1:], " ")
+	case s[0] == ' ' && (bracket || upper):
+		s = strings.TrimLeft(s, " ")
+	default:
+		return 0, line, false
+	}
+	return level, s, true
+}
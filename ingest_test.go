@@ -0,0 +1,73 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCopyFrom(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+	in := "ERROR|disk failed\n[warn] disk almost full\ninfo: started\nError connecting\nplain"
+	if err := CopyFrom(l, DEBUG, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	want := "DEBUG|ERROR|disk failed\nDEBUG|[warn] disk almost full\nDEBUG|info: started\nDEBUG|Error connecting\nDEBUG|plain\n"
+	if buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := CopyFromDetect(l, DEBUG, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	want = "ERROR|disk failed\nWARN|disk almost full\nINFO|started\nDEBUG|Error connecting\nDEBUG|plain\n"
+	if buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+
+	errRead := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("partial"), &errReader{errRead})
+	buf.Reset()
+	if err := CopyFrom(l, DEBUG, r); err != errRead {
+		t.Fatalf("CopyFrom returned %v, want %v", err, errRead)
+	}
+	if want := "DEBUG|partial\n"; buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDetectLevel(t *testing.T) {
+	for _, tc := range []struct {
+		line  string
+		level LogLevel
+		rest  string
+		ok    bool
+	}{
+		{"ERROR|disk failed", ERROR, "disk failed", true},
+		{"WARNING: disk almost full", WARN, "disk almost full", true},
+		{"[Info] started", INFO, "started", true},
+		{"TRACE", TRACE, "", true},
+		{"Debug enabled", 0, "Debug enabled", false},
+		{"[ERROR disk failed", 0, "[ERROR disk failed", false},
+		{"INFORMATION", 0, "INFORMATION", false},
+	} {
+		level, rest, ok := DetectLevel(tc.line)
+		if level != tc.level || rest != tc.rest || ok != tc.ok {
+			t.Errorf("DetectLevel(%q) = %v, %q, %v, want %v, %q, %v", tc.line, level, rest, ok, tc.level, tc.rest, tc.ok)
+		}
+	}
+}
+
+// errReader is an io.Reader failing with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,178 @@
+package ylog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Configuration is the effective configuration of a logger, see Config.
+type Configuration struct {
+	Options           // log level, log dir, rotation and retention of the log files
+	Flags    string   `json:"flags"`              // properties of the header, e.g. "Ldate|Ltime"
+	Manifest bool     `json:"manifest,omitempty"` // maintain a manifest of the log files
+	Preamble bool     `json:"preamble,omitempty"` // write a preamble at the top of new log files
+	Trailer  bool     `json:"trailer,omitempty"`  // write a trailer at the end of log files
+	Sinks    []string `json:"sinks"`              // destinations for output, e.g. "stderr"
+	VModule  string   `json:"vmodule,omitempty"`  // log levels by source file pattern, see SetVModule
+}
+
+// Config returns the effective configuration of the default logger.
+func Config() Configuration {
+	return ConfigOf(DefaultLogger())
+}
+
+// ConfigOf returns the effective configuration of l. The destinations of a Router are
+// all reported, its options are those of the first destination logging to files.
+func ConfigOf(l Logger) Configuration {
+	switch l := l.(type) {
+	case interface{ configuration() Configuration }:
+		return l.configuration()
+	case *TestingLogger:
+		return Configuration{Options: Options{Level: l.LogLevel().LogLevelName()}, Sinks: []string{"testing"}}
+	default:
+		return Configuration{Options: Options{Level: logLevelOf(l).LogLevelName()}, Sinks: []string{fmt.Sprintf("%T", l)}}
+	}
+}
+
+func (l *WriterLogger) configuration() Configuration {
+	c := Configuration{Options: Options{Level: l.LogLevel().LogLevelName()}, Flags: flagNames(l.Flags()), VModule: l.VModule()}
+	c.addSink(l.Writer())
+	return c
+}
+
+func (l *prefixLogger) configuration() Configuration {
+	return ConfigOf(l.inner)
+}
+
+func (l *filteredLogger) configuration() Configuration {
+	c := ConfigOf(l.inner)
+	c.Level = l.lowestLevel().LogLevelName()
+	return c
+}
+
+func (l *staticFieldsLogger) configuration() Configuration {
+	return ConfigOf(l.inner)
+}
+
+func (l *groupLogger) configuration() Configuration {
+	return ConfigOf(l.inner)
+}
+
+func (s *AdaptiveSampler) configuration() Configuration {
+	return ConfigOf(s.inner)
+}
+
+func (l *ShadowLogger) configuration() Configuration {
+	return ConfigOf(l.primary)
+}
+
+func (r *Router) configuration() Configuration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := Configuration{Options: Options{Level: minLogLevel(r.defaults, r.levels).LogLevelName()}}
+	add := func(tag string, dests []Logger) {
+		for _, dest := range dests {
+			dc := ConfigOf(dest)
+			if c.LogDir == "" && dc.LogDir != "" {
+				level, flags, sinks := c.Level, c.Flags, c.Sinks
+				c = dc
+				c.Level, c.Flags, c.Sinks = level, flags, sinks
+			}
+			if c.Flags == "" {
+				c.Flags = dc.Flags
+			}
+			for _, sink := range dc.Sinks {
+				if tag != "" {
+					sink = tag + ":" + sink
+				}
+				c.Sinks = append(c.Sinks, sink)
+			}
+		}
+	}
+	add("", r.defaults)
+	tags := make([]string, 0, len(r.routes))
+	for tag := range r.routes {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		add(tag, r.routes[tag])
+	}
+	return c
+}
+
+// addSink reports w as a destination for output.
+func (c *Configuration) addSink(w io.Writer) {
+	switch w := w.(type) {
+	case *RotateWriter:
+		w.mu.Lock()
+		c.LogDir = w.logDir
+		c.LogSizeLimit = Size(w.logSizeLimit)
+		c.MaxAge = w.maxAge
+		c.MaxFiles = w.maxFiles
+		c.Compress = w.compress
+		c.SequenceWidth = w.seqWidth
+		c.Manifest = w.manifest
+		c.Preamble = w.preamble != nil
+		c.Trailer = w.trailer
+		w.mu.Unlock()
+		c.Sinks = append(c.Sinks, "files:"+c.LogDir)
+	case *AsyncWriter:
+		n := len(c.Sinks)
+		c.addSink(w.out)
+		c.Sinks[n] = "async:" + c.Sinks[n]
+	case *BufferedWriter:
+		n := len(c.Sinks)
+		c.addSink(w.out)
+		c.Sinks[n] = "buffered:" + c.Sinks[n]
+	default:
+		c.Sinks = append(c.Sinks, writerName(w))
+	}
+}
+
+// writerName returns a description of w.
+func writerName(w io.Writer) string {
+	switch w {
+	case os.Stderr:
+		return "stderr"
+	case os.Stdout:
+		return "stdout"
+	}
+	if f, ok := w.(*os.File); ok {
+		return "file:" + f.Name()
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+var flagNameList = []struct {
+	flag int
+	name string
+}{
+	{Ldate, "Ldate"},
+	{Ltime, "Ltime"},
+	{Lmicroseconds, "Lmicroseconds"},
+	{Llongfile, "Llongfile"},
+	{Lshortfile, "Lshortfile"},
+	{LUTC, "LUTC"},
+	{Lfuncname, "Lfuncname"},
+	{Lloglevel, "Lloglevel"},
+	{Lnologlevel, "Lnologlevel"},
+	{Lshortfunc, "Lshortfunc"},
+	{Lsequence, "Lsequence"},
+	{Lpid, "Lpid"},
+	{Lhostname, "Lhostname"},
+}
+
+// flagNames returns the names of the flags in flags, separated by '|'.
+func flagNames(flags int) string {
+	var names []string
+	for _, f := range flagNameList {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,200 @@
+package ylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONEncoder is an Encoder of the entries as JSON objects, one per line, in the JSON
+// form of WebSocketHandler, such as
+//
+//	{"time":"2024-01-02T15:04:05.123456Z","level":"ERROR","file":"/src/app/main.go","line":42,"func":"main.charge","message":"payment failed","fields":{"order":42}}
+//
+// The entries are appended to the buffer without allocation for the fields of the common
+// types: strings, booleans, integers, floats, errors, time.Duration, as nanoseconds,
+// time.Time and the Bytes fields. The other values are encoded by encoding/json, or as
+// their string forms if they are not marshalable.
+//
+// The stamp of the version of the schema is opt-in: the entries are stamped only if
+// SchemaVersion is set, as the first member of the objects, e.g.
+// {"schema_version":1,"time":...}, so that the parsers tell the entries of the schemas of
+// different releases apart. The zero JSONEncoder encodes the entries unstamped, as the
+// releases before the stamp did; NewLogger sets JSONSchemaLatest in ModeStdoutJSON. The
+// other encoders, CEFEncoder, LEEFEncoder and DockerEncoder, follow the schemas of their
+// formats and are not stamped, but for the log lines of a DockerEncoder whose Encoder is
+// a JSONEncoder with SchemaVersion set.
+type JSONEncoder struct {
+	SchemaVersion int // version of the schema of the entries, e.g. JSONSchemaV1, no stamp if zero
+}
+
+// Versions of the schema of the entries encoded by JSONEncoder. The members are added
+// or renamed in new versions only, the previous versions remain encoded as they are.
+const (
+	JSONSchemaV1     = 1            // time, level, file, line, func, message and fields
+	JSONSchemaLatest = JSONSchemaV1 // latest version, the entries of unknown versions are encoded in it
+)
+
+// Encode appends e to buf as a JSON object.
+func (enc JSONEncoder) Encode(buf []byte, e *Entry) []byte {
+	buf = append(buf, '{')
+	if v := enc.SchemaVersion; v != 0 {
+		if v < 0 || v > JSONSchemaLatest {
+			v = JSONSchemaLatest
+		}
+		buf = append(buf, `"schema_version":`...)
+		buf = strconv.AppendInt(buf, int64(v), 10)
+		buf = append(buf, ',')
+	}
+	buf = append(buf, `"time":"`...)
+	buf = e.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, '"')
+	if e.Level != noLevel {
+		buf = append(buf, `,"level":"`...)
+		buf = append(buf, e.Level.LogLevelName()...)
+		buf = append(buf, '"')
+	}
+	buf = append(buf, `,"file":`...)
+	buf = appendJSONString(buf, e.File)
+	buf = append(buf, `,"line":`...)
+	buf = strconv.AppendInt(buf, int64(e.Line), 10)
+	buf = append(buf, `,"func":`...)
+	buf = appendJSONString(buf, e.Func)
+	buf = append(buf, `,"message":`...)
+	buf = appendJSONString(buf, e.Message)
+	if len(e.Fields) > 0 {
+		buf = append(buf, `,"fields":{`...)
+		for i, f := range e.Fields {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONString(buf, f.Key)
+			buf = append(buf, ':')
+			buf = appendJSONValue(buf, f.Value)
+		}
+		buf = append(buf, '}')
+	}
+	return append(buf, "}\n"...)
+}
+
+// appendJSONValue appends v to buf as a JSON value.
+func appendJSONValue(buf []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendJSONString(buf, v)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case float32:
+		return appendJSONFloat(buf, float64(v), 32)
+	case float64:
+		return appendJSONFloat(buf, v, 64)
+	case time.Duration:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case time.Time:
+		buf = append(buf, '"')
+		buf = v.AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, '"')
+	case error:
+		return appendJSONString(buf, v.Error())
+	case bytesValue:
+		// the cut bytes marker within the string
+		buf = appendJSONString(buf, v.s)
+		buf = v.appendCut(buf[:len(buf)-1])
+		return append(buf, '"')
+	case SecretValue:
+		return appendJSONString(buf, SecretMask+v.last)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(buf, fmt.Sprint(v))
+	}
+	return append(buf, b...)
+}
+
+// appendJSONFloat appends f to buf as a JSON number, or as a string if it is not finite.
+func appendJSONFloat(buf []byte, f float64, bits int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		buf = append(buf, '"')
+		buf = strconv.AppendFloat(buf, f, 'g', -1, bits)
+		return append(buf, '"')
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, bits)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s to buf as a JSON string, replacing the invalid UTF-8 by
+// U+FFFD as encoding/json.
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			// line separators of JavaScript
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
@@ -0,0 +1,71 @@
+package ylog
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONEncoder(t *testing.T) {
+	at := time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.UTC)
+	e := &Entry{
+		Time:    at,
+		Level:   ERROR,
+		File:    "/src/app/main.go",
+		Line:    42,
+		Func:    "main.charge",
+		Message: "payment \"failed\"\n\x01\xff ",
+		Fields: []Field{
+			{"order", 42}, {"ok", false}, {"amount", 9.5}, {"took", time.Second}, {"at", at},
+			{"err", errors.New("declined")}, {"tags", []string{"a"}}, {"nan", math.NaN()}, {"none", nil},
+		},
+	}
+	b := JSONEncoder{}.Encode(nil, e)
+	if b[len(b)-1] != '\n' {
+		t.Fatalf("encoded %q without newline", b)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("encoded %s: %v", b, err)
+	}
+	want := map[string]interface{}{
+		"time":    "2024-01-02T15:04:05.123456Z",
+		"level":   "ERROR",
+		"file":    "/src/app/main.go",
+		"line":    42.0,
+		"func":    "main.charge",
+		"message": "payment \"failed\"\n\x01� ",
+		"fields": map[string]interface{}{
+			"order": 42.0, "ok": false, "amount": 9.5, "took": 1e9, "at": "2024-01-02T15:04:05.123456Z",
+			"err": "declined", "tags": []interface{}{"a"}, "nan": "NaN", "none": nil,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("encoded %s, want %v", b, want)
+	}
+}
+
+func TestJSONEncoderSchemaVersion(t *testing.T) {
+	e := &Entry{Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Level: INFO, File: "main.go", Line: 1, Message: "served"}
+	for version, want := range map[int]string{
+		0:                    `{"time":"2024-01-02T15:04:05Z","level":"INFO","file":"main.go","line":1,"func":"","message":"served"}`,
+		JSONSchemaV1:         `{"schema_version":1,"time":"2024-01-02T15:04:05Z","level":"INFO","file":"main.go","line":1,"func":"","message":"served"}`,
+		JSONSchemaLatest + 1: `{"schema_version":1,"time":"2024-01-02T15:04:05Z","level":"INFO","file":"main.go","line":1,"func":"","message":"served"}`,
+	} {
+		if got := string(JSONEncoder{SchemaVersion: version}.Encode(nil, e)); got != want+"\n" {
+			t.Errorf("version %d encoded %s, want %s", version, got, want)
+		}
+	}
+}
+
+func TestJSONEncoderAllocs(t *testing.T) {
+	e := &Entry{Time: time.Now(), Level: INFO, File: "main.go", Line: 1, Func: "main.main", Message: "served",
+		Fields: []Field{{"status", 200}, {"path", "/"}, {"took", time.Millisecond}}}
+	buf := make([]byte, 0, 1024)
+	if n := testing.AllocsPerRun(100, func() { buf = JSONEncoder{}.Encode(buf[:0], e) }); n != 0 {
+		t.Fatalf("Encode allocates %v times", n)
+	}
+}
@@ -0,0 +1,71 @@
+package ylog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kubernetesNamespaceFile is the namespace of the pod in the service account token
+// volume, mounted in the containers by default.
+var kubernetesNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// kubernetesFields are the keys of the fields of the Kubernetes identity, with the
+// environment variables and the downward API files they are read from.
+var kubernetesFields = []struct {
+	key, env, file string
+}{
+	{"k8s.pod", "POD_NAME", "pod_name"},
+	{"k8s.namespace", "POD_NAMESPACE", "pod_namespace"},
+	{"k8s.node", "NODE_NAME", "node_name"},
+	{"k8s.container", "CONTAINER_NAME", "container_name"},
+}
+
+// KubernetesFields returns the Kubernetes identity of the process, as the fields
+// "k8s.pod", "k8s.namespace", "k8s.node" and "k8s.container", which are read from the
+// environment variables POD_NAME, POD_NAMESPACE, NODE_NAME and CONTAINER_NAME, or from
+// the files pod_name, pod_namespace, node_name and container_name of dir, a downward
+// API volume, e.g.
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: metadata.name
+//
+// The environment variables take precedence, give "" as dir to read them only. In a
+// cluster, the pod name defaults to the host name and the namespace to the one of the
+// service account. The fields unknown are omitted, none outside a cluster.
+func KubernetesFields(dir string) []Field {
+	var fields []Field
+	for _, kf := range kubernetesFields {
+		v := strings.TrimSpace(os.Getenv(kf.env))
+		if v == "" && dir != "" {
+			// ignore error, the field is omitted
+			b, _ := ioutil.ReadFile(filepath.Join(dir, kf.file))
+			v = strings.TrimSpace(string(b))
+		}
+		if v == "" && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+			switch kf.env {
+			case "POD_NAME":
+				v = hostname
+			case "POD_NAMESPACE":
+				b, _ := ioutil.ReadFile(kubernetesNamespaceFile)
+				v = strings.TrimSpace(string(b))
+			}
+		}
+		if v != "" {
+			fields = append(fields, Field{kf.key, v})
+		}
+	}
+	return fields
+}
+
+// WithKubernetesFields returns a Logger passing the entries logged through it to inner
+// with the Kubernetes identity of the process, see KubernetesFields and
+// WithStaticFields, so that the logs shipped from files carry it. The identity is read
+// once.
+func WithKubernetesFields(inner Logger, dir string) Logger {
+	return WithStaticFields(inner, KubernetesFields(dir))
+}
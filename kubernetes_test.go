@@ -0,0 +1,46 @@
+package ylog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKubernetesFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { kubernetesNamespaceFile = path }(kubernetesNamespaceFile)
+	kubernetesNamespaceFile = filepath.Join(dir, "namespace")
+
+	for _, env := range []string{"POD_NAME", "POD_NAMESPACE", "NODE_NAME", "CONTAINER_NAME", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(env, "")
+	}
+	if fields := KubernetesFields(dir); len(fields) != 0 {
+		t.Errorf("KubernetesFields() = %v outside a cluster", fields)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "node_name"), []byte("node-1\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "container_name"), []byte("app\n"), 0644)
+	ioutil.WriteFile(kubernetesNamespaceFile, []byte("shop"), 0644)
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("CONTAINER_NAME", "billing")
+	want := []Field{{"k8s.pod", hostname}, {"k8s.namespace", "shop"}, {"k8s.node", "node-1"}, {"k8s.container", "billing"}}
+	if got := KubernetesFields(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("KubernetesFields() = %v, want %v", got, want)
+	}
+
+	t.Setenv("POD_NAME", "billing-7d4f")
+	var buf bytes.Buffer
+	inner := NewWriterLogger(&buf, TRACE)
+	inner.SetFlags(Lloglevel)
+	WithKubernetesFields(inner, "").Info("charged")
+	if want := "INFO|k8s.pod=billing-7d4f|k8s.namespace=shop|k8s.container=billing|charged\n"; buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+}
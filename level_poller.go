@@ -0,0 +1,179 @@
+package ylog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LevelSource returns the level spec to apply, "" to keep the current log levels. A level
+// spec is a log level name and the log levels of packages as "pkg=LEVEL", see
+// SetPackageLevel, separated by commas, e.g. "INFO,github.com/us/app/db=DEBUG". The
+// package levels of a spec which the next one lacks are cleared.
+type LevelSource func() (string, error)
+
+// LevelFile returns a LevelSource reading the level spec from the file at path,
+// e.g. a Kubernetes ConfigMap mounted at /etc/ylog/level. A missing file keeps the
+// current log level.
+func LevelFile(path string) LevelSource {
+	return func() (string, error) {
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return strings.TrimSpace(string(b)), err
+	}
+}
+
+// LevelEnv returns a LevelSource reading the level spec from the environment variable
+// name.
+func LevelEnv(name string) LevelSource {
+	return func() (string, error) {
+		return strings.TrimSpace(os.Getenv(name)), nil
+	}
+}
+
+// LevelPoller polls a LevelSource at an interval and applies the changes of log levels
+// to a logger, so that they can be changed without an in-process endpoint. As only the
+// changes of the source are applied, the log level set otherwise, e.g. by LevelHandler,
+// is kept until the source changes. Call Close to stop polling.
+type LevelPoller struct {
+	l    LevelLogger
+	src  LevelSource
+	done chan struct{} // closed by Close
+	once sync.Once     // closes done
+	err  atomic.Value  // errorHolder, error of the last poll
+
+	mu   sync.Mutex // serializes the polls; protects the following fields
+	last string     // level spec returned by the last poll
+	pkgs []string   // packages whose log level was set by the last level spec
+}
+
+// NewLevelPoller returns a LevelPoller applying the level spec of src to l every
+// interval, starting with the current one. The sources blocking until a change, such
+// as ConsulLevel and EtcdLevel, are polled again after interval once they return.
+func NewLevelPoller(l LevelLogger, src LevelSource, interval time.Duration) *LevelPoller {
+	p := &LevelPoller{l: l, src: src, done: make(chan struct{})}
+	p.Poll()
+	go p.run(interval)
+	return p
+}
+
+func (p *LevelPoller) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.Poll()
+		}
+	}
+}
+
+// Poll applies the level spec of the source if it changed since the last poll. It
+// returns the error reading the source or of an invalid level spec, which is also
+// returned by Err until the next poll.
+func (p *LevelPoller) Poll() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.poll()
+	p.err.Store(errorHolder{err})
+	return err
+}
+
+func (p *LevelPoller) poll() error {
+	spec, err := p.src()
+	if err != nil {
+		return err
+	}
+	if spec == p.last {
+		return nil
+	}
+	if spec != "" {
+		if err := p.apply(spec); err != nil {
+			return err
+		}
+	}
+	p.last = spec
+	return nil
+}
+
+// apply applies the level spec, clearing the package levels of the previous one which
+// it lacks.
+func (p *LevelPoller) apply(spec string) error {
+	level, hasLevel, pkgs, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	pl, ok := p.l.(packageLeveler)
+	if len(pkgs) > 0 && !ok {
+		return fmt.Errorf("ylog: logger does not support package levels")
+	}
+	if hasLevel {
+		p.l.SetLogLevel(level)
+	}
+	if ok {
+		for _, pkg := range p.pkgs {
+			if _, ok := pkgs[pkg]; !ok {
+				pl.ClearPackageLevel(pkg)
+			}
+		}
+		p.pkgs = p.pkgs[:0]
+		for pkg, level := range pkgs {
+			pl.SetPackageLevel(pkg, level)
+			p.pkgs = append(p.pkgs, pkg)
+		}
+	}
+	return nil
+}
+
+// packageLeveler is implemented by the loggers with package levels, such as WriterLogger.
+type packageLeveler interface {
+	SetPackageLevel(pkg string, level LogLevel)
+	ClearPackageLevel(pkg string)
+}
+
+// parseLevelSpec parses a level spec: a log level name and the log levels of packages
+// as "pkg=LEVEL", separated by commas, e.g. "INFO,github.com/us/app/db=DEBUG".
+func parseLevelSpec(spec string) (level LogLevel, hasLevel bool, pkgs map[string]LogLevel, err error) {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pkg, name := "", item
+		if i := strings.LastIndexByte(item, '='); i >= 0 {
+			pkg, name = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+		l, ok := LogLevelMap[strings.ToUpper(name)]
+		if !ok {
+			return 0, false, nil, fmt.Errorf("ylog: unknown log level %q", name)
+		}
+		if pkg == "" {
+			level, hasLevel = l, true
+			continue
+		}
+		if pkgs == nil {
+			pkgs = make(map[string]LogLevel)
+		}
+		pkgs[pkg] = l
+	}
+	return level, hasLevel, pkgs, nil
+}
+
+// Err returns the error of the last poll.
+func (p *LevelPoller) Err() error {
+	h, _ := p.err.Load().(errorHolder)
+	return h.err
+}
+
+// Close stops polling.
+func (p *LevelPoller) Close() {
+	p.once.Do(func() { close(p.done) })
+}
@@ -0,0 +1,41 @@
+package ylog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLevelPoller(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level")
+	l := NewWriterLogger(ioutil.Discard, INFO)
+	p := NewLevelPoller(l, LevelFile(path), time.Hour)
+	defer p.Close()
+	if err := p.Err(); err != nil || l.LogLevel() != INFO {
+		t.Fatalf("missing file: level %v, error %v, want INFO kept", l.LogLevel().LogLevelName(), err)
+	}
+
+	ioutil.WriteFile(path, []byte("debug\n"), 0644)
+	if err := p.Poll(); err != nil || l.LogLevel() != DEBUG {
+		t.Fatalf("level %v, error %v, want DEBUG", l.LogLevel().LogLevelName(), err)
+	}
+
+	// kept until the file changes
+	l.SetLogLevel(WARN)
+	if err := p.Poll(); err != nil || l.LogLevel() != WARN {
+		t.Fatalf("level %v, error %v, want WARN kept", l.LogLevel().LogLevelName(), err)
+	}
+
+	ioutil.WriteFile(path, []byte("verbose"), 0644)
+	if err := p.Poll(); err == nil || p.Err() == nil || l.LogLevel() != WARN {
+		t.Fatalf("level %v, error %v, want an error", l.LogLevel().LogLevelName(), err)
+	}
+}
+
+func TestLevelEnv(t *testing.T) {
+	t.Setenv("YLOG_TEST_LEVEL", " ERROR ")
+	if name, err := LevelEnv("YLOG_TEST_LEVEL")(); name != "ERROR" || err != nil {
+		t.Fatalf("LevelEnv returned %q, %v, want ERROR", name, err)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build !ylog_nodebug
+// +build !ylog_nodebug
+
+package ylog
+
+import (
+	"fmt"
+)
+
+// DebugStripped reports whether the TRACE and DEBUG methods of the loggers are stripped
+// by the ylog_nodebug build tag. With it, they have empty bodies inlined at the calls on
+// the concrete loggers, such as *WriterLogger, which removes the check of the log level
+// and the boxing of the arguments. Guard the calls through the Logger interface, or
+// whose arguments are costly, with it to remove them from the binary:
+//
+//	if !ylog.DebugStripped {
+//		l.Debugf("state %v", expensive())
+//	}
+//
+// The entries output by Output and TestingLogger are not stripped.
+const DebugStripped = false
+
+func (l *WriterLogger) Tracef(format string, v ...interface{}) {
+	if l.lowestLevel() <= TRACE {
+		l.output(2, TRACE, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *WriterLogger) Trace(v ...interface{}) {
+	if l.lowestLevel() <= TRACE {
+		l.outputln(2, TRACE, v)
+	}
+}
+
+func (l *WriterLogger) Debugf(format string, v ...interface{}) {
+	if l.lowestLevel() <= DEBUG {
+		l.output(2, DEBUG, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *WriterLogger) Debug(v ...interface{}) {
+	if l.lowestLevel() <= DEBUG {
+		l.outputln(2, DEBUG, v)
+	}
+}
+
+func (m loggerMethods) Tracef(format string, v ...interface{}) {
+	if m.l.lowestLevel() <= TRACE {
+		m.l.output(2, TRACE, fmt.Sprintf(format, v...))
+	}
+}
+
+func (m loggerMethods) Trace(v ...interface{}) {
+	if m.l.lowestLevel() <= TRACE {
+		m.l.output(2, TRACE, fmt.Sprintln(v...))
+	}
+}
+
+func (m loggerMethods) Debugf(format string, v ...interface{}) {
+	if m.l.lowestLevel() <= DEBUG {
+		m.l.output(2, DEBUG, fmt.Sprintf(format, v...))
+	}
+}
+
+func (m loggerMethods) Debug(v ...interface{}) {
+	if m.l.lowestLevel() <= DEBUG {
+		m.l.output(2, DEBUG, fmt.Sprintln(v...))
+	}
+}
+
+func (l *WriterLogger) Tracet(template string, fields ...Field) {
+	if l.lowestLevel() <= TRACE {
+		l.outputFields(2, TRACE, renderTemplate(template, fields), fields)
+	}
+}
+
+func (l *WriterLogger) Debugt(template string, fields ...Field) {
+	if l.lowestLevel() <= DEBUG {
+		l.outputFields(2, DEBUG, renderTemplate(template, fields), fields)
+	}
+}
+
+func (m loggerMethods) Tracet(template string, fields ...Field) {
+	if m.l.lowestLevel() <= TRACE {
+		outputTemplate(m.l, 2, TRACE, template, fields)
+	}
+}
+
+func (m loggerMethods) Debugt(template string, fields ...Field) {
+	if m.l.lowestLevel() <= DEBUG {
+		outputTemplate(m.l, 2, DEBUG, template, fields)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build ylog_nodebug
+// +build ylog_nodebug
+
+package ylog
+
+// DebugStripped reports whether the TRACE and DEBUG methods of the loggers are stripped
+// by the ylog_nodebug build tag.
+const DebugStripped = true
+
+func (l *WriterLogger) Tracef(format string, v ...interface{}) {}
+
+func (l *WriterLogger) Trace(v ...interface{}) {}
+
+func (l *WriterLogger) Debugf(format string, v ...interface{}) {}
+
+func (l *WriterLogger) Debug(v ...interface{}) {}
+
+func (m loggerMethods) Tracef(format string, v ...interface{}) {}
+
+func (m loggerMethods) Trace(v ...interface{}) {}
+
+func (m loggerMethods) Debugf(format string, v ...interface{}) {}
+
+func (m loggerMethods) Debug(v ...interface{}) {}
+
+func (l *WriterLogger) Tracet(template string, fields ...Field) {}
+
+func (l *WriterLogger) Debugt(template string, fields ...Field) {}
+
+func (m loggerMethods) Tracet(template string, fields ...Field) {}
+
+func (m loggerMethods) Debugt(template string, fields ...Field) {}
@@ -0,0 +1,30 @@
+//go:build ylog_nodebug
+// +build ylog_nodebug
+
+package ylog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDebugStripped(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+	for _, logger := range []Logger{l, WithMinLevel(l, TRACE)} {
+		logger.Trace("a")
+		logger.Tracef("%s", "b")
+		logger.Debug("c")
+		logger.Debugf("%s", "d")
+	}
+	l.Tracet("{x}", String("x", "e"))
+	l.Debugt("{x}", String("x", "f"))
+	if buf.Len() != 0 {
+		t.Fatalf("stripped methods logged %q", buf.String())
+	}
+	l.Warn("g")
+	if got := buf.String(); got != "WARN|g\n" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -1,5 +1,16 @@
 package ylog
 
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // log level
 type LogLevel int32
 
@@ -13,6 +24,9 @@ const (
 	FATAL
 )
 
+// noLevel is the log level of content without log level, such as the content given to Output.
+const noLevel LogLevel = -1
+
 func (level LogLevel) LogLevelName() string {
 	switch level {
 	case TRACE:
@@ -42,6 +56,24 @@ var (
 	}
 )
 
+// Entry is a log entry.
+type Entry struct {
+	Time    time.Time // time of the entry
+	Level   LogLevel  // log level of the entry
+	File    string    // file name of the caller
+	Line    int       // line number of the caller
+	Func    string    // fully qualified function name of the caller
+	Message string    // message without the header and the trailing newline
+	Fields  []Field   // fields written between the header and the message
+	Seq     uint64    // sequence number of the entry in the logger, from 1, see Lsequence
+}
+
+// Field is a key/value pair attached to an entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
 type Logger interface {
 	Tracef(format string, v ...interface{})
 	Trace(v ...interface{})
@@ -61,3 +93,439 @@ type Logger interface {
 	Fatalf(format string, v ...interface{})
 	Fatal(v ...interface{})
 }
+
+// levelHolder holds the log level of a logger.
+// It is embedded by loggers to provide the log level accessors.
+type levelHolder struct {
+	level     LogLevel     // log level, accessed atomically
+	lowest    LogLevel     // lowest of level and package levels, accessed atomically
+	pkgLevels atomic.Value // map[string]LogLevel, log levels by package path, copy on write
+	vmodules  atomic.Value // *vmodule, log levels by source file pattern, see SetVModule
+
+	mu           sync.Mutex  // serializes the changes; protects the following fields
+	restoreTimer *time.Timer // pending restore of a temporary log level
+	restoreLevel LogLevel    // log level to restore when restoreTimer fires
+}
+
+// LogLevel returns the log level for the logger
+func (h *levelHolder) LogLevel() LogLevel {
+	return LogLevel(atomic.LoadInt32((*int32)(&h.level)))
+}
+
+// SetLogLevel sets log level for the logger.
+// It cancels the pending restore of a previous SetLogLevelFor call.
+func (h *levelHolder) SetLogLevel(level LogLevel) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.restoreTimer != nil {
+		h.restoreTimer.Stop()
+		h.restoreTimer = nil
+	}
+	h.setLogLevel(level)
+}
+
+// SetLogLevelFor sets log level for the logger during d, then restores the previous log level.
+// Successive calls extend the period and restore the log level prior to the first call.
+func (h *levelHolder) SetLogLevelFor(level LogLevel, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.restoreTimer != nil {
+		h.restoreTimer.Stop()
+	} else {
+		h.restoreLevel = h.LogLevel()
+	}
+	h.setLogLevel(level)
+
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.restoreTimer != t { // superseded by a later call
+			return
+		}
+		h.restoreTimer = nil
+		h.setLogLevel(h.restoreLevel)
+	})
+	h.restoreTimer = t
+}
+
+// SetPackageLevel sets the log level for the entries logged from the package with path pkg
+// and its subpackages, e.g. "github.com/us/app/storage", overriding the log level of the
+// logger. The most specific package wins.
+func (h *levelHolder) SetPackageLevel(pkg string, level LogLevel) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.packageLevels()
+	m := make(map[string]LogLevel, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[strings.TrimSuffix(pkg, "/")] = level
+	h.pkgLevels.Store(m)
+	h.updateLowest()
+}
+
+// ClearPackageLevel removes the log level for the package with path pkg.
+func (h *levelHolder) ClearPackageLevel(pkg string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pkg = strings.TrimSuffix(pkg, "/")
+	old := h.packageLevels()
+	if _, ok := old[pkg]; !ok {
+		return
+	}
+	m := make(map[string]LogLevel, len(old))
+	for k, v := range old {
+		if k != pkg {
+			m[k] = v
+		}
+	}
+	h.pkgLevels.Store(m)
+	h.updateLowest()
+}
+
+// PackageLevels returns the log levels by package path.
+func (h *levelHolder) PackageLevels() map[string]LogLevel {
+	m := make(map[string]LogLevel)
+	for k, v := range h.packageLevels() {
+		m[k] = v
+	}
+	return m
+}
+
+func (h *levelHolder) packageLevels() map[string]LogLevel {
+	m, _ := h.pkgLevels.Load().(map[string]LogLevel)
+	return m
+}
+
+// setLogLevel sets the log level, h.mu must be held.
+func (h *levelHolder) setLogLevel(level LogLevel) {
+	atomic.StoreInt32((*int32)(&h.level), int32(level))
+	h.updateLowest()
+}
+
+// updateLowest updates the lowest log level, h.mu must be held.
+func (h *levelHolder) updateLowest() {
+	lowest := h.LogLevel()
+	for _, level := range h.packageLevels() {
+		if level < lowest {
+			lowest = level
+		}
+	}
+	if v := h.vmodule(); v != nil {
+		for _, rule := range v.rules {
+			if rule.level < lowest {
+				lowest = rule.level
+			}
+		}
+	}
+	atomic.StoreInt32((*int32)(&h.lowest), int32(lowest))
+}
+
+// lowestLevel returns the lowest log level of the entries which may be output.
+func (h *levelHolder) lowestLevel() LogLevel {
+	return LogLevel(atomic.LoadInt32((*int32)(&h.lowest)))
+}
+
+// levelFor returns the log level for the entries logged from function fn in file.
+func (h *levelHolder) levelFor(file, fn string) LogLevel {
+	if v := h.vmodule(); v != nil {
+		if level, ok := v.levelFor(file); ok {
+			return level
+		}
+	}
+	m := h.packageLevels()
+	if len(m) == 0 {
+		return h.LogLevel()
+	}
+	// look up the package and then its parents
+	for pkg := pkgPath(fn); pkg != ""; {
+		if level, ok := m[pkg]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(pkg, '/')
+		if i < 0 {
+			break
+		}
+		pkg = pkg[:i]
+	}
+	return h.LogLevel()
+}
+
+// enabled reports whether the entry with log level from function fn in file is output.
+func (h *levelHolder) enabled(level LogLevel, file, fn string) bool {
+	return level == noLevel || level >= INFO || h.levelFor(file, fn) <= level
+}
+
+// Clock provides the current time to loggers and writers.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the Clock returning time.Now().
+var SystemClock Clock = systemClock{}
+
+// MonotonicClock is a Clock returning the wall time of its creation plus the monotonic
+// time elapsed since, rather than the wall time, so that the time of the entries never
+// goes backwards, e.g. when NTP steps the wall clock, and their order reconstructs the
+// timeline of an incident. It drifts from the wall clock by the steps.
+type MonotonicClock struct {
+	anchor time.Time // wall and monotonic time of the creation
+}
+
+// NewMonotonicClock returns a MonotonicClock anchored at the current time, e.g. for
+// SetClock at the startup.
+func NewMonotonicClock() *MonotonicClock {
+	return &MonotonicClock{anchor: time.Now()}
+}
+
+// Now returns the anchor plus the monotonic time elapsed since.
+func (c *MonotonicClock) Now() time.Time {
+	return c.anchor.Add(time.Since(c.anchor)).Round(0)
+}
+
+// CoarseClock is a Clock returning a time cached and refreshed at a granularity, e.g.
+// 1ms, rather than calling time.Now for each entry, trading the precision of the time of
+// the entries for the throughput of the loggers on hot paths. Call Stop to stop
+// refreshing it.
+type CoarseClock struct {
+	now  atomic.Value  // time.Time, cached time
+	done chan struct{} // closed by Stop
+	once sync.Once     // closes done
+}
+
+// NewCoarseClock returns a CoarseClock refreshed every granularity.
+func NewCoarseClock(granularity time.Duration) *CoarseClock {
+	c := &CoarseClock{done: make(chan struct{})}
+	c.now.Store(time.Now())
+	go c.run(granularity)
+	return c
+}
+
+func (c *CoarseClock) run(granularity time.Duration) {
+	ticker := time.NewTicker(granularity)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.now.Store(now)
+		}
+	}
+}
+
+// Now returns the cached time.
+func (c *CoarseClock) Now() time.Time {
+	return c.now.Load().(time.Time)
+}
+
+// Stop stops refreshing the cached time.
+func (c *CoarseClock) Stop() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// Flusher is implemented by loggers and writers buffering their output.
+type Flusher interface {
+	Flush() error
+}
+
+// LevelWriter is implemented by the writers handling the entries according to their
+// log level, WriterLogger calls WriteLevel rather than Write for them. The level of the
+// entries output by Output is negative.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level LogLevel, p []byte) (int, error)
+}
+
+// Encoder encodes the entries output by a WriterLogger in another format than the text
+// one of its flags, such as SyslogEncoder, see WriterLogger.SetEncoder.
+type Encoder interface {
+	// Encode appends e, terminated by a newline, to buf and returns the extended buffer.
+	Encode(buf []byte, e *Entry) []byte
+}
+
+// LevelLogger is a Logger whose log level can be changed at runtime.
+// Both WriterLogger and RotateLogger implement it.
+type LevelLogger interface {
+	Logger
+	LogLevel() LogLevel
+	SetLogLevel(level LogLevel)
+	SetLogLevelFor(level LogLevel, d time.Duration)
+}
+
+// depthLogger is implemented by loggers which can output content on behalf of
+// a caller further up the stack, such as WriterLogger and RotateLogger.
+type depthLogger interface {
+	// lowestLevel returns the lowest log level of the entries which may be output,
+	// output is only called for such entries.
+	lowestLevel() LogLevel
+	output(skipdepth int, level LogLevel, s string) error
+}
+
+// fieldLogger is implemented by the depthLoggers which can output content with fields,
+// set as the Fields of the entry.
+type fieldLogger interface {
+	depthLogger
+	outputFields(skipdepth int, level LogLevel, s string, fields []Field) error
+}
+
+// logLevelOf returns the lowest log level of the entries which may be output by l,
+// or TRACE if l does not report one.
+func logLevelOf(l Logger) LogLevel {
+	if dl, ok := l.(depthLogger); ok {
+		return dl.lowestLevel()
+	}
+	if ll, ok := l.(interface {
+		LogLevel() LogLevel
+	}); ok {
+		return ll.LogLevel()
+	}
+	return TRACE
+}
+
+// outputTo outputs content with log level to l, skipdepth is counted as in Output.
+// The caller information is lost if l is not a depthLogger. It never exits, even for
+// FATAL entries, the caller does once the entry is output to all its destinations.
+func outputTo(l Logger, skipdepth int, level LogLevel, s string) error {
+	if dl, ok := l.(depthLogger); ok {
+		return dl.output(skipdepth+1, level, s)
+	}
+
+	s = strings.TrimSuffix(s, "\n")
+	if level == FATAL {
+		// as the Output of a log.Logger, or as an error since Fatal exits
+		if o, ok := l.(interface {
+			Output(calldepth int, s string) error
+		}); ok {
+			return o.Output(skipdepth+1, "FATAL|"+s)
+		}
+		l.Error("FATAL|" + s)
+		return nil
+	}
+	switch level {
+	case TRACE:
+		l.Trace(s)
+	case DEBUG:
+		l.Debug(s)
+	case WARN:
+		l.Warn(s)
+	case ERROR:
+		l.Error(s)
+	default:
+		l.Info(s)
+	}
+	return nil
+}
+
+// outputFieldsTo outputs content with log level and fields to l, skipdepth is counted
+// as in Output. The fields are written as "key=value|" before the content if l is not
+// a fieldLogger.
+func outputFieldsTo(l Logger, skipdepth int, level LogLevel, s string, fields []Field) error {
+	if len(fields) == 0 {
+		return outputTo(l, skipdepth+1, level, s)
+	}
+	if fl, ok := l.(fieldLogger); ok {
+		return fl.outputFields(skipdepth+1, level, s, fields)
+	}
+	var buf []byte
+	for _, f := range fields {
+		buf = append(buf, f.Key...)
+		buf = append(buf, '=')
+		buf = appendFieldValue(buf, f.Value)
+		buf = append(buf, '|')
+	}
+	return outputTo(l, skipdepth+1, level, string(buf)+s)
+}
+
+// Enabled reports whether l may output the entries with level, e.g. to spare building
+// costly fields. INFO and FATAL entries are always enabled.
+func Enabled(l Logger, level LogLevel) bool {
+	return level >= INFO || logLevelOf(l) <= level
+}
+
+// OutputFields outputs s with level and fields to l, on behalf of the caller skipdepth
+// frames up, counted as in Output, e.g. for the helpers generated by ylogen. It never
+// exits, even for FATAL entries. The fields are written as "key=value|" before s if l
+// does not support them.
+func OutputFields(l Logger, skipdepth int, level LogLevel, s string, fields ...Field) error {
+	if !Enabled(l, level) {
+		return nil
+	}
+	return outputFieldsTo(l, skipdepth+1, level, s, fields)
+}
+
+// OutputEntry outputs e as built by the caller to l, with its own time, caller and
+// fields if l supports it, such as WriterLogger.OutputEntry, otherwise with its level,
+// message and fields on behalf of the caller of OutputEntry. It never exits, even for
+// FATAL entries.
+func OutputEntry(l Logger, e Entry) error {
+	if el, ok := l.(interface {
+		OutputEntry(e Entry) error
+	}); ok {
+		if e.File == "" {
+			if pc, file, line, ok := runtime.Caller(1); ok {
+				e.File, e.Line, e.Func = file, line, runtime.FuncForPC(pc).Name()
+			}
+		}
+		return el.OutputEntry(e)
+	}
+	if !Enabled(l, e.Level) {
+		return nil
+	}
+	return outputFieldsTo(l, 2, e.Level, e.Message, e.Fields)
+}
+
+// loggerMethods implements Logger on top of a depthLogger.
+// It is embedded by the loggers decorating another Logger.
+type loggerMethods struct {
+	l depthLogger
+}
+
+func (m loggerMethods) Fatalf(format string, v ...interface{}) {
+	m.l.output(2, FATAL, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+func (m loggerMethods) Fatal(v ...interface{}) {
+	m.l.output(2, FATAL, fmt.Sprintln(v...))
+	os.Exit(1)
+}
+
+func (m loggerMethods) Infof(format string, v ...interface{}) {
+	m.l.output(2, INFO, fmt.Sprintf(format, v...))
+}
+
+func (m loggerMethods) Info(v ...interface{}) {
+	m.l.output(2, INFO, fmt.Sprintln(v...))
+}
+
+func (m loggerMethods) Errorf(format string, v ...interface{}) {
+	if m.l.lowestLevel() <= ERROR {
+		m.l.output(2, ERROR, fmt.Sprintf(format, v...))
+	}
+}
+
+func (m loggerMethods) Error(v ...interface{}) {
+	if m.l.lowestLevel() <= ERROR {
+		m.l.output(2, ERROR, fmt.Sprintln(v...))
+	}
+}
+
+func (m loggerMethods) Warnf(format string, v ...interface{}) {
+	if m.l.lowestLevel() <= WARN {
+		m.l.output(2, WARN, fmt.Sprintf(format, v...))
+	}
+}
+
+func (m loggerMethods) Warn(v ...interface{}) {
+	if m.l.lowestLevel() <= WARN {
+		m.l.output(2, WARN, fmt.Sprintln(v...))
+	}
+}
@@ -4,12 +4,13 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 )
 
 func BenchmarkGolangLogger(b *testing.B) {
 	nullf, err := os.OpenFile("/dev/null", os.O_WRONLY, 0666)
 	if err != nil {
-		b.Fatal("%v", err)
+		b.Fatal(err)
 	}
 	defer nullf.Close()
 	logger := log.New(nullf, "", log.Ldate|log.Ltime|log.Lmicroseconds|log.Lshortfile)
@@ -22,7 +23,7 @@ func BenchmarkGolangLogger(b *testing.B) {
 func BenchmarkGolangLoggerParallel(b *testing.B) {
 	nullf, err := os.OpenFile("/dev/null", os.O_WRONLY, 0666)
 	if err != nil {
-		b.Fatal("%v", err)
+		b.Fatal(err)
 	}
 	defer nullf.Close()
 	logger := log.New(nullf, "", log.Ldate|log.Ltime|log.Lmicroseconds|log.Lshortfile)
@@ -37,7 +38,7 @@ func BenchmarkGolangLoggerParallel(b *testing.B) {
 func BenchmarkWriterLogger(b *testing.B) {
 	nullf, err := os.OpenFile("/dev/null", os.O_WRONLY, 0666)
 	if err != nil {
-		b.Fatal("%v", err)
+		b.Fatal(err)
 	}
 	defer nullf.Close()
 	logger := NewWriterLogger(nullf, TRACE)
@@ -47,10 +48,40 @@ func BenchmarkWriterLogger(b *testing.B) {
 	}
 }
 
+func BenchmarkWriterLoggerCoarseClock(b *testing.B) {
+	nullf, err := os.OpenFile("/dev/null", os.O_WRONLY, 0666)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer nullf.Close()
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Stop()
+	logger := NewWriterLogger(nullf, TRACE)
+	logger.SetFlags(logger.Flags() & (^Lloglevel))
+	logger.SetClock(clock)
+	for i := 0; i < b.N; i++ {
+		logger.Debug("testing")
+	}
+}
+
+func BenchmarkWriterLoggerCallerSampling(b *testing.B) {
+	nullf, err := os.OpenFile("/dev/null", os.O_WRONLY, 0666)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer nullf.Close()
+	logger := NewWriterLogger(nullf, TRACE)
+	logger.SetFlags(logger.Flags() & (^Lloglevel))
+	logger.SetCallerSampling(100)
+	for i := 0; i < b.N; i++ {
+		logger.Debug("testing")
+	}
+}
+
 func BenchmarkWriterLoggerParallel(b *testing.B) {
 	nullf, err := os.OpenFile("/dev/null", os.O_WRONLY, 0666)
 	if err != nil {
-		b.Fatal("%v", err)
+		b.Fatal(err)
 	}
 	defer nullf.Close()
 	logger := NewWriterLogger(nullf, TRACE)
@@ -61,3 +92,13 @@ func BenchmarkWriterLoggerParallel(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkJSONEncoder(b *testing.B) {
+	e := &Entry{Time: time.Now(), Level: INFO, File: "main.go", Line: 1, Func: "main.main", Message: "served",
+		Fields: []Field{{"status", 200}, {"path", "/"}, {"took", time.Millisecond}}}
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = JSONEncoder{}.Encode(buf[:0], e)
+	}
+}
@@ -0,0 +1,106 @@
+package ylog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestSetLogLevelFor(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, ERROR)
+
+	l.SetLogLevelFor(DEBUG, 20*time.Millisecond)
+	l.SetLogLevelFor(TRACE, 40*time.Millisecond)
+	if got := l.LogLevel(); got != TRACE {
+		t.Fatalf("LogLevel() = %v during boost, want TRACE", got.LogLevelName())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := l.LogLevel(); got != TRACE {
+		t.Fatalf("LogLevel() = %v after superseded boost expired, want TRACE", got.LogLevelName())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := l.LogLevel(); got != ERROR {
+		t.Fatalf("LogLevel() = %v after boost, want ERROR", got.LogLevelName())
+	}
+
+	l.SetLogLevelFor(DEBUG, 20*time.Millisecond)
+	l.SetLogLevel(WARN)
+	time.Sleep(30 * time.Millisecond)
+	if got := l.LogLevel(); got != WARN {
+		t.Fatalf("LogLevel() = %v, want WARN set explicitly during boost", got.LogLevelName())
+	}
+}
+
+func TestSetPackageLevel(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, ERROR)
+	l.SetFlags(Lloglevel)
+
+	l.SetPackageLevel("github.com/yplusplus", DEBUG)
+	l.Debug("kept")
+	l.Trace("dropped")
+	l.SetPackageLevel("github.com/yplusplus/ylog", WARN)
+	l.Debug("dropped")
+	l.Warn("kept")
+	l.ClearPackageLevel("github.com/yplusplus/ylog")
+	l.ClearPackageLevel("github.com/yplusplus")
+	l.Warn("dropped")
+	if got := l.lowestLevel(); got != ERROR {
+		t.Errorf("lowestLevel() = %v after clearing package levels, want ERROR", got.LogLevelName())
+	}
+
+	if got, want := buf.String(), "DEBUG|kept\nWARN|kept\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestMonotonicClock(t *testing.T) {
+	c := NewMonotonicClock()
+	prev := c.Now()
+	if d := time.Since(prev); d < 0 || d > time.Second {
+		t.Fatalf("Now is %v off the wall clock", d)
+	}
+	for i := 0; i < 1000; i++ {
+		now := c.Now()
+		if now.Before(prev) {
+			t.Fatalf("Now went backwards: %v after %v", now, prev)
+		}
+		prev = now
+	}
+}
+
+func TestCoarseClock(t *testing.T) {
+	c := NewCoarseClock(time.Millisecond)
+	defer c.Stop()
+	first := c.Now()
+	if d := time.Since(first); d < 0 || d > time.Second {
+		t.Fatalf("Now is %v off the wall clock", d)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !c.Now().After(first) {
+		if time.Now().After(deadline) {
+			t.Fatal("Now was not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOutputFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, WARN)
+	l.SetFlags(Lfuncname)
+	userLoggedIn := func(l Logger, user string) {
+		if Enabled(l, DEBUG) {
+			t.Fatal("DEBUG enabled below the log level")
+		}
+		OutputFields(l, 2, INFO, "user logged in", Field{"user", user})
+	}
+	userLoggedIn(l, "ana")
+	if got, want := buf.String(), "github.com/yplusplus/ylog.TestOutputFields|INFO|user=ana|user logged in\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package ylog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest of the log files in a log dir.
+const ManifestFileName = "ylog-manifest.json"
+
+// logFileInfo describes a log file in a log dir.
+type logFileInfo struct {
+	name       string
+	t          time.Time // hour of the log file
+	id         int32
+	compressed bool
+	size       int64
+	modTime    time.Time
+}
+
+// listLogFiles returns the log files in logDir, oldest first.
+func listLogFiles(logDir string) ([]logFileInfo, error) {
+	infos, err := ioutil.ReadDir(logDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []logFileInfo
+	for _, info := range infos {
+		name := info.Name()
+		t, id, compressed, ok := parseLogFileName(name)
+		if !ok || info.IsDir() {
+			continue
+		}
+		files = append(files, logFileInfo{name, t, id, compressed, info.Size(), info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].t.Equal(files[j].t) {
+			return files[i].t.Before(files[j].t)
+		}
+		return files[i].id < files[j].id
+	})
+	return files, nil
+}
+
+// manifestFile is a log file listed in the manifest.
+type manifestFile struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Start      time.Time `json:"start"` // start of the hour of the log file
+	End        time.Time `json:"end"`   // last modification time
+	Compressed bool      `json:"compressed"`
+	Current    bool      `json:"current,omitempty"` // still being written
+	SHA256     string    `json:"sha256,omitempty"`  // checksum, unless current
+}
+
+// manifest lists the log files of a log dir, oldest first.
+type manifest struct {
+	Updated time.Time      `json:"updated"`
+	Files   []manifestFile `json:"files"`
+}
+
+// writeManifest updates the manifest of the log files in logDir, reusing the
+// checksums of the unchanged files.
+func writeManifest(logDir, current string, now time.Time) error {
+	files, err := listLogFiles(logDir)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(logDir, ManifestFileName)
+	var old manifest
+	if b, err := ioutil.ReadFile(path); err == nil {
+		// ignore error, the checksums are computed again
+		json.Unmarshal(b, &old)
+	}
+	checksums := make(map[string]manifestFile, len(old.Files))
+	for _, f := range old.Files {
+		checksums[f.Name] = f
+	}
+
+	m := manifest{Updated: now, Files: make([]manifestFile, 0, len(files))}
+	for _, f := range files {
+		mf := manifestFile{
+			Name:       f.name,
+			Size:       f.size,
+			Start:      f.t,
+			End:        f.modTime,
+			Compressed: f.compressed,
+			Current:    strings.TrimSuffix(f.name, ".gz") == current,
+		}
+		if !mf.Current {
+			if o, ok := checksums[f.name]; ok && o.Size == f.size && o.End.Equal(f.modTime) && o.SHA256 != "" {
+				mf.SHA256 = o.SHA256
+			} else if mf.SHA256, err = fileChecksum(filepath.Join(logDir, f.name)); err != nil {
+				// the file may be removed meanwhile
+				continue
+			}
+		}
+		m.Files = append(m.Files, mf)
+	}
+
+	b, err := json.MarshalIndent(&m, "", "  ")
+	if err != nil {
+		return err
+	}
+	// replace the manifest atomically
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return renameFile(tmp, path)
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,83 @@
+package ylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+)
+
+// pagerDutyURL is the endpoint of the Events API v2 of PagerDuty.
+var pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutySender is an AlertSender triggering PagerDuty events of the FATAL alerts and
+// the ERROR ones matching a pattern, closing the loop between logging and on-call paging.
+// The fingerprints of the alerts are the dedup keys of the events, so that the alerts of
+// the same condition page once for their incident.
+type PagerDutySender struct {
+	RoutingKey string         // integration key of the service
+	Match      *regexp.Regexp // pattern of the messages of the ERROR alerts paged, none if nil
+	Client     *http.Client   // client of the requests, http.DefaultClient if nil
+}
+
+// pagerDutySeverities are the PagerDuty severities of the log levels.
+var pagerDutySeverities = map[LogLevel]string{
+	WARN:  "warning",
+	ERROR: "error",
+	FATAL: "critical",
+}
+
+// SendAlert triggers an event for a if it is paged.
+func (s *PagerDutySender) SendAlert(a *Alert) error {
+	if a.Level != FATAL && (a.Level != ERROR || s.Match == nil || !s.Match.MatchString(a.Message)) {
+		return nil
+	}
+	severity, ok := pagerDutySeverities[a.Level]
+	if !ok {
+		severity = "info"
+	}
+	details := make(map[string]interface{}, len(a.Fields)+1)
+	for _, f := range a.Fields {
+		details[f.Key] = fmt.Sprint(f.Value)
+	}
+	details["caller"] = fmt.Sprintf("%s (%s:%d)", a.Func, a.File, a.Line)
+	summary := a.Message
+	if len(summary) > 1024 {
+		summary = summary[:1024]
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"routing_key":  s.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    a.Fingerprint,
+		"payload": map[string]interface{}{
+			"summary":        summary,
+			"source":         hostname,
+			"severity":       severity,
+			"timestamp":      a.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+			"custom_details": details,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(pagerDutyURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("ylog: pagerduty: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
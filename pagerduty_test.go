@@ -0,0 +1,43 @@
+package ylog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestPagerDutySender(t *testing.T) {
+	var events []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Error(err)
+		}
+		events = append(events, ev)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	defer func(u string) { pagerDutyURL = u }(pagerDutyURL)
+	pagerDutyURL = srv.URL
+
+	s := &PagerDutySender{RoutingKey: "key", Match: regexp.MustCompile("^database")}
+	for _, a := range []*Alert{
+		{Entry: Entry{Level: ERROR, Message: "order 42 failed"}, Fingerprint: "orders"},
+		{Entry: Entry{Level: ERROR, Message: "database unreachable"}, Fingerprint: "db"},
+		{Entry: Entry{Level: FATAL, Message: "out of disk"}, Fingerprint: "disk"},
+	} {
+		if err := s.SendAlert(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("triggered %d events, want 2", len(events))
+	}
+	payload := events[1]["payload"].(map[string]interface{})
+	if events[0]["dedup_key"] != "db" || events[1]["routing_key"] != "key" || payload["severity"] != "critical" || payload["summary"] != "out of disk" {
+		t.Fatalf("triggered %v", events)
+	}
+}
@@ -0,0 +1,167 @@
+// Package parse reads back the entries written in the strict text format of
+// ylog.StrictEncoder, e.g.
+//
+//	d := parse.NewDecoder(f)
+//	for d.Next() {
+//		e := d.Entry()
+//		...
+//	}
+//	if err := d.Err(); err != nil {
+//		...
+//	}
+//
+// The entries read back are the ones written, but the values of their fields, which are
+// read as strings.
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yplusplus/ylog"
+)
+
+// noLevel is the log level of the content without one, as given to Output.
+const noLevel ylog.LogLevel = -1
+
+// headerColumns is the number of elements of an entry before its fields.
+const headerColumns = 5
+
+// ParseLine parses an entry written by ylog.StrictEncoder, with or without its trailing
+// newline.
+func ParseLine(line string) (ylog.Entry, error) {
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	var e ylog.Entry
+	cols := split(line, '|')
+	if len(cols) < headerColumns+1 {
+		return e, errors.New("parse: missing elements")
+	}
+
+	var err error
+	if e.Time, err = time.Parse(time.RFC3339Nano, cols[0]); err != nil {
+		return e, fmt.Errorf("parse: invalid time %q", cols[0])
+	}
+	if e.Seq, err = strconv.ParseUint(cols[1], 10, 64); err != nil {
+		return e, fmt.Errorf("parse: invalid sequence number %q", cols[1])
+	}
+	e.Level = noLevel
+	if cols[2] != "" {
+		level, ok := ylog.LogLevelMap[cols[2]]
+		if !ok {
+			return e, fmt.Errorf("parse: unknown log level %q", cols[2])
+		}
+		e.Level = level
+	}
+	i := strings.LastIndexByte(cols[3], ':')
+	if i < 0 {
+		return e, fmt.Errorf("parse: invalid file and line number %q", cols[3])
+	}
+	if e.Line, err = strconv.Atoi(cols[3][i+1:]); err != nil {
+		return e, fmt.Errorf("parse: invalid line number %q", cols[3][i+1:])
+	}
+	e.File = unescape(cols[3][:i])
+	e.Func = unescape(cols[4])
+	for _, col := range cols[headerColumns : len(cols)-1] {
+		kv := split(col, '=')
+		if len(kv) < 2 {
+			return e, fmt.Errorf("parse: invalid field %q", col)
+		}
+		e.Fields = append(e.Fields, ylog.Field{Key: unescape(kv[0]), Value: unescape(col[len(kv[0])+1:])})
+	}
+	e.Message = unescape(cols[len(cols)-1])
+	return e, nil
+}
+
+// split splits s around the unescaped sep, the parts are left escaped.
+func split(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescape returns s without the escaping of the strict text format.
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			switch c = s[i]; c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Decoder reads the entries written by ylog.StrictEncoder from a stream, one line at a
+// time, without a limit on their length.
+type Decoder struct {
+	r    *bufio.Reader
+	e    ylog.Entry
+	err  error
+	line int // number of the last line read
+}
+
+// NewDecoder returns a Decoder of the entries read from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Next reads the next entry, it returns false at the end of the stream or on error, see
+// Err. The empty lines are skipped.
+func (d *Decoder) Next() bool {
+	for d.err == nil {
+		line, err := d.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err != io.EOF {
+				d.err = err
+			}
+			return false
+		}
+		d.line++
+		if strings.TrimRight(line, "\r\n") == "" {
+			continue
+		}
+		e, perr := ParseLine(line)
+		if perr != nil {
+			d.err = fmt.Errorf("line %d: %v", d.line, perr)
+			return false
+		}
+		d.e = e
+		return true
+	}
+	return false
+}
+
+// Entry returns the entry read by the last call to Next.
+func (d *Decoder) Entry() ylog.Entry {
+	return d.e
+}
+
+// Err returns the error of reading or parsing the stream, nil at its end.
+func (d *Decoder) Err() error {
+	return d.err
+}
@@ -0,0 +1,90 @@
+package parse
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yplusplus/ylog"
+)
+
+func TestParseLine(t *testing.T) {
+	entries := []ylog.Entry{
+		{
+			Time: time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.FixedZone("", 8*3600)), Seq: 42, Level: ylog.ERROR,
+			File: "/src/app/main.go", Line: 42, Func: "main.charge",
+			Fields:  []ylog.Field{{Key: "order", Value: "42"}, {Key: "a=b|c", Value: "x=y|z\\"}, {Key: "", Value: ""}},
+			Message: "payment | failed\nretry \\n at=3\r",
+		},
+		{Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Level: noLevel, File: `C:\src\main.go`, Message: ""},
+		{Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Level: ylog.INFO, Message: "a=b"},
+	}
+	var buf []byte
+	for _, e := range entries {
+		buf = ylog.StrictEncoder{}.Encode(buf[:0], &e)
+		if bytes.Count(buf, []byte("\n")) != 1 {
+			t.Errorf("encoded %q on several lines", buf)
+		}
+		got, err := ParseLine(string(buf))
+		if err != nil {
+			t.Errorf("ParseLine(%q): %v", buf, err)
+			continue
+		}
+		if !got.Time.Equal(e.Time) {
+			t.Errorf("ParseLine(%q) time = %v, want %v", buf, got.Time, e.Time)
+		}
+		got.Time = e.Time
+		if !reflect.DeepEqual(got, e) {
+			t.Errorf("ParseLine(%q) = %+v, want %+v", buf, got, e)
+		}
+	}
+
+	for _, line := range []string{
+		"",
+		"2024-01-02T15:04:05Z|1|INFO|main.go:1|message",
+		"yesterday|1|INFO|main.go:1|main.main|message",
+		"2024-01-02T15:04:05Z|one|INFO|main.go:1|main.main|message",
+		"2024-01-02T15:04:05Z|1|NOTICE|main.go:1|main.main|message",
+		"2024-01-02T15:04:05Z|1|INFO|main.go|main.main|message",
+		"2024-01-02T15:04:05Z|1|INFO|main.go:1|main.main|no field|message",
+	} {
+		if _, err := ParseLine(line); err == nil {
+			t.Errorf("ParseLine(%q) accepted an invalid line", line)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	var buf bytes.Buffer
+	l := ylog.NewWriterLogger(&buf, ylog.TRACE)
+	l.SetEncoder(ylog.StrictEncoder{})
+	l.Info("first\nline")
+	ylog.WithStaticFields(l, []ylog.Field{{Key: "user", Value: 7}}).Warn("second")
+	buf.WriteString("\n")
+	l.Error("third")
+
+	d := NewDecoder(strings.NewReader(strings.TrimSuffix(buf.String(), "\n")))
+	var got []string
+	for d.Next() {
+		e := d.Entry()
+		if !strings.HasSuffix(e.File, "parse_test.go") || e.Line == 0 || e.Seq != uint64(len(got)+1) {
+			t.Errorf("decoded entry %+v, want the caller and sequence number", e)
+		}
+		got = append(got, e.Level.LogLevelName()+" "+e.Message)
+	}
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"INFO first\nline", "WARN second", "ERROR third"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %q, want %q", got, want)
+	}
+
+	d = NewDecoder(strings.NewReader(buf.String() + "garbage\n"))
+	for d.Next() {
+	}
+	if err := d.Err(); err == nil || !strings.HasPrefix(err.Error(), "line 5:") {
+		t.Errorf("Err() = %v, want an error at line 5", err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build ylog_pflag
+// +build ylog_pflag
+
+package ylog
+
+import (
+	"github.com/spf13/pflag"
+)
+
+// RegisterPFlags registers the options of the default logger as flags in fs, so that
+// cobra based programs show them in their help. Call Init once they are parsed.
+// The flag names are the option names, e.g. viper.BindPFlags(fs) binds them to the
+// keys of the same names.
+//
+// It requires the ylog_pflag build tag.
+func RegisterPFlags(fs *pflag.FlagSet) {
+	registerFlags(fs, func(p *Size, name, usage string) { fs.Var(p, name, usage) })
+}
@@ -0,0 +1,86 @@
+package ylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// processStart is the approximate start time of the process.
+var processStart = time.Now()
+
+// DefaultPreamble returns a preamble describing the process, making a log file found
+// long after self-describing, e.g.
+//
+//	Log file created at: 2024-01-02 15:04:05
+//	Program: server
+//	Version: v1.2.3 (github.com/us/server), built with go1.21.0 for linux/amd64
+//	Running on machine: host1
+//	PID: 1234
+//	Process started at: 2024-01-02 09:00:00
+//	Command line: server -log-dir /var/log/server
+//
+// It is meant to be given to RotateWriter.SetPreamble.
+func DefaultPreamble(now time.Time) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Log file created at: %s\n", now.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&buf, "Program: %s\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(&buf, "Version: %s, built with %s for %s/%s\n", buildVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	fmt.Fprintf(&buf, "Running on machine: %s\n", host)
+	fmt.Fprintf(&buf, "PID: %d\n", os.Getpid())
+	fmt.Fprintf(&buf, "Process started at: %s\n", processStart.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&buf, "Command line: %s\n", strings.Join(os.Args, " "))
+	return buf.String()
+}
+
+// buildVersion returns the version and path of the main module, or "unknown".
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s (%s)", info.Main.Version, info.Main.Path)
+}
+
+// LogStartup logs a startup entry to the default logger, see LogStartupTo.
+func LogStartup() {
+	logStartup(std)
+}
+
+// LogStartupTo logs a startup entry to l at INFO level, which is never dropped by the log
+// level, with the program, its version, the Go version, the host name, the process ID,
+// the command line arguments and the effective configuration of l as fields, so that the
+// log tells who produced it, e.g.
+//
+//	INFO|program=server|version=v1.2.3 (github.com/us/server)|go=go1.21.0|host=host1|pid=1234|args=[-log-dir /var/log/server]|config={"log-level":"INFO",...}|startup
+func LogStartupTo(l Logger) {
+	logStartup(l)
+}
+
+// logStartup logs the startup entry on behalf of the caller of the exported helpers.
+func logStartup(l Logger) {
+	config, err := json.Marshal(ConfigOf(l))
+	if err != nil {
+		config = []byte(err.Error())
+	}
+	// skip logStartup and the exported helper
+	outputFieldsTo(l, 3, INFO, "startup", []Field{
+		{"program", filepath.Base(os.Args[0])},
+		{"version", buildVersion()},
+		{"go", runtime.Version()},
+		{"host", hostname},
+		{"pid", pid},
+		{"args", os.Args[1:]},
+		{"config", string(config)},
+	})
+}
@@ -0,0 +1,33 @@
+package ylog
+
+// prefixLogger prefixes the content logged through it before passing it to another Logger.
+type prefixLogger struct {
+	loggerMethods
+	inner  Logger
+	prefix string
+}
+
+// WithPrefix returns a Logger passing the content logged through it to inner prefixed
+// with prefix, after the header, e.g. WithPrefix(l, "[reconciler] "). The caller of the
+// entries is the one of the returned Logger.
+func WithPrefix(inner Logger, prefix string) Logger {
+	return newPrefixLogger(inner, prefix)
+}
+
+func newPrefixLogger(inner Logger, prefix string) *prefixLogger {
+	l := &prefixLogger{inner: inner, prefix: prefix}
+	l.loggerMethods = loggerMethods{l}
+	return l
+}
+
+func (l *prefixLogger) lowestLevel() LogLevel {
+	return logLevelOf(l.inner)
+}
+
+func (l *prefixLogger) output(skipdepth int, level LogLevel, s string) error {
+	return outputTo(l.inner, skipdepth+1, level, l.prefix+s)
+}
+
+func (l *prefixLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	return outputFieldsTo(l.inner, skipdepth+1, level, l.prefix+s, fields)
+}
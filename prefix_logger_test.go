@@ -0,0 +1,21 @@
+package ylog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	inner := NewWriterLogger(&buf, TRACE)
+	inner.SetFlags(Lshortfile | Lloglevel)
+	l := WithPrefix(inner, "[reconciler] ")
+
+	l.Infof("synced %d objects", 3)
+	WithPrefix(l, "[pod] ").Warn("evicted")
+
+	want := "prefix_logger_test.go:14|INFO|[reconciler] synced 3 objects\nprefix_logger_test.go:15|WARN|[reconciler] [pod] evicted\n"
+	if buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,177 @@
+package ylog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remoteLevelWait is the max duration the remote level sources wait for a change.
+var remoteLevelWait = 5 * time.Minute
+
+// ConsulLevel returns a LevelSource reading the level spec from the key of the Consul KV
+// store at addr, e.g. "http://127.0.0.1:8500", so that the log levels of a fleet are
+// changed from one place with a single "consul kv put". It blocks until the key changes,
+// for 5 minutes at most, see LevelPoller. A missing key keeps the current log levels.
+func ConsulLevel(addr, key string) LevelSource {
+	u := strings.TrimSuffix(addr, "/") + "/v1/kv/" + strings.TrimPrefix(key, "/")
+	var index uint64 // index of the last read
+	var spec string  // value of the last read
+	return func() (string, error) {
+		v := url.Values{"raw": {""}}
+		if index > 0 {
+			v.Set("index", strconv.FormatUint(index, 10))
+			v.Set("wait", fmt.Sprintf("%ds", int(remoteLevelWait/time.Second)))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), remoteLevelWait+10*time.Second)
+		defer cancel()
+		req, err := http.NewRequest("GET", u+"?"+v.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if err != nil {
+			return "", err
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			spec = strings.TrimSpace(string(b))
+		case http.StatusNotFound:
+			spec = ""
+		default:
+			return "", fmt.Errorf("ylog: consul: %s: %s", resp.Status, bytes.TrimSpace(b))
+		}
+		// the index is reset if it goes backwards, as recommended by Consul
+		if i, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64); err == nil && i > index {
+			index = i
+		} else {
+			index = 0
+		}
+		return spec, nil
+	}
+}
+
+// etcdKV is a key/value pair of the JSON gateway of etcd.
+type etcdKV struct {
+	Value       []byte `json:"value"`
+	ModRevision int64  `json:"mod_revision,string"`
+}
+
+// etcdHeader is the response header of the JSON gateway of etcd.
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+// EtcdLevel returns a LevelSource reading the level spec from the key of etcd through
+// the JSON gateway of its v3 API at endpoint, e.g. "http://127.0.0.1:2379", as
+// ConsulLevel. It blocks until the key changes, for 5 minutes at most.
+func EtcdLevel(endpoint, key string) LevelSource {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	var revision int64 // revision of the last read
+	var spec string    // value of the last read
+	return func() (string, error) {
+		if revision == 0 {
+			var resp struct {
+				Header etcdHeader `json:"header"`
+				KVs    []etcdKV   `json:"kvs"`
+			}
+			body, err := etcdPost(context.Background(), endpoint+"/v3/kv/range", map[string]interface{}{"key": []byte(key)})
+			if err != nil {
+				return "", err
+			}
+			defer body.Close()
+			if err := json.NewDecoder(body).Decode(&resp); err != nil {
+				return "", err
+			}
+			spec = ""
+			if len(resp.KVs) > 0 {
+				spec = strings.TrimSpace(string(resp.KVs[0].Value))
+			}
+			revision = resp.Header.Revision
+			return spec, nil
+		}
+
+		// watch from the next revision until an event
+		ctx, cancel := context.WithTimeout(context.Background(), remoteLevelWait)
+		defer cancel()
+		body, err := etcdPost(ctx, endpoint+"/v3/watch", map[string]interface{}{
+			"create_request": map[string]interface{}{"key": []byte(key), "start_revision": strconv.FormatInt(revision+1, 10)},
+		})
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		dec := json.NewDecoder(body)
+		for {
+			var msg struct {
+				Result struct {
+					Canceled     bool   `json:"canceled"`
+					CancelReason string `json:"cancel_reason"`
+					Events       []struct {
+						Type string `json:"type"`
+						KV   etcdKV `json:"kv"`
+					} `json:"events"`
+				} `json:"result"`
+			}
+			if err := dec.Decode(&msg); err != nil {
+				if ctx.Err() != nil {
+					// no change
+					return spec, nil
+				}
+				return "", err
+			}
+			if msg.Result.Canceled {
+				// read the key again, e.g. after a compaction
+				revision = 0
+				return "", fmt.Errorf("ylog: etcd: watch canceled: %s", msg.Result.CancelReason)
+			}
+			if n := len(msg.Result.Events); n > 0 {
+				ev := msg.Result.Events[n-1]
+				if ev.Type == "DELETE" {
+					spec = ""
+				} else {
+					spec = strings.TrimSpace(string(ev.KV.Value))
+				}
+				revision = ev.KV.ModRevision
+				return spec, nil
+			}
+		}
+	}
+}
+
+// etcdPost posts the JSON encoding of v to the JSON gateway of etcd at u and returns the
+// response body.
+func etcdPost(ctx context.Context, u string, v interface{}) (io.ReadCloser, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", u, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		resp.Body.Close()
+		return nil, fmt.Errorf("ylog: etcd: %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,79 @@
+package ylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConsulLevel(t *testing.T) {
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Path != "/v1/kv/ylog/level" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("index") {
+		case "":
+			w.Header().Set("X-Consul-Index", "7")
+			fmt.Fprint(w, "INFO,github.com/us/app/db=DEBUG\n")
+		default:
+			w.Header().Set("X-Consul-Index", "8")
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	l := NewWriterLogger(ioutil.Discard, WARN)
+	p := NewLevelPoller(l, ConsulLevel(s.URL, "/ylog/level"), 1<<62)
+	defer p.Close()
+	if err := p.Err(); err != nil || l.LogLevel() != INFO || l.PackageLevels()["github.com/us/app/db"] != DEBUG {
+		t.Fatalf("level %v, package levels %v, error %v", l.LogLevel().LogLevelName(), l.PackageLevels(), err)
+	}
+	// deleted key
+	if err := p.Poll(); err != nil || l.LogLevel() != INFO || len(l.PackageLevels()) != 1 {
+		t.Fatalf("level %v, package levels %v, error %v, want them kept", l.LogLevel().LogLevelName(), l.PackageLevels(), err)
+	}
+	if want := "index=7&raw=&wait=300s"; len(queries) != 2 || queries[1] != want {
+		t.Fatalf("queries %q, want a blocking query %q", queries, want)
+	}
+}
+
+func TestEtcdLevel(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			if string(req["key"]) != `"eWxvZy9sZXZlbA=="` {
+				t.Errorf("range request %s", req)
+			}
+			fmt.Fprint(w, `{"header":{"revision":"10"},"kvs":[{"key":"eWxvZy9sZXZlbA==","value":"REVCVUcsZGI9V0FSTg==","mod_revision":"9"}]}`)
+		case "/v3/watch":
+			if want := `{"key":"eWxvZy9sZXZlbA==","start_revision":"11"}`; string(req["create_request"]) != want {
+				t.Errorf("watch request %s, want %s", req["create_request"], want)
+			}
+			fmt.Fprint(w, `{"result":{"header":{"revision":"10"},"created":true}}`+"\n")
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, `{"result":{"header":{"revision":"12"},"events":[{"kv":{"key":"eWxvZy9sZXZlbA==","value":"RVJST1I=","mod_revision":"12"}}]}}`+"\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	l := NewWriterLogger(ioutil.Discard, WARN)
+	p := NewLevelPoller(l, EtcdLevel(s.URL, "ylog/level"), 1<<62)
+	defer p.Close()
+	if err := p.Err(); err != nil || l.LogLevel() != DEBUG || l.PackageLevels()["db"] != WARN {
+		t.Fatalf("level %v, package levels %v, error %v", l.LogLevel().LogLevelName(), l.PackageLevels(), err)
+	}
+	// the package levels missing from the new spec are cleared
+	if err := p.Poll(); err != nil || l.LogLevel() != ERROR || len(l.PackageLevels()) != 0 {
+		t.Fatalf("level %v, package levels %v, error %v", l.LogLevel().LogLevelName(), l.PackageLevels(), err)
+	}
+}
@@ -0,0 +1,98 @@
+//go:build windows
+// +build windows
+
+package ylog
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32        = syscall.NewLazyDLL("kernel32.dll")
+	procCreateEvent = kernel32.NewProc("CreateEventW")
+	procOpenEvent   = kernel32.NewProc("OpenEventW")
+	procSetEvent    = kernel32.NewProc("SetEvent")
+)
+
+const (
+	eventModifyState = 0x0002 // EVENT_MODIFY_STATE
+	rotateEventPoll  = 200    // milliseconds between the checks of the stop of HandleRotateEvent
+)
+
+// HandleRotateEvent makes the default logger continue in a new log file if it supports
+// it, such as RotateLogger, each time the named event name is signaled, e.g. by
+// SignalRotateEvent or by a rotation tool, once the logger is flushed. It is the
+// equivalent on Windows of SIGHUP, see HandleSignals, e.g. "Global\\myapp-rotate" for
+// the tools of the other sessions. Call the returned function to uninstall the handler,
+// more calls have no effect.
+func HandleRotateEvent(name string) (stop func(), err error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	// auto reset, not signaled
+	r, _, err := procCreateEvent.Call(0, 0, 0, uintptr(unsafe.Pointer(p)))
+	if r == 0 {
+		return nil, err
+	}
+	h := syscall.Handle(r)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			ev, err := syscall.WaitForSingleObject(h, rotateEventPoll)
+			if err != nil {
+				return
+			}
+			if ev != syscall.WAIT_OBJECT_0 {
+				continue
+			}
+			l := DefaultLogger()
+			if f, ok := l.(Flusher); ok {
+				f.Flush()
+			}
+			if r, ok := l.(interface {
+				Rotate() error
+			}); ok {
+				r.Rotate()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+			syscall.CloseHandle(h)
+		})
+	}, nil
+}
+
+// SignalRotateEvent signals the named event name, which makes the programs handling it
+// continue in new log files, see HandleRotateEvent.
+func SignalRotateEvent(name string) error {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	r, _, err := procOpenEvent.Call(eventModifyState, 0, uintptr(unsafe.Pointer(p)))
+	if r == 0 {
+		return err
+	}
+	h := syscall.Handle(r)
+	defer syscall.CloseHandle(h)
+	if r, _, err := procSetEvent.Call(uintptr(h)); r == 0 {
+		return err
+	}
+	return nil
+}
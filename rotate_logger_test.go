@@ -0,0 +1,140 @@
+package ylog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateLoggerSetLogDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewRotateLogger(filepath.Join(dir, "a"), TRACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetFlags(Lloglevel)
+
+	l.Info("first")
+	if err := l.SetLogDir(filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("second")
+
+	name := getLogFileName(time.Now(), 0, 0)
+	for sub, want := range map[string]string{"a": "INFO|first\n", "b": "INFO|second\n"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, sub, name))
+		if err != nil || string(b) != want {
+			t.Errorf("log file in %s contains %q, %v, want %q", sub, b, err, want)
+		}
+	}
+	if got := l.LogDir(); got != filepath.Join(dir, "b") {
+		t.Errorf("LogDir() = %q", got)
+	}
+}
+
+func TestRotateLoggerRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewRotateLogger(dir, TRACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetFlags(Lloglevel)
+
+	l.Info("first")
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("second")
+
+	now := time.Now()
+	for id, want := range []string{"INFO|first\n", "INFO|second\n"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, getLogFileName(now, int32(id), 0)))
+		if err != nil || string(b) != want {
+			t.Errorf("log file %d contains %q, %v, want %q", id, b, err, want)
+		}
+	}
+}
+
+func TestRotateLoggerLogFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewRotateLogger(dir, TRACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetCompress(true)
+	l.Info("first")
+	l.Rotate()
+	l.Info("second")
+	l.Close()
+
+	files, err := l.LogFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if len(files) != 2 {
+		t.Fatalf("LogFiles() = %+v", files)
+	}
+	if f := files[0]; f.Path != filepath.Join(dir, getLogFileName(now, 0, 0)+".gz") || !f.Compressed || f.Current {
+		t.Errorf("rotated log file %+v", f)
+	}
+	if f := files[1]; f.Path != filepath.Join(dir, getLogFileName(now, 1, 0)) || f.Compressed || !f.Current || f.Size == 0 {
+		t.Errorf("current log file %+v", f)
+	}
+}
+
+func TestRotateLoggerSetOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewRotateLogger(filepath.Join(dir, "a"), TRACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetFlags(Lloglevel)
+	if err := l.SetRotateWriter(nil); err == nil {
+		t.Fatal("SetRotateWriter accepted a nil RotateWriter")
+	}
+
+	w, err := NewRotateWriter(filepath.Join(dir, "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetOutput(w)
+	l.SetOutput(NewAsyncWriter(w, 16))
+	if l.RotateWriter() != w || l.LogDir() != filepath.Join(dir, "b") {
+		t.Fatalf("RotateWriter() is not the RotateWriter set by SetOutput")
+	}
+	l.Info("async")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	name := getLogFileName(time.Now(), 0, 0)
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "b", name)); err != nil || string(b) != "INFO|async\n" {
+		t.Fatalf("log file contains %q, %v", b, err)
+	}
+}
@@ -0,0 +1,111 @@
+//go:build windows
+// +build windows
+
+package ylog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateWriterRenameOpenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)}
+	w, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetLogSizeLimit(20)
+	w.Write([]byte("0123456789\n"))
+	w.Write([]byte("0123456789\n")) // above the limit, the next log file has an id
+	// renames the current log file, which is open
+	if err := w.SetSequenceWidth(3); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("renamed\n"))
+	w.Close()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "2024010215.log.001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "renamed\n"; got != want {
+		t.Fatalf("renamed log file has %q, want %q", got, want)
+	}
+}
+
+func TestRemoveLockedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "2024010215.log")
+	if err := ioutil.WriteFile(path, []byte("locked\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// held open without sharing for deletion, as by an antivirus scanner
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(2*lockRetryDelay, func() { f.Close() })
+	if err := removeFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Stat() = %v after removeFile, want not exist", err)
+	}
+}
+
+func TestHandleRotateEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := NewRotateLogger(dir, TRACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	old := DefaultLogger()
+	defer SetDefaultLogger(old)
+	SetDefaultLogger(l)
+
+	name := fmt.Sprintf("ylog-test-rotate-%d", os.Getpid())
+	stop, err := HandleRotateEvent(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	l.Info("before rotation")
+	if err := SignalRotateEvent(name); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		files, err := l.LogFiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d log files after the event, want 2", len(files))
+		}
+	}
+	stop()
+	stop()
+}
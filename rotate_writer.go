@@ -0,0 +1,702 @@
+package ylog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxWriteRetries = 3                     // max number of retries of a write making no progress
+	maxHeldBytes    = 1 << 20               // max bytes of the writes held back while the disk is full
+	minFullBackoff  = time.Second           // first backoff of the writes while the disk is full
+	maxFullBackoff  = 30 * time.Second      // max backoff of the writes while the disk is full
+	maxLockRetries  = 5                     // max number of retries of a rename or removal of a locked file
+	lockRetryDelay  = 10 * time.Millisecond // first delay of the retries of a locked file
+)
+
+// fileWrite writes to the log files, replaced by the tests.
+var fileWrite = (*os.File).Write
+
+// RotateWriter is an io.WriteCloser which splits its output into several log files
+// according to write time and file size. A single Write is never split across files.
+// It optionally compresses the rotated files and removes the old ones. On Windows, the
+// log files are opened shared for deletion, so that they can be renamed and removed
+// while open, and their renames and removals are retried for a moment while another
+// process, such as an antivirus scanner, holds them open.
+type RotateWriter struct {
+	clock Clock // source of the current time
+
+	mu           sync.Mutex                 // ensures atomic writes; protects the following fields
+	logDir       string                     // log dir
+	logSizeLimit int64                      // log file size limit (Byte)
+	maxAge       time.Duration              // max age of rotated log files, 0 means no limit
+	maxFiles     int                        // max number of rotated log files, 0 means no limit
+	compress     bool                       // compress rotated log files
+	manifest     bool                       // maintain the manifest of the log files
+	preamble     func(now time.Time) string // returns the preamble of new log files
+	f            *os.File                   // destination of output
+	fpath        string                     // path of the current log file
+	handedOver   bool                       // the current log file is handed over, see Handover
+	fname        string                     // current log file name (format: YYYYMMDDHH.log[.ID])
+	nbytes       int64                      // current log file size (Byte)
+	fid          int32                      // log file id
+	seqWidth     int                        // min number of digits of the log file ids, zero padded
+	trailer      bool                       // write a trailer at the end of log files
+	nwrites      int64                      // number of writes to the current log file
+	nwritten     int64                      // bytes of the writes to the current log file
+	firstWrite   time.Time                  // time of the first write to the current log file
+	lastWrite    time.Time                  // time of the last write to the current log file
+	errorHandler func(err error)            // called with the errors of the writes
+	held         []byte                     // writes held back while the disk is full
+	heldErr      error                      // error of the last write held back
+	backoff      time.Duration              // current backoff of the writes held back
+	retryAt      time.Time                  // time of the next try of the writes held back
+
+	cleanupMu sync.Mutex     // serializes the compression and removal of rotated log files
+	wg        sync.WaitGroup // waits for the pending cleanups
+}
+
+// NewRotateWriter makes logDir if needed and opens the log file for the current hour.
+func NewRotateWriter(logDir string) (*RotateWriter, error) {
+	return NewRotateWriterWithClock(logDir, SystemClock)
+}
+
+// NewRotateWriterWithClock is like NewRotateWriter but takes the current time from clock,
+// which lets tests simulate the rotation by time.
+func NewRotateWriterWithClock(logDir string, clock Clock) (*RotateWriter, error) {
+	w := &RotateWriter{
+		clock:        clock,
+		logDir:       logDir,
+		logSizeLimit: DEFAULT_LOG_FILE_SIZE,
+	}
+
+	// make log director
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := w.resumeFile(w.clock.Now()); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// resumeFile opens the last log file of the hour of now in w.logDir, the one with the
+// greatest id whatever their number, so that the next one rotated by size has the next
+// id. The ids are taken from a single scan of the log dir.
+func (w *RotateWriter) resumeFile(now time.Time) error {
+	fname := getLogFileName(now, 0, 0)
+	files, err := listLogFiles(w.logDir)
+	if err != nil {
+		return err
+	}
+	var fid int32
+	for _, f := range files {
+		if f.id > fid && getLogFileName(f.t, 0, 0) == fname {
+			fid = f.id
+		}
+	}
+
+	// create file
+	w.fname = fname
+	w.fid = fid
+	return w.createFile()
+}
+
+// getLogFileName returns the name of the log file id of the hour of t, whose id has at
+// least width digits.
+func getLogFileName(t time.Time, id int32, width int) string {
+	fname := fmt.Sprintf("%04d%02d%02d%02d.log", t.Year(), t.Month(), t.Day(), t.Hour())
+	if id > 0 {
+		fname = fname + fmt.Sprintf(".%0*d", width, id)
+	}
+	return fname
+}
+
+// parseLogFileName parses a log file name made by getLogFileName, of any width, possibly
+// compressed.
+func parseLogFileName(name string) (t time.Time, id int32, compressed bool, ok bool) {
+	if strings.HasSuffix(name, ".gz") {
+		name = strings.TrimSuffix(name, ".gz")
+		compressed = true
+	}
+	if len(name) < len("YYYYMMDDHH.log") || name[10:14] != ".log" {
+		return
+	}
+	t, err := time.ParseInLocation("2006010215", name[:10], time.Local)
+	if err != nil {
+		return
+	}
+	if rest := name[14:]; rest != "" {
+		if rest[0] != '.' {
+			return
+		}
+		n, err := strconv.ParseInt(rest[1:], 10, 32)
+		if err != nil || n <= 0 {
+			return
+		}
+		id = int32(n)
+	}
+	return t, id, compressed, true
+}
+
+// createFile creates a log file according to w.fname and w.fid
+func (w *RotateWriter) createFile() error {
+	filePath := filepath.Join(w.logDir, w.currentName())
+	var err error
+	w.f, err = openLogFile(filePath)
+	if err != nil {
+		return err
+	}
+	w.fpath = filePath
+
+	// ignore error
+	stat, err := w.f.Stat()
+	if err == nil {
+		w.nbytes = stat.Size()
+	}
+
+	// write the preamble at the top of new files only
+	if w.preamble != nil && w.nbytes == 0 {
+		nn, err := io.WriteString(w.f, w.preamble(w.clock.Now()))
+		w.nbytes += int64(nn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *RotateWriter) rotateFile(now time.Time) (err error) {
+	if w.handedOver && w.f != nil {
+		// the process taking over rotates the log file
+		return nil
+	}
+	needCreateFile := false
+	reason := ""
+
+	currentFileName := getLogFileName(now, 0, 0)
+	if w.fname != currentFileName { // current log file is too old
+		w.fname = currentFileName
+		w.fid = 0
+		needCreateFile = true
+		reason = "time"
+	} else if w.logSizeLimit > 0 && w.nbytes >= w.logSizeLimit { // current log file is too large
+		w.fid++
+		needCreateFile = true
+		reason = "size"
+	} else if w.f == nil {
+		needCreateFile = true
+	}
+
+	if needCreateFile {
+		if w.f != nil {
+			rotated, _ := w.closeFile(reason)
+			w.cleanup(rotated)
+		}
+		if err = w.createFile(); err != nil {
+			// failed to create log file, we dont panic and try next write
+			return
+		}
+	}
+	return
+}
+
+// Write writes p to the current log file, rotating it beforehand if needed. The
+// interrupted and short writes are retried at once. While the disk is full, the writes
+// are held back, up to 1MiB, and retried before the next ones after a backoff growing
+// from 1s to 30s. The errors of the writes are reported to the error handler, see
+// SetErrorHandler.
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	// get time early
+	now := w.clock.Now()
+
+	w.mu.Lock()
+	nn, err, reported := w.write(now, p)
+	handler := w.errorHandler
+	w.mu.Unlock()
+
+	if reported != nil && handler != nil {
+		handler(reported)
+	}
+	return nn, err
+}
+
+// write writes p at now, it returns the error to report to the error handler if any.
+// w.mu must be held.
+func (w *RotateWriter) write(now time.Time, p []byte) (nn int, err error, reported error) {
+	if err = w.rotateFile(now); err != nil {
+		return 0, err, err
+	}
+
+	if w.nwrites == 0 {
+		w.firstWrite = now
+	}
+	w.nwrites++
+	w.lastWrite = now
+
+	if len(w.held) > 0 {
+		if now.Before(w.retryAt) {
+			nn, err = w.hold(p)
+			return nn, err, nil
+		}
+		n, err := w.writeFile(w.held)
+		w.held = w.held[:copy(w.held, w.held[n:])]
+		if err != nil {
+			w.backOff(now, err)
+			nn, err = w.hold(p)
+			return nn, err, w.heldErr
+		}
+		w.backoff = 0
+	}
+
+	nn, err = w.writeFile(p)
+	if err == nil {
+		return nn, nil, nil
+	}
+	if !isDiskFull(err) {
+		return nn, err, err
+	}
+	w.backOff(now, err)
+	n, herr := w.hold(p[nn:])
+	return nn + n, herr, err
+}
+
+// writeFile writes p to the current log file, retrying the interrupted and short writes.
+// w.mu must be held.
+func (w *RotateWriter) writeFile(p []byte) (int, error) {
+	nn, stalls := 0, 0
+	for {
+		n, err := fileWrite(w.f, p[nn:])
+		nn += n
+		w.nbytes += int64(n)
+		w.nwritten += int64(n)
+		if nn == len(p) {
+			return nn, nil
+		}
+		if err != nil && err != io.ErrShortWrite && !isTransient(err) {
+			return nn, err
+		}
+		if n > 0 {
+			stalls = 0
+		} else if stalls++; stalls > maxWriteRetries {
+			if err == nil {
+				err = io.ErrShortWrite
+			}
+			return nn, err
+		}
+	}
+}
+
+// backOff postpones the next try of the writes held back after err at now. w.mu must be
+// held.
+func (w *RotateWriter) backOff(now time.Time, err error) {
+	switch {
+	case w.backoff == 0:
+		w.backoff = minFullBackoff
+	case w.backoff < maxFullBackoff:
+		w.backoff *= 2
+		if w.backoff > maxFullBackoff {
+			w.backoff = maxFullBackoff
+		}
+	}
+	w.retryAt = now.Add(w.backoff)
+	w.heldErr = err
+}
+
+// hold holds p back until the next try, or drops it if too many writes are held back.
+// w.mu must be held.
+func (w *RotateWriter) hold(p []byte) (int, error) {
+	if len(w.held)+len(p) > maxHeldBytes {
+		return 0, w.heldErr
+	}
+	w.held = append(w.held, p...)
+	return len(p), nil
+}
+
+// SetErrorHandler sets the function called with the errors of the writes, including
+// the ones held back while the disk is full, which are not returned by Write. It is
+// called by the writing goroutine, and must not write to w. Give nil to remove it.
+func (w *RotateWriter) SetErrorHandler(handler func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errorHandler = handler
+}
+
+// Rotate closes the current log file and continues writing in a new one. It has no
+// effect once the log file is handed over, see Handover.
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.handedOver && w.f != nil {
+		return nil
+	}
+
+	if w.f != nil {
+		rotated, _ := w.closeFile("rotate")
+		w.cleanup(rotated)
+	}
+	now := w.clock.Now()
+	if currentFileName := getLogFileName(now, 0, 0); w.fname != currentFileName {
+		w.fname = currentFileName
+		w.fid = 0
+	} else {
+		w.fid++
+	}
+	return w.createFile()
+}
+
+// Close closes the current log file, waits for the pending cleanups and updates the
+// manifest if needed. The next Write reopens a log file.
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.f != nil {
+		if len(w.held) > 0 {
+			// last try, the writes still held back are lost
+			w.writeFile(w.held)
+			w.held, w.backoff = nil, 0
+		}
+		_, err = w.closeFile("close")
+	}
+	logDir, manifest := w.logDir, w.manifest
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	if manifest {
+		w.cleanupMu.Lock()
+		// ignore error, the manifest is updated on next rotation
+		writeManifest(logDir, "", w.clock.Now())
+		w.cleanupMu.Unlock()
+	}
+	return err
+}
+
+// LogDir returns the directory of the log files
+func (w *RotateWriter) LogDir() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.logDir
+}
+
+// SetLogDir closes the current log file and continues writing in logDir, which is made
+// if needed. The rotated log files are left in the previous directory.
+func (w *RotateWriter) SetLogDir(logDir string) error {
+	// make log director
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f != nil {
+		rotated, _ := w.closeFile("logdir")
+		w.cleanup(rotated)
+	}
+	w.logDir = logDir
+	return w.resumeFile(w.clock.Now())
+}
+
+// LogFile describes a log file managed by a RotateWriter.
+type LogFile struct {
+	Path       string    // path of the log file
+	Size       int64     // size (Byte)
+	Start      time.Time // start of the hour of the log file
+	End        time.Time // last modification time
+	Compressed bool      // compressed with gzip
+	Current    bool      // still being written
+}
+
+// LogFiles returns the log files in the log dir, oldest first, including the rotated
+// ones left by previous processes.
+func (w *RotateWriter) LogFiles() ([]LogFile, error) {
+	w.mu.Lock()
+	logDir, current := w.logDir, w.currentName()
+	w.mu.Unlock()
+
+	files, err := listLogFiles(logDir)
+	if err != nil {
+		return nil, err
+	}
+	logFiles := make([]LogFile, 0, len(files))
+	for _, f := range files {
+		logFiles = append(logFiles, LogFile{
+			Path:       filepath.Join(logDir, f.name),
+			Size:       f.size,
+			Start:      f.t,
+			End:        f.modTime,
+			Compressed: f.compressed,
+			Current:    strings.TrimSuffix(f.name, ".gz") == current,
+		})
+	}
+	return logFiles, nil
+}
+
+// LogSizeLimit returns a single log file size limit
+func (w *RotateWriter) LogSizeLimit() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.logSizeLimit
+}
+
+// SetLogSizeLimit sets the single log file size limit in bytes, which applies to the
+// whole file, including its preamble and the contents left by previous processes.
+// Give a non positive logSizeLimit to disable log splitting by size.
+func (w *RotateWriter) SetLogSizeLimit(logSizeLimit int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.logSizeLimit = logSizeLimit
+}
+
+// SetSequenceWidth sets the min number of digits of the ids of the log files of an hour,
+// which are zero padded, e.g. 2024010215.log.0001 rather than 2024010215.log.1 for 4,
+// so that their lexical order is their chronological order. The log files in the log
+// dir, including the ones left by previous processes and the current one, are renamed
+// to the width, the ones whose new names are taken being left as they are. It returns
+// the first error of the renames. The default width is 0, no padding.
+func (w *RotateWriter) SetSequenceWidth(width int) error {
+	if width < 0 {
+		width = 0
+	}
+	// the cleanups must not compress or remove the files being renamed
+	w.cleanupMu.Lock()
+	defer w.cleanupMu.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.seqWidth = width
+	files, err := listLogFiles(w.logDir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.id == 0 {
+			continue
+		}
+		name := getLogFileName(f.t, f.id, width)
+		if f.compressed {
+			name += ".gz"
+		}
+		if name == f.name {
+			continue
+		}
+		newPath := filepath.Join(w.logDir, name)
+		if _, serr := os.Lstat(newPath); serr == nil {
+			continue
+		}
+		oldPath := filepath.Join(w.logDir, f.name)
+		if rerr := renameFile(oldPath, newPath); rerr != nil {
+			if err == nil {
+				err = rerr
+			}
+		} else if oldPath == w.fpath {
+			w.fpath = newPath
+		}
+	}
+	return err
+}
+
+// SetMaxAge sets the max age of the rotated log files, older ones are removed on rotation.
+// Give a non positive maxAge to keep them regardless of their age.
+func (w *RotateWriter) SetMaxAge(maxAge time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxAge = maxAge
+}
+
+// SetMaxFiles sets the max number of the rotated log files, the oldest ones are removed
+// on rotation. Give a non positive maxFiles to keep them regardless of their number.
+func (w *RotateWriter) SetMaxFiles(maxFiles int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxFiles = maxFiles
+}
+
+// SetCompress sets whether to compress the rotated log files with gzip.
+func (w *RotateWriter) SetCompress(compress bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.compress = compress
+}
+
+// SetManifest sets whether to maintain a manifest of the log files in the log dir, named
+// ManifestFileName, which is updated after every rotation and cleanup.
+func (w *RotateWriter) SetManifest(manifest bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.manifest = manifest
+}
+
+// SetTrailer sets whether to write a trailer line at the end of every log file when it is
+// closed, summarizing the writes to it by this writer, excluding the preamble, and why it
+// is closed, e.g.
+//
+//	Log file closed at: 2024-01-02 16:00:00, reason: time, entries: 1024, bytes: 65536, first entry at: 2024-01-02 15:00:00.000001, last entry at: 2024-01-02 15:59:59.999999
+//
+// The reason is one of "time", "size", "rotate", "logdir" and "close".
+func (w *RotateWriter) SetTrailer(trailer bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.trailer = trailer
+}
+
+// closeFile writes the trailer if needed and closes the current log file,
+// it returns the name of the closed file. w.mu must be held.
+func (w *RotateWriter) closeFile(reason string) (string, error) {
+	// the process taking over goes on writing the handed over file
+	if w.trailer && !w.handedOver {
+		const layout = "2006-01-02 15:04:05.000000"
+		trailer := fmt.Sprintf("Log file closed at: %s, reason: %s, entries: %d, bytes: %d",
+			w.clock.Now().Format("2006-01-02 15:04:05"), reason, w.nwrites, w.nwritten)
+		if w.nwrites > 0 {
+			trailer += fmt.Sprintf(", first entry at: %s, last entry at: %s",
+				w.firstWrite.Format(layout), w.lastWrite.Format(layout))
+		}
+		// ignore error, the file is closed anyway
+		io.WriteString(w.f, trailer+"\n")
+	}
+
+	name := w.fpath
+	err := w.f.Close()
+	w.f = nil
+	w.nbytes = 0
+	w.nwrites = 0
+	w.nwritten = 0
+	return name, err
+}
+
+// SetPreamble sets the function returning the preamble written at the top of every new
+// log file, such as DefaultPreamble. Give nil to write no preamble.
+func (w *RotateWriter) SetPreamble(preamble func(now time.Time) string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.preamble = preamble
+}
+
+// cleanup compresses the rotated log file, removes the old log files and updates the
+// manifest in background, w.mu must be held.
+func (w *RotateWriter) cleanup(rotated string) {
+	compress, maxAge, maxFiles, manifest := w.compress, w.maxAge, w.maxFiles, w.manifest
+	if !compress && maxAge <= 0 && maxFiles <= 0 && !manifest {
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.cleanupMu.Lock()
+		defer w.cleanupMu.Unlock()
+
+		if compress {
+			// ignore error, the uncompressed file is kept
+			compressFile(rotated)
+		}
+
+		w.mu.Lock()
+		current := w.currentName()
+		w.mu.Unlock()
+		logDir := filepath.Dir(rotated)
+		if maxAge > 0 || maxFiles > 0 {
+			w.removeOldFiles(logDir, current, maxAge, maxFiles)
+		}
+		if manifest {
+			// ignore error, the manifest is updated on next rotation
+			writeManifest(logDir, current, w.clock.Now())
+		}
+	}()
+}
+
+// currentName returns the name of the current log file, w.mu must be held.
+func (w *RotateWriter) currentName() string {
+	fileName := w.fname
+	if w.fid > 0 {
+		fileName += fmt.Sprintf(".%0*d", w.seqWidth, w.fid)
+	}
+	return fileName
+}
+
+// removeOldFiles removes the log files in logDir older than maxAge and the oldest beyond
+// maxFiles, except the current one. The age of a log file is taken from the hour in its
+// name, rather than its modification time, so that it follows the clock of w.
+func (w *RotateWriter) removeOldFiles(logDir, current string, maxAge time.Duration, maxFiles int) {
+	files, err := listLogFiles(logDir)
+	if err != nil {
+		return
+	}
+
+	now := w.clock.Now()
+	n := 0
+	// newest first
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		if strings.TrimSuffix(f.name, ".gz") == current {
+			continue
+		}
+		if (maxFiles > 0 && n >= maxFiles) || (maxAge > 0 && now.Sub(f.t.Add(time.Hour)) > maxAge) {
+			removeFile(filepath.Join(logDir, f.name))
+		}
+		n++
+	}
+}
+
+// compressFile compresses the file at path into path.gz and removes it.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		src.Close()
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	// Windows does not remove the files open
+	src.Close()
+	if err != nil {
+		removeFile(path + ".gz")
+		return err
+	}
+	return removeFile(path)
+}
+
+// renameFile renames the file at oldPath to newPath, retrying while it is locked.
+func renameFile(oldPath, newPath string) error {
+	return retryLocked(func() error { return os.Rename(oldPath, newPath) })
+}
+
+// removeFile removes the file at path, retrying while it is locked.
+func removeFile(path string) error {
+	return retryLocked(func() error { return os.Remove(path) })
+}
+
+// retryLocked calls op, and again up to maxLockRetries times, after a delay doubling from
+// lockRetryDelay, while its error is caused by another process holding the file open,
+// such as an antivirus scanner on Windows, see fileLocked.
+func retryLocked(op func() error) error {
+	err := op()
+	for i, delay := 0, lockRetryDelay; i < maxLockRetries && fileLocked(err); i++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = op()
+	}
+	return err
+}
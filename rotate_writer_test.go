@@ -0,0 +1,352 @@
+package ylog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseLogFileName(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         int32
+		compressed bool
+		ok         bool
+	}{
+		{"2024010215.log", 0, false, true},
+		{"2024010215.log.3", 3, false, true},
+		{"2024010215.log.3.gz", 3, true, true},
+		{"2024010215.log.0", 0, false, false},
+		{"2024010215.txt", 0, false, false},
+		{"app.log", 0, false, false},
+	}
+	for _, tt := range tests {
+		ts, id, compressed, ok := parseLogFileName(tt.name)
+		if id != tt.id || compressed != tt.compressed || ok != tt.ok {
+			t.Errorf("parseLogFileName(%q) = %v, %v, %v, want %v, %v, %v",
+				tt.name, id, compressed, ok, tt.id, tt.compressed, tt.ok)
+		}
+		if ok && ts.Format("2006010215") != "2024010215" {
+			t.Errorf("parseLogFileName(%q) time = %v", tt.name, ts)
+		}
+	}
+}
+
+func TestRotateWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := NewRotateWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetLogSizeLimit(10)
+	w.SetCompress(true)
+	w.SetMaxFiles(2)
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("0123456789\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	base := getLogFileName(time.Now(), 0, 0)
+	var names []string
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	want := []string{base + ".2.gz", base + ".3.gz", base + ".4"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("log files %v, want %v", names, want)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, base+".4"))
+	if err != nil || string(b) != "0123456789\n" {
+		t.Fatalf("current log file contains %q, %v", b, err)
+	}
+}
+
+// fakeClock is a Clock returning a settable time.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRotateWriterClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 59, 0, 0, time.Local)}
+	w, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetLogSizeLimit(10)
+
+	w.Write([]byte("0123456789\n"))
+	w.Write([]byte("0123456789\n")) // too large
+	clock.Add(time.Minute)
+	w.Write([]byte("0123456789\n")) // next hour
+
+	for _, name := range []string{"2024010215.log", "2024010215.log.1", "2024010216.log"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != "0123456789\n" {
+			t.Errorf("log file %s contains %q, %v", name, b, err)
+		}
+	}
+}
+
+func TestRotateWriterPreamble(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := NewRotateWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetPreamble(DefaultPreamble)
+	w.Write([]byte("first\n"))
+	w.Rotate()
+	w.SetTrailer(true)
+	w.Write([]byte("second\n"))
+	w.Close()
+
+	// the first log file was created before the preamble was set
+	now := time.Now()
+	b, err := ioutil.ReadFile(filepath.Join(dir, getLogFileName(now, 0, 0)))
+	if err != nil || string(b) != "first\n" {
+		t.Fatalf("first log file contains %q, %v", b, err)
+	}
+	// the trailer counts the bytes of the writes only
+	b, err = ioutil.ReadFile(filepath.Join(dir, getLogFileName(now, 1, 0)))
+	if err != nil || !strings.HasPrefix(string(b), "Log file created at: ") ||
+		!strings.Contains(string(b), "\nsecond\nLog file closed at: ") || !strings.Contains(string(b), ", entries: 1, bytes: 7, ") ||
+		!strings.Contains(string(b), fmt.Sprintf("\nPID: %d\n", os.Getpid())) {
+		t.Fatalf("second log file contains %q, %v", b, err)
+	}
+}
+
+func TestRotateWriterTrailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)}
+	w, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetTrailer(true)
+	w.SetLogSizeLimit(10)
+	w.Write([]byte("012345\n"))
+	clock.Add(time.Second)
+	w.Write([]byte("012345\n"))
+	w.Write([]byte("012345\n")) // too large
+	w.Close()
+
+	want := map[string]string{
+		"2024010215.log": "012345\n012345\nLog file closed at: 2024-01-02 15:00:01, reason: size, entries: 2, bytes: 14, " +
+			"first entry at: 2024-01-02 15:00:00.000000, last entry at: 2024-01-02 15:00:01.000000\n",
+		"2024010215.log.1": "012345\nLog file closed at: 2024-01-02 15:00:01, reason: close, entries: 1, bytes: 7, " +
+			"first entry at: 2024-01-02 15:00:01.000000, last entry at: 2024-01-02 15:00:01.000000\n",
+	}
+	for name, content := range want {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != content {
+			t.Errorf("log file %s contains %q, %v, want %q", name, b, err, content)
+		}
+	}
+}
+
+func TestRotateWriterManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := NewRotateWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetManifest(true)
+	w.Write([]byte("first\n"))
+	w.Rotate()
+	w.Write([]byte("second\n"))
+	w.Close()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if len(m.Files) != 2 || m.Files[0].Name != getLogFileName(now, 0, 0) || m.Files[1].Name != getLogFileName(now, 1, 0) {
+		t.Fatalf("manifest lists %+v", m.Files)
+	}
+	sum := sha256.Sum256([]byte("first\n"))
+	if f := m.Files[0]; f.Current || f.Size != 6 || f.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("manifest lists rotated file %+v", f)
+	}
+	// closed by Close
+	sum = sha256.Sum256([]byte("second\n"))
+	if f := m.Files[1]; f.Current || f.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("manifest lists closed file %+v", f)
+	}
+}
+
+func TestRotateWriterMaxAgeClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)}
+	w, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetMaxAge(2 * time.Hour)
+
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("0123456789\n"))
+		clock.Add(time.Hour)
+	}
+	w.Write([]byte("0123456789\n"))
+	w.Close()
+
+	var names []string
+	files, _ := listLogFiles(dir)
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	// 15 ended at 16:00, more than 2h before 19:00
+	if want := "2024010216.log 2024010217.log 2024010218.log 2024010219.log"; strings.Join(names, " ") != want {
+		t.Fatalf("log files %v, want %s", names, want)
+	}
+}
+
+func TestRotateWriterSequenceWidth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// left by a previous process
+	for _, name := range []string{"2024010214.log.1.gz", "2024010214.log.2", "2024010214.log.0002"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)}
+	w, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetLogSizeLimit(10)
+	w.Write([]byte("0123456789\n"))
+	w.Write([]byte("0123456789\n")) // too large
+	if err := w.SetSequenceWidth(4); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("0123456789\n")) // too large
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	// the name taken is left as it is
+	want := "2024010214.log.0001.gz 2024010214.log.0002 2024010214.log.2 2024010215.log 2024010215.log.0001 2024010215.log.0002"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("log files %s, want %s", got, want)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "2024010215.log.0002"))
+	if string(b) != "0123456789\n" || err != nil {
+		t.Errorf("last log file contains %q, %v", b, err)
+	}
+}
+
+func TestRotateWriterResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// left by previous processes, beyond 100 files and with gaps
+	for _, name := range []string{"2024010215.log", "2024010215.log.1.gz", "2024010215.log.150.gz", "2024010215.log.151",
+		"2024010216.log.200"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.Local)}
+	w, err := NewRotateWriterWithClock(dir, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.SetLogSizeLimit(30)
+	w.Write([]byte("0123456789\n"))
+	w.Write([]byte("0123456789\n")) // too large
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "2024010215.log.151"))
+	if want := "2024010215.log.151\n0123456789\n"; string(b) != want || err != nil {
+		t.Errorf("resumed log file contains %q, %v, want %q", b, err, want)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "2024010215.log.152"))
+	if string(b) != "0123456789\n" || err != nil {
+		t.Errorf("next log file contains %q, %v", b, err)
+	}
+}
@@ -0,0 +1,168 @@
+package ylog
+
+import (
+	"sync"
+)
+
+// Router is a Logger which routes entries to destination loggers according to their tag.
+// Entries logged through the Router itself or with a tag without route go to the default
+// destinations, e.g.
+//
+//	r := NewRouter(appLogger)
+//	r.SetRoute("billing", billingLogger, kafkaLogger)
+//	r.Tagged("billing").Info("charged") // goes to billingLogger and kafkaLogger
+//	r.Info("started")                   // goes to appLogger
+//
+// Each destination only receives the entries allowed by its own log level, and by the
+// min log level set for it in the Router, see SetMinLevel, e.g.
+//
+//	r := NewRouter(consoleLogger, fileLogger)
+//	r.SetMinLevel(consoleLogger, WARN)
+//	r.Debug("dialing") // goes to fileLogger only
+type Router struct {
+	loggerMethods
+
+	mu       sync.RWMutex        // protects the following fields
+	defaults []Logger            // default destinations
+	routes   map[string][]Logger // destinations by tag
+	levels   map[Logger]LogLevel // min log levels of the destinations, copy on write
+}
+
+func NewRouter(defaults ...Logger) *Router {
+	r := &Router{defaults: defaults, routes: make(map[string][]Logger)}
+	r.loggerMethods = loggerMethods{r}
+	return r
+}
+
+// SetRoute sets the destinations of the entries tagged with tag.
+// Give no destination to remove the route.
+func (r *Router) SetRoute(tag string, dests ...Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(dests) == 0 {
+		delete(r.routes, tag)
+		return
+	}
+	r.routes[tag] = dests
+}
+
+// SetDefaultRoute sets the default destinations.
+func (r *Router) SetDefaultRoute(dests ...Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaults = dests
+}
+
+// SetMinLevel sets the min log level of the entries routed to dest, whichever its routes,
+// so that the destinations sharing a Router have their verbosities set in one place,
+// rather than by wrapping them with WithMinLevel. As for the other loggers, INFO and
+// FATAL entries are never dropped, and dest still drops the entries below its own log
+// level. Give TRACE to remove it. dest must be comparable, as the loggers of this
+// package are.
+func (r *Router) SetMinLevel(dest Logger, level LogLevel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	levels := make(map[Logger]LogLevel, len(r.levels)+1)
+	for l, ll := range r.levels {
+		levels[l] = ll
+	}
+	if level > TRACE {
+		levels[dest] = level
+	} else {
+		delete(levels, dest)
+	}
+	r.levels = levels
+}
+
+// Tagged returns a Logger which tags its entries with tag.
+func (r *Router) Tagged(tag string) Logger {
+	l := &taggedLogger{r: r, tag: tag}
+	l.loggerMethods = loggerMethods{l}
+	return l
+}
+
+func (r *Router) lowestLevel() LogLevel {
+	return minLogLevel(r.destinations(""))
+}
+
+func (r *Router) output(skipdepth int, level LogLevel, s string) error {
+	dests, levels := r.destinations("")
+	return outputToAll(dests, levels, skipdepth+1, level, s, nil)
+}
+
+func (r *Router) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	dests, levels := r.destinations("")
+	return outputToAll(dests, levels, skipdepth+1, level, s, fields)
+}
+
+// destinations returns the destinations of entries tagged with tag, "" for untagged
+// entries, and the min log levels of the destinations.
+func (r *Router) destinations(tag string) ([]Logger, map[Logger]LogLevel) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if dests, ok := r.routes[tag]; ok && tag != "" {
+		return dests, r.levels
+	}
+	return r.defaults, r.levels
+}
+
+// taggedLogger logs the entries tagged with tag through a Router.
+type taggedLogger struct {
+	loggerMethods
+	r   *Router
+	tag string
+}
+
+func (l *taggedLogger) lowestLevel() LogLevel {
+	return minLogLevel(l.r.destinations(l.tag))
+}
+
+func (l *taggedLogger) output(skipdepth int, level LogLevel, s string) error {
+	dests, levels := l.r.destinations(l.tag)
+	return outputToAll(dests, levels, skipdepth+1, level, s, nil)
+}
+
+func (l *taggedLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	dests, levels := l.r.destinations(l.tag)
+	return outputToAll(dests, levels, skipdepth+1, level, s, fields)
+}
+
+// minLogLevel returns the lowest log level of the loggers, raised to their min log
+// levels in levels.
+func minLogLevel(ls []Logger, levels map[Logger]LogLevel) LogLevel {
+	min := FATAL
+	for _, l := range ls {
+		if level := destLevel(l, levels); level < min {
+			min = level
+		}
+	}
+	return min
+}
+
+// destLevel returns the log level of l, raised to its min log level in levels.
+func destLevel(l Logger, levels map[Logger]LogLevel) LogLevel {
+	level := logLevelOf(l)
+	if len(levels) > 0 {
+		// the lookups of non comparable loggers panic even in an empty map
+		if min, ok := levels[l]; ok && min > level {
+			level = min
+		}
+	}
+	return level
+}
+
+// outputToAll outputs content with fields to the loggers whose log level, raised to their
+// min log levels in levels, allows it, skipdepth is counted as in Output. It returns the
+// first error.
+func outputToAll(ls []Logger, levels map[Logger]LogLevel, skipdepth int, level LogLevel, s string, fields []Field) error {
+	var err error
+	for _, l := range ls {
+		if level < INFO && destLevel(l, levels) > level {
+			continue
+		}
+		if e := outputFieldsTo(l, skipdepth+1, level, s, fields); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package ylog
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var app, billing, audit bytes.Buffer
+	appLogger := NewWriterLogger(&app, TRACE)
+	appLogger.SetFlags(Lshortfile | Lloglevel)
+	billingLogger := NewWriterLogger(&billing, TRACE)
+	billingLogger.SetFlags(Lshortfile | Lloglevel)
+	auditLogger := NewWriterLogger(&audit, WARN)
+	auditLogger.SetFlags(Lloglevel)
+
+	r := NewRouter(appLogger)
+	r.SetRoute("billing", billingLogger, auditLogger)
+
+	r.Info("started")
+	r.Tagged("billing").Debug("charging")
+	r.Tagged("billing").Warn("charge declined")
+	r.Tagged("unknown").Error("failed")
+
+	if got, want := app.String(), "router_test.go:24|INFO|started\nrouter_test.go:27|ERROR|failed\n"; got != want {
+		t.Errorf("app logged %q, want %q", got, want)
+	}
+	if got, want := billing.String(), "router_test.go:25|DEBUG|charging\nrouter_test.go:26|WARN|charge declined\n"; got != want {
+		t.Errorf("billing logged %q, want %q", got, want)
+	}
+	if got, want := audit.String(), "WARN|charge declined\n"; got != want {
+		t.Errorf("audit logged %q, want %q", got, want)
+	}
+}
+
+// errorLogger is a Logger which is not a depthLogger and records its errors.
+type errorLogger struct {
+	Logger
+	errors []string
+	exited bool
+}
+
+func (l *errorLogger) Error(v ...interface{}) { l.errors = append(l.errors, fmt.Sprint(v...)) }
+func (l *errorLogger) Fatal(v ...interface{}) { l.exited = true }
+
+func TestRouterFatal(t *testing.T) {
+	var buf bytes.Buffer
+	first := &errorLogger{}
+	last := NewWriterLogger(&buf, TRACE)
+	last.SetFlags(0)
+	r := NewRouter(first, last)
+
+	// as Fatal, without exiting
+	r.output(1, FATAL, "boom\n")
+	if first.exited || len(first.errors) != 1 || first.errors[0] != "FATAL|boom" {
+		t.Fatalf("first destination exited %v, logged %q", first.exited, first.errors)
+	}
+	if got := buf.String(); got != "FATAL|boom\n" {
+		t.Fatalf("last destination logged %q", got)
+	}
+}
+
+func TestRouterMinLevel(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var console, file bytes.Buffer
+	consoleLogger := NewWriterLogger(&console, TRACE)
+	consoleLogger.SetFlags(Lloglevel)
+	fileLogger := NewWriterLogger(&file, TRACE)
+	fileLogger.SetFlags(Lloglevel)
+
+	r := NewRouter(consoleLogger, fileLogger)
+	r.SetRoute("billing", consoleLogger)
+	r.SetMinLevel(consoleLogger, WARN)
+	if got := r.lowestLevel(); got != TRACE {
+		t.Fatalf("lowestLevel() = %v, want TRACE", got)
+	}
+	if got := r.Tagged("billing").(depthLogger).lowestLevel(); got != WARN {
+		t.Fatalf("lowestLevel() of the tagged logger = %v, want WARN", got)
+	}
+	r.Debug("dialing")
+	r.Tagged("billing").Debug("charging")
+	r.Warn("slow")
+	r.Info("started")
+	r.SetMinLevel(consoleLogger, TRACE)
+	r.Debug("dialed")
+
+	if got, want := console.String(), "WARN|slow\nINFO|started\nDEBUG|dialed\n"; got != want {
+		t.Errorf("console logged %q, want %q", got, want)
+	}
+	if got, want := file.String(), "DEBUG|dialing\nWARN|slow\nINFO|started\nDEBUG|dialed\n"; got != want {
+		t.Errorf("file logged %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package ylog
+
+import (
+	"io/ioutil"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RuntimeMetrics logs the stats of the Go runtime at an interval, such as
+//
+//	DEBUG|goroutines=12|heap_bytes=4194304|heap_sys_bytes=12582912|gc=3|gc_pause=1.2ms|gc_pause_max=800µs|fds=9|runtime
+//
+// where gc is the number of collections since the last entry, gc_pause their total pause
+// and gc_pause_max the longest one, for a basic observability on the hosts without
+// metrics agent. The number of open file descriptors is only logged where /proc/self/fd
+// lists them. Call Close to stop it.
+type RuntimeMetrics struct {
+	l     Logger
+	level LogLevel
+	done  chan struct{} // closed by Close
+	once  sync.Once     // closes done
+
+	mu         sync.Mutex // protects the following fields
+	numGC      uint32     // number of collections at the last entry
+	pauseTotal uint64     // total pause of the collections at the last entry, in ns
+}
+
+// NewRuntimeMetrics returns a RuntimeMetrics logging to l at level every interval, from
+// now on.
+func NewRuntimeMetrics(l Logger, interval time.Duration, level LogLevel) *RuntimeMetrics {
+	m := &RuntimeMetrics{l: l, level: level, done: make(chan struct{})}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	m.numGC, m.pauseTotal = ms.NumGC, ms.PauseTotalNs
+	go m.run(interval)
+	return m
+}
+
+func (m *RuntimeMetrics) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.Collect()
+		}
+	}
+}
+
+// Collect logs the stats of the runtime since the last entry. It is called every
+// interval.
+func (m *RuntimeMetrics) Collect() {
+	if m.level < INFO && logLevelOf(m.l) > m.level {
+		// spare stopping the world
+		return
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	m.mu.Lock()
+	gc := ms.NumGC - m.numGC
+	pause := time.Duration(ms.PauseTotalNs - m.pauseTotal)
+	var pauseMax time.Duration
+	// the ring of the recent pauses holds the last 256 ones
+	for i := uint32(0); i < gc && i < uint32(len(ms.PauseNs)); i++ {
+		if p := time.Duration(ms.PauseNs[(ms.NumGC-1-i)%uint32(len(ms.PauseNs))]); p > pauseMax {
+			pauseMax = p
+		}
+	}
+	m.numGC, m.pauseTotal = ms.NumGC, ms.PauseTotalNs
+	m.mu.Unlock()
+
+	fields := []Field{
+		{"goroutines", runtime.NumGoroutine()},
+		{"heap_bytes", ms.HeapAlloc},
+		{"heap_sys_bytes", ms.HeapSys},
+		{"gc", gc},
+		{"gc_pause", pause},
+		{"gc_pause_max", pauseMax},
+	}
+	if fds, err := ioutil.ReadDir("/proc/self/fd"); err == nil {
+		fields = append(fields, Field{"fds", len(fds)})
+	}
+	outputFieldsTo(m.l, 1, m.level, "runtime", fields)
+}
+
+// Close stops logging the stats.
+func (m *RuntimeMetrics) Close() {
+	m.once.Do(func() { close(m.done) })
+}
@@ -0,0 +1,29 @@
+package ylog
+
+import (
+	"bytes"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRuntimeMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, DEBUG)
+	l.SetFlags(0)
+	m := NewRuntimeMetrics(l, time.Hour, DEBUG)
+	defer m.Close()
+	runtime.GC()
+	m.Collect()
+	if got := buf.String(); !regexp.MustCompile(`^DEBUG\|goroutines=\d+\|heap_bytes=\d+\|heap_sys_bytes=\d+\|gc=[1-9]\d*\|gc_pause=\S+\|gc_pause_max=\S+\|(fds=\d+\|)?runtime\n$`).MatchString(got) {
+		t.Fatalf("logged %q", got)
+	}
+
+	buf.Reset()
+	l.SetLogLevel(ERROR)
+	m.Collect()
+	if buf.Len() != 0 {
+		t.Fatalf("logged %q below the log level", buf.String())
+	}
+}
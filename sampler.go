@@ -0,0 +1,188 @@
+package ylog
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxSampleFactor is the max number of entries an AdaptiveSampler keeps one out of.
+const maxSampleFactor = 1024
+
+// samplerFlushInterval is the interval at which an AdaptiveSampler reports the suppressed
+// entries while no entry is logged.
+var samplerFlushInterval = time.Second
+
+// AdaptiveSampler is a Logger passing the entries logged through it to another Logger,
+// sampling them while they are logged at a high rate, e.g. during an incident-induced
+// log storm. The sampler keeps 1 entry out of a power of two: each second more than the
+// threshold of entries would be kept, it increases the power so that they would not be,
+// and each second less than half of the threshold are kept, it decreases the power,
+// until all the entries are kept again.
+//
+// The number of suppressed entries is reported by an INFO entry once a second at most,
+// with the next entry logged or within a second otherwise, see Flush:
+//
+//	ylog: suppressed 9000 entries in the last 1s, keeping 1 entry out of 16
+//
+// ERROR and FATAL entries are never suppressed. Call Close to stop the sampler.
+type AdaptiveSampler struct {
+	loggerMethods
+	inner     Logger
+	threshold int
+	clock     atomic.Value  // clockHolder, source of the current time
+	done      chan struct{} // closed by Close
+	once      sync.Once     // closes done
+
+	mu         sync.Mutex // protects the following fields
+	start      time.Time  // start of the current second
+	n          int        // number of entries logged in the current second
+	seq        uint64     // number of entries subject to sampling
+	factor     uint64     // keeps 1 entry out of factor
+	suppressed uint64     // number of entries suppressed in the current second
+	total      uint64     // number of entries suppressed
+}
+
+// NewAdaptiveSampler returns an AdaptiveSampler passing the entries to inner, sampling
+// them while more than threshold entries are logged per second.
+func NewAdaptiveSampler(inner Logger, threshold int) *AdaptiveSampler {
+	if threshold < 1 {
+		threshold = 1
+	}
+	s := &AdaptiveSampler{inner: inner, threshold: threshold, factor: 1, done: make(chan struct{})}
+	s.loggerMethods = loggerMethods{s}
+	s.SetClock(SystemClock)
+	go s.run()
+	return s
+}
+
+// run reports the suppressed entries while no entry is logged, until Close.
+func (s *AdaptiveSampler) run() {
+	ticker := time.NewTicker(samplerFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.flushSummary()
+		}
+	}
+}
+
+// Flush reports the entries suppressed in the last second if it is over, and flushes
+// the inner logger if it is a Flusher.
+func (s *AdaptiveSampler) Flush() error {
+	s.flushSummary()
+	if f, ok := s.inner.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close reports the entries suppressed in the last second if it is over, and stops
+// reporting them in background. More calls have no effect.
+func (s *AdaptiveSampler) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	s.flushSummary()
+	return nil
+}
+
+// flushSummary logs the summary of the last second if it is over.
+func (s *AdaptiveSampler) flushSummary() {
+	now := s.clock.Load().(clockHolder).Now()
+	s.mu.Lock()
+	summary := s.rollover(now)
+	s.mu.Unlock()
+	if summary != "" {
+		outputTo(s.inner, 2, INFO, summary)
+	}
+}
+
+// SetClock sets the source of the current time.
+func (s *AdaptiveSampler) SetClock(clock Clock) {
+	s.clock.Store(clockHolder{clock})
+}
+
+// Suppressed returns the number of entries suppressed so far.
+func (s *AdaptiveSampler) Suppressed() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+// SampleFactor returns the current sampling, the sampler keeps 1 entry out of it.
+func (s *AdaptiveSampler) SampleFactor() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int(s.factor)
+}
+
+func (s *AdaptiveSampler) lowestLevel() LogLevel {
+	return logLevelOf(s.inner)
+}
+
+func (s *AdaptiveSampler) output(skipdepth int, level LogLevel, str string) error {
+	return s.outputFields(skipdepth+1, level, str, nil)
+}
+
+func (s *AdaptiveSampler) outputFields(skipdepth int, level LogLevel, str string, fields []Field) error {
+	keep, summary := s.sample(level)
+	if summary != "" {
+		outputTo(s.inner, skipdepth+1, INFO, summary)
+	}
+	if !keep {
+		return nil
+	}
+	return outputFieldsTo(s.inner, skipdepth+1, level, str, fields)
+}
+
+// sample returns whether to keep an entry with level, and the summary of the previous
+// second to log first if any.
+func (s *AdaptiveSampler) sample(level LogLevel) (keep bool, summary string) {
+	now := s.clock.Load().(clockHolder).Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary = s.rollover(now)
+
+	s.n++
+	if level == ERROR || level == FATAL {
+		return true, summary
+	}
+	s.seq++
+	if s.factor > 1 && s.seq%s.factor != 0 {
+		s.suppressed++
+		s.total++
+		return false, summary
+	}
+	return true, summary
+}
+
+// rollover starts a new second if the current one is over, adapting the sampling to its
+// entries, and returns its summary if entries were suppressed. s.mu must be held.
+func (s *AdaptiveSampler) rollover(now time.Time) (summary string) {
+	if elapsed := now.Sub(s.start); elapsed >= time.Second || elapsed < 0 {
+		threshold := uint64(s.threshold)
+		if kept := uint64(s.n) / s.factor; kept > threshold {
+			for s.factor < maxSampleFactor && uint64(s.n)/s.factor > threshold {
+				s.factor *= 2
+			}
+		} else if kept < threshold/2 && s.factor > 1 {
+			s.factor /= 2
+		}
+		// the following seconds had no entry
+		for d := 2 * time.Second; d <= elapsed && s.factor > 1; d += time.Second {
+			s.factor /= 2
+		}
+		if s.suppressed > 0 {
+			summary = fmt.Sprintf("ylog: suppressed %d entries in the last %v, keeping 1 entry out of %d\n",
+				s.suppressed, elapsed.Round(time.Second), s.factor)
+		}
+		s.start, s.n, s.suppressed = now, 0, 0
+	}
+	return summary
+}
@@ -0,0 +1,96 @@
+package ylog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveSampler(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)}
+	var buf bytes.Buffer
+	inner := NewWriterLogger(&buf, TRACE)
+	inner.SetFlags(Lloglevel)
+	s := NewAdaptiveSampler(inner, 10)
+	defer s.Close()
+	s.SetClock(clock)
+
+	count := func(line string) int {
+		n := strings.Count(buf.String(), line+"\n")
+		buf.Reset()
+		return n
+	}
+
+	// below the threshold in the first second
+	for i := 0; i < 40; i++ {
+		s.Debug("storm")
+	}
+	if n := count("DEBUG|storm"); n != 40 {
+		t.Fatalf("%d entries kept in the first second, want 40", n)
+	}
+
+	clock.Add(time.Second)
+	for i := 0; i < 40; i++ {
+		s.Debug("storm")
+	}
+	s.Error("outage")
+	if !strings.Contains(buf.String(), "ERROR|outage") {
+		t.Fatal("ERROR entry suppressed")
+	}
+	if n, f := count("DEBUG|storm"), s.SampleFactor(); n != 10 || f != 4 {
+		t.Fatalf("%d entries kept with sample factor %d, want 10 with 4", n, f)
+	}
+
+	clock.Add(time.Second)
+	s.Debug("storm")
+	if n := count("INFO|ylog: suppressed 30 entries in the last 1s, keeping 1 entry out of 4"); n != 1 {
+		t.Fatal("suppressed entries not reported")
+	}
+
+	// relaxed after the storm
+	clock.Add(10 * time.Second)
+	s.Debug("calm")
+	if n, f := count("DEBUG|calm"), s.SampleFactor(); n != 1 || f != 1 {
+		t.Fatalf("%d entries kept with sample factor %d after the storm, want 1 with 1", n, f)
+	}
+	if n := s.Suppressed(); n != 31 {
+		t.Fatalf("%d entries suppressed, want 31", n)
+	}
+}
+
+func TestAdaptiveSamplerFlush(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)}
+	var buf bytes.Buffer
+	inner := NewWriterLogger(&buf, TRACE)
+	inner.SetFlags(Lloglevel)
+	s := NewAdaptiveSampler(inner, 1)
+	s.SetClock(clock)
+
+	for i := 0; i < 8; i++ {
+		s.Debug("storm")
+	}
+	clock.Add(time.Second)
+	for i := 0; i < 8; i++ {
+		s.Debug("storm")
+	}
+	buf.Reset()
+
+	// the second is not over
+	s.Flush()
+	if buf.Len() != 0 {
+		t.Fatalf("logged %q before the end of the second", buf.String())
+	}
+	// reported without a next entry
+	clock.Add(time.Second)
+	s.Close()
+	if got, want := buf.String(), "INFO|ylog: suppressed 7 entries in the last 1s, keeping 1 entry out of 8\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package ylog
+
+import (
+	"fmt"
+	"io"
+)
+
+// SecretMask is the form of the Secret values in the logs.
+const SecretMask = "***"
+
+// SecretValue is a value logged as SecretMask, optionally followed by the last characters
+// of the original value, whatever the format verb or the encoder, e.g.
+//
+//	l.Infof("login with %v", ylog.Secret(password))                   // login with ***
+//	l.Infot("charged {card}", ylog.Field{"card", ylog.SecretLast4(card)}) // charged ***4242
+//
+// It does not keep the original value, so that even the reflection of the entries, e.g.
+// by encoding/json, cannot leak it.
+type SecretValue struct {
+	last string // last characters revealed
+}
+
+// Secret returns the SecretValue of v, logged as SecretMask.
+func Secret(v interface{}) SecretValue {
+	return SecretValue{}
+}
+
+// SecretLast4 returns the SecretValue of v revealing its last 4 characters, in the form
+// of fmt.Sprint, e.g. "***4242" for a card number. The values shorter than 8 characters
+// are masked entirely, the last ones being most of them.
+func SecretLast4(v interface{}) SecretValue {
+	r := []rune(fmt.Sprint(v))
+	if len(r) < 8 {
+		return SecretValue{}
+	}
+	return SecretValue{last: string(r[len(r)-4:])}
+}
+
+func (s SecretValue) String() string {
+	return SecretMask + s.last
+}
+
+// GoString returns the masked form for the %#v verb too.
+func (s SecretValue) GoString() string {
+	return s.String()
+}
+
+// Format writes the masked form for all the verbs, e.g. %x and %d.
+func (s SecretValue) Format(f fmt.State, verb rune) {
+	io.WriteString(f, SecretMask)
+	io.WriteString(f, s.last)
+}
+
+// MarshalText marshals the masked form, e.g. for encoding/json.
+func (s SecretValue) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// appendTo appends the masked form of s to buf.
+func (s SecretValue) appendTo(buf []byte) []byte {
+	buf = append(buf, SecretMask...)
+	return append(buf, s.last...)
+}
@@ -0,0 +1,47 @@
+package ylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	password := "hunter2hunter2"
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%d", "%10s"} {
+		if got := fmt.Sprintf(verb, Secret(password)); got != SecretMask {
+			t.Errorf("%s formatted %q, want %q", verb, got, SecretMask)
+		}
+	}
+	if got := fmt.Sprint(SecretLast4("4242424242424242")); got != "***4242" {
+		t.Errorf("SecretLast4 formatted %q, want ***4242", got)
+	}
+	if got := fmt.Sprint(SecretLast4("1234")); got != SecretMask {
+		t.Errorf("SecretLast4 of a short value formatted %q, want %q", got, SecretMask)
+	}
+	b, err := json.Marshal(map[string]interface{}{"password": Secret(password)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"password":"***"}`; got != want {
+		t.Errorf("marshaled %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lnologlevel)
+	l.Infot("charged {card}", Field{"card", SecretLast4("4242424242424242")}, Field{"password", Secret(password)})
+	for _, enc := range []Encoder{JSONEncoder{}, StrictEncoder{}, &DockerEncoder{}} {
+		l.SetEncoder(enc)
+		l.Info(Secret(password))
+		OutputFields(l, 1, INFO, "login", Field{"password", Secret(password)})
+	}
+	if got := buf.String(); strings.Contains(got, "hunter2") || strings.Contains(got, "42424242") {
+		t.Fatalf("secret leaked in %q", got)
+	}
+	if got, want := strings.SplitN(buf.String(), "\n", 2)[0], "card=***4242|password=***|charged ***4242"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package ylog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSentryRateLimit is the max number of events sent to Sentry per minute by default.
+const DefaultSentryRateLimit = 60
+
+// SentrySender is an AlertSender of the alerts to Sentry, as events carrying the message,
+// the fields as extra data and the stack of the entries, grouped by their fingerprint.
+// The events above the rate limit, or while Sentry asks to retry later, are dropped.
+type SentrySender struct {
+	Environment string       // environment of the events, e.g. "production"
+	Release     string       // release of the events, e.g. the version of the program
+	RateLimit   int          // max number of events sent per minute, DefaultSentryRateLimit if zero
+	Client      *http.Client // client of the requests, http.DefaultClient if nil
+
+	store string // URL of the store endpoint
+	auth  string // X-Sentry-Auth header
+
+	mu         sync.Mutex // protects the following fields
+	window     time.Time  // start of the current minute of the rate limit
+	sent       int        // number of events sent in the current minute
+	retryAfter time.Time  // time until which Sentry asked not to send events
+}
+
+// NewSentrySender returns a SentrySender of the project of dsn, the Data Source Name of
+// the project in Sentry, e.g. "https://key@o1.ingest.sentry.io/42".
+func NewSentrySender(dsn string) (*SentrySender, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndexByte(u.Path, '/')
+	if u.User == nil || u.User.Username() == "" || i < 0 || u.Path[i+1:] == "" {
+		return nil, fmt.Errorf("ylog: invalid Sentry DSN %q", dsn)
+	}
+	auth := "Sentry sentry_version=7, sentry_client=ylog/1.0, sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	store := u.Scheme + "://" + u.Host + u.Path[:i] + "/api/" + u.Path[i+1:] + "/store/"
+	return &SentrySender{store: store, auth: auth}, nil
+}
+
+// sentryLevels are the Sentry levels of the log levels.
+var sentryLevels = map[LogLevel]string{
+	TRACE: "debug",
+	DEBUG: "debug",
+	WARN:  "warning",
+	ERROR: "error",
+	INFO:  "info",
+	FATAL: "fatal",
+}
+
+// sentryFrame is a frame of the stack trace of a Sentry event.
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+}
+
+// sentryEvent is a Sentry event, see https://develop.sentry.dev/sdk/event-payloads/.
+type sentryEvent struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Platform    string                 `json:"platform"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger"`
+	Message     string                 `json:"message"`
+	Culprit     string                 `json:"culprit,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Fingerprint []string               `json:"fingerprint"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Stacktrace  *sentryStacktrace      `json:"stacktrace,omitempty"`
+}
+
+// sentryStacktrace is the stack trace of a Sentry event.
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+// SendAlert sends a as a Sentry event, or returns ErrRateLimited.
+func (s *SentrySender) SendAlert(a *Alert) error {
+	if !s.allow(time.Now()) {
+		return ErrRateLimited
+	}
+	b, err := marshalSentryEvent(a, s.Environment, s.Release)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.store, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retry := 60 * time.Second
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retry = time.Duration(secs) * time.Second
+		}
+		s.mu.Lock()
+		s.retryAfter = time.Now().Add(retry)
+		s.mu.Unlock()
+		return ErrRateLimited
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("ylog: sentry: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// allow reports whether an event can be sent at now under the rate limits.
+func (s *SentrySender) allow(now time.Time) bool {
+	limit := s.RateLimit
+	if limit <= 0 {
+		limit = DefaultSentryRateLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Before(s.retryAfter) {
+		return false
+	}
+	if now.Sub(s.window) >= time.Minute {
+		s.window, s.sent = now, 0
+	}
+	if s.sent >= limit {
+		return false
+	}
+	s.sent++
+	return true
+}
+
+// marshalSentryEvent returns the Sentry event of a.
+func marshalSentryEvent(a *Alert, environment, release string) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	ev := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   a.Time.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		Platform:    "go",
+		Level:       sentryLevels[a.Level],
+		Logger:      "ylog",
+		Message:     a.Message,
+		Culprit:     a.Func,
+		Environment: environment,
+		Release:     release,
+		Fingerprint: []string{a.Fingerprint},
+	}
+	if len(a.Fields) > 0 {
+		ev.Extra = make(map[string]interface{}, len(a.Fields))
+		for _, f := range a.Fields {
+			ev.Extra[f.Key] = f.Value
+		}
+	}
+	if len(a.Stack) > 0 {
+		ev.Stacktrace = new(sentryStacktrace)
+		// Sentry wants the outermost frame first
+		for i := len(a.Stack) - 1; i >= 0; i-- {
+			f := a.Stack[i]
+			ev.Stacktrace.Frames = append(ev.Stacktrace.Frames, sentryFrame{
+				Function: f.Function,
+				Filename: f.File[strings.LastIndexByte(f.File, '/')+1:],
+				AbsPath:  f.File,
+				Lineno:   f.Line,
+			})
+		}
+	}
+	b, err := json.Marshal(&ev)
+	if err != nil {
+		// fields may not be marshalable, fall back to their string forms
+		for k, v := range ev.Extra {
+			ev.Extra[k] = fmt.Sprint(v)
+		}
+		b, err = json.Marshal(&ev)
+	}
+	return b, err
+}
@@ -0,0 +1,65 @@
+package ylog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSentrySender(t *testing.T) {
+	var events []sentryEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/store/" {
+			t.Errorf("posted to %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("X-Sentry-Auth"); !strings.Contains(auth, "sentry_key=key") {
+			t.Errorf("X-Sentry-Auth is %q", auth)
+		}
+		var ev sentryEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Error(err)
+		}
+		events = append(events, ev)
+	}))
+	defer srv.Close()
+
+	s, err := NewSentrySender(strings.Replace(srv.URL, "://", "://key@", 1) + "/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RateLimit = 1
+	a := &Alert{
+		Entry:       Entry{Time: time.Now(), Level: ERROR, Func: "main.f", Message: "failed", Fields: []Field{{"order", 42}}},
+		Stack:       []runtime.Frame{{Function: "main.f", File: "/src/main.go", Line: 7}, {Function: "main.main", File: "/src/main.go", Line: 3}},
+		Fingerprint: "orders",
+	}
+	if err := s.SendAlert(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendAlert(a); err != ErrRateLimited {
+		t.Fatalf("sent above the rate limit: %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("sent %d events, want 1", len(events))
+	}
+	ev := events[0]
+	if ev.Level != "error" || ev.Message != "failed" || ev.Fingerprint[0] != "orders" || ev.Extra["order"] != 42.0 {
+		t.Fatalf("sent %+v", ev)
+	}
+	if f := ev.Stacktrace.Frames; len(f) != 2 || f[0].Function != "main.main" || f[1].Filename != "main.go" {
+		t.Fatalf("sent frames %+v", f)
+	}
+}
+
+func TestNewSentrySenderInvalid(t *testing.T) {
+	for _, dsn := range []string{"https://o1.ingest.sentry.io/42", "https://key@o1.ingest.sentry.io/"} {
+		if _, err := NewSentrySender(dsn); err == nil {
+			t.Errorf("accepted %q", dsn)
+		}
+	}
+}
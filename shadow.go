@@ -0,0 +1,119 @@
+package ylog
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// ShadowLogger is a Logger passing the entries logged through it to a primary Logger,
+// and duplicating a percentage of them to a candidate Logger, e.g. a new encoder or
+// collector validated in production before the cut over:
+//
+//	l := ylog.NewShadowLogger(fileLogger, collectorLogger, 10)
+//
+// The primary logger remains authoritative, the errors of the candidate one are not
+// reported but counted, and the outcomes of the duplicated entries are compared in the
+// counters of Stats. The
+// duplicated entries are spread evenly among the entries allowed by both log levels.
+type ShadowLogger struct {
+	loggerMethods
+	primary   Logger
+	candidate Logger
+	rate      uint64 // duplicated entries per 10000, accessed atomically
+	seq       uint64 // number of entries subject to duplication, accessed atomically
+
+	entries         uint64 // accessed atomically, see ShadowStats
+	shadowed        uint64 // accessed atomically, see ShadowStats
+	primaryErrors   uint64 // accessed atomically, see ShadowStats
+	candidateErrors uint64 // accessed atomically, see ShadowStats
+	mismatches      uint64 // accessed atomically, see ShadowStats
+}
+
+// ShadowStats are the counters of a ShadowLogger.
+type ShadowStats struct {
+	Entries         uint64 // entries passed to the primary logger
+	Shadowed        uint64 // entries duplicated to the candidate logger
+	PrimaryErrors   uint64 // entries failed by the primary logger
+	CandidateErrors uint64 // entries duplicated and failed by the candidate logger
+	Mismatches      uint64 // entries duplicated and failed by one logger only
+}
+
+// NewShadowLogger returns a ShadowLogger passing the entries to primary and duplicating
+// percent of them to candidate, see SetPercent.
+func NewShadowLogger(primary, candidate Logger, percent float64) *ShadowLogger {
+	l := &ShadowLogger{primary: primary, candidate: candidate}
+	l.loggerMethods = loggerMethods{l}
+	l.SetPercent(percent)
+	return l
+}
+
+// SetPercent sets the percentage of the entries duplicated to the candidate logger,
+// from 0 to 100, with two decimals, e.g. 0.5.
+func (l *ShadowLogger) SetPercent(percent float64) {
+	rate := math.Round(percent * 100)
+	switch {
+	case !(rate > 0): // including NaN
+		rate = 0
+	case rate > 10000:
+		rate = 10000
+	}
+	atomic.StoreUint64(&l.rate, uint64(rate))
+}
+
+// Stats returns the counters of the entries of the logger.
+func (l *ShadowLogger) Stats() ShadowStats {
+	return ShadowStats{
+		Entries:         atomic.LoadUint64(&l.entries),
+		Shadowed:        atomic.LoadUint64(&l.shadowed),
+		PrimaryErrors:   atomic.LoadUint64(&l.primaryErrors),
+		CandidateErrors: atomic.LoadUint64(&l.candidateErrors),
+		Mismatches:      atomic.LoadUint64(&l.mismatches),
+	}
+}
+
+// Flush flushes the primary and the candidate loggers if they are Flushers, it returns
+// the error of the primary one.
+func (l *ShadowLogger) Flush() error {
+	if f, ok := l.candidate.(Flusher); ok {
+		f.Flush()
+	}
+	if f, ok := l.primary.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (l *ShadowLogger) lowestLevel() LogLevel {
+	return logLevelOf(l.primary)
+}
+
+func (l *ShadowLogger) output(skipdepth int, level LogLevel, s string) error {
+	return l.outputFields(skipdepth+1, level, s, nil)
+}
+
+func (l *ShadowLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	atomic.AddUint64(&l.entries, 1)
+	shadow := false
+	if level >= INFO || logLevelOf(l.candidate) <= level {
+		rate := atomic.LoadUint64(&l.rate)
+		n := atomic.AddUint64(&l.seq, 1)
+		shadow = n*rate/10000 != (n-1)*rate/10000
+	}
+
+	err := outputFieldsTo(l.primary, skipdepth+1, level, s, fields)
+	if err != nil {
+		atomic.AddUint64(&l.primaryErrors, 1)
+	}
+	if !shadow {
+		return err
+	}
+	atomic.AddUint64(&l.shadowed, 1)
+	cerr := outputFieldsTo(l.candidate, skipdepth+1, level, s, fields)
+	if cerr != nil {
+		atomic.AddUint64(&l.candidateErrors, 1)
+	}
+	if (err == nil) != (cerr == nil) {
+		atomic.AddUint64(&l.mismatches, 1)
+	}
+	return err
+}
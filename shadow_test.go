@@ -0,0 +1,42 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestShadowLogger(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var primary, candidate bytes.Buffer
+	pl := NewWriterLogger(&primary, TRACE)
+	pl.SetFlags(Lshortfile | Lloglevel)
+	cl := NewWriterLogger(&candidate, WARN)
+	cl.SetFlags(Lshortfile | Lloglevel)
+	l := NewShadowLogger(pl, cl, 50)
+
+	l.Warn("first")
+	l.Warn("second")
+	l.Debug("not for the candidate")
+	l.Error("third")
+	l.Error("fourth")
+
+	if want := "shadow_test.go:21|WARN|second\nshadow_test.go:24|ERROR|fourth\n"; candidate.String() != want {
+		t.Errorf("candidate logged %q, want %q", candidate.String(), want)
+	}
+	if n := bytes.Count(primary.Bytes(), []byte("\n")); n != 5 {
+		t.Errorf("primary logged %d entries, want 5", n)
+	}
+
+	cl.SetOutput(writerFunc(func(p []byte) (int, error) { return 0, errors.New("collector down") }))
+	l.SetPercent(100)
+	if err := l.output(1, INFO, "fifth"); err != nil {
+		t.Errorf("output() = %v, want the nil error of the primary logger", err)
+	}
+	want := ShadowStats{Entries: 6, Shadowed: 3, CandidateErrors: 1, Mismatches: 1}
+	if got := l.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package ylog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCEFSeverities are the CEF severities, from 0 to 10, of the log levels by default.
+var DefaultCEFSeverities = map[LogLevel]int{
+	TRACE: 1,
+	DEBUG: 2,
+	WARN:  5,
+	ERROR: 7,
+	INFO:  3,
+	FATAL: 10,
+}
+
+// CEFEncoder is an Encoder of the entries in the Common Event Format of ArcSight, for the
+// SIEMs only ingesting it, such as
+//
+//	CEF:0|Acme|Shop|1.2|ERROR|payment failed|7|rt=1704207845123 order=42
+//
+// The Signature ID is the value of the "signature" field of the entry if any, otherwise
+// its log level name, and the Name is its message. The extension holds the time of the
+// entry and its other fields.
+type CEFEncoder struct {
+	Vendor     string           // Device Vendor
+	Product    string           // Device Product
+	Version    string           // Device Version
+	Severities map[LogLevel]int // severities of the log levels, DefaultCEFSeverities if nil
+}
+
+// Encode appends e to buf as a CEF event.
+func (enc *CEFEncoder) Encode(buf []byte, e *Entry) []byte {
+	severities := enc.Severities
+	if severities == nil {
+		severities = DefaultCEFSeverities
+	}
+	signature := siemSignature(e)
+
+	buf = append(buf, "CEF:0|"...)
+	for _, s := range []string{enc.Vendor, enc.Product, enc.Version, signature, e.Message} {
+		buf = appendCEFHeader(buf, s)
+		buf = append(buf, '|')
+	}
+	itoa(&buf, severities[e.Level], -1)
+	buf = append(buf, "|rt="...)
+	buf = append(buf, fmt.Sprint(e.Time.UnixNano()/1e6)...)
+	for _, f := range e.Fields {
+		if f.Key == "signature" {
+			continue
+		}
+		buf = append(buf, ' ')
+		buf = appendSIEMKey(buf, f.Key)
+		buf = append(buf, '=')
+		buf = appendCEFValue(buf, fmt.Sprint(f.Value))
+	}
+	return append(buf, '\n')
+}
+
+// LEEFEncoder is an Encoder of the entries in the Log Event Extended Format of QRadar,
+// alongside CEFEncoder, such as
+//
+//	LEEF:1.0|Acme|Shop|1.2|ERROR|devTime=Jan 02 2024 15:04:05.123 UTC	sev=7	msg=payment failed	order=42
+//
+// with tabs between the attributes. The Event ID is chosen as the Signature ID of
+// CEFEncoder, and the attributes hold the time, severity and message of the entry,
+// followed by its other fields.
+type LEEFEncoder struct {
+	Vendor     string           // Vendor
+	Product    string           // Product
+	Version    string           // Version
+	Severities map[LogLevel]int // severities of the log levels, DefaultCEFSeverities if nil
+}
+
+// Encode appends e to buf as a LEEF event.
+func (enc *LEEFEncoder) Encode(buf []byte, e *Entry) []byte {
+	severities := enc.Severities
+	if severities == nil {
+		severities = DefaultCEFSeverities
+	}
+
+	buf = append(buf, "LEEF:1.0|"...)
+	for _, s := range []string{enc.Vendor, enc.Product, enc.Version, siemSignature(e)} {
+		buf = appendCEFHeader(buf, s)
+		buf = append(buf, '|')
+	}
+	buf = append(buf, "devTime="...)
+	buf = e.Time.AppendFormat(buf, "Jan 02 2006 15:04:05.000 MST")
+	buf = append(buf, "\tsev="...)
+	itoa(&buf, severities[e.Level], -1)
+	buf = append(buf, "\tmsg="...)
+	buf = appendLEEFValue(buf, e.Message)
+	for _, f := range e.Fields {
+		if f.Key == "signature" {
+			continue
+		}
+		buf = append(buf, '\t')
+		buf = appendSIEMKey(buf, f.Key)
+		buf = append(buf, '=')
+		buf = appendLEEFValue(buf, fmt.Sprint(f.Value))
+	}
+	return append(buf, '\n')
+}
+
+// appendLEEFValue appends s to buf as a LEEF attribute value, whose tabs and line breaks
+// are replaced by spaces.
+func appendLEEFValue(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\t', '\r', '\n':
+			buf = append(buf, ' ')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// siemSignature returns the value of the "signature" field of e if any, otherwise its
+// log level name.
+func siemSignature(e *Entry) string {
+	for _, f := range e.Fields {
+		if f.Key == "signature" {
+			return fmt.Sprint(f.Value)
+		}
+	}
+	if e.Level == noLevel {
+		return "OUTPUT"
+	}
+	return e.Level.LogLevelName()
+}
+
+// appendCEFHeader appends s to buf as a CEF header field, escaping '|' and '\'.
+func appendCEFHeader(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '|', '\\':
+			buf = append(buf, '\\', c)
+		case '\r', '\n':
+			buf = append(buf, ' ')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// appendCEFValue appends s to buf as a CEF extension value, escaping '=', '\' and the
+// line breaks.
+func appendCEFValue(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '=', '\\':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, `\n`...)
+		case '\r':
+			buf = append(buf, `\r`...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// appendSIEMKey appends key to buf as an extension key, whose characters but the
+// letters, digits and '_' are replaced by '_'.
+func appendSIEMKey(buf []byte, key string) []byte {
+	if key == "" {
+		return append(buf, '_')
+	}
+	return append(buf, strings.Map(func(r rune) rune {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, key)...)
+}
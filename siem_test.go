@@ -0,0 +1,56 @@
+package ylog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCEFEncoder(t *testing.T) {
+	enc := &CEFEncoder{Vendor: "Acme", Product: "Shop|EU", Version: "1.2"}
+	e := &Entry{
+		Time:    time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.UTC),
+		Level:   ERROR,
+		Message: "payment failed",
+		Fields:  []Field{{"order", 42}, {"note", "a=b\nc"}, {"user name", `x\y`}},
+	}
+	want := `CEF:0|Acme|Shop\|EU|1.2|ERROR|payment failed|7|rt=1704207845123 order=42 note=a\=b\nc user_name=x\\y` + "\n"
+	if got := string(enc.Encode(nil, e)); got != want {
+		t.Fatalf("encoded %q, want %q", got, want)
+	}
+
+	e.Fields = []Field{{"signature", "login-failure"}}
+	enc.Severities = map[LogLevel]int{ERROR: 9}
+	want = "CEF:0|Acme|Shop\\|EU|1.2|login-failure|payment failed|9|rt=1704207845123\n"
+	if got := string(enc.Encode(nil, e)); got != want {
+		t.Fatalf("encoded %q, want %q", got, want)
+	}
+}
+
+func TestLEEFEncoder(t *testing.T) {
+	enc := &LEEFEncoder{Vendor: "Acme", Product: "Shop", Version: "1.2"}
+	l := NewWriterLogger(nil, TRACE)
+	var got []byte
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		got = append(got[:0], p...)
+		return len(p), nil
+	}))
+	l.SetEncoder(enc)
+	l.SetClock(&fakeClock{now: time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.UTC)})
+
+	l.AddInterceptor(func(e *Entry) bool {
+		e.Fields = append(e.Fields, Field{"order", 42}, Field{"note", "a\tb"})
+		return true
+	})
+	l.Error("payment failed")
+	want := "LEEF:1.0|Acme|Shop|1.2|ERROR|devTime=Jan 02 2024 15:04:05.123 UTC\tsev=7\tmsg=payment failed\torder=42\tnote=a b\n"
+	if string(got) != want {
+		t.Fatalf("encoded %q, want %q", got, want)
+	}
+}
+
+// writerFunc is an io.Writer calling itself.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,39 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package ylog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// HandleSignals installs a handler which flushes the default logger, see Flusher, on
+// os.Interrupt, then exits with status 1, since the signal cannot be raised again. On
+// Windows, see HandleRotateEvent for the rotation of the log files.
+// Call the returned function to uninstall the handler, more calls have no effect.
+func HandleSignals() (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, os.Interrupt)
+
+	go func() {
+		select {
+		case <-done:
+		case <-c:
+			if f, ok := DefaultLogger().(Flusher); ok {
+				f.Flush()
+			}
+			os.Exit(1)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
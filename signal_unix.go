@@ -0,0 +1,56 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ylog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals installs a handler which flushes the default logger, see Flusher, on
+// SIGTERM, SIGINT, SIGHUP and SIGUSR1. On SIGHUP and SIGUSR1 the default logger then
+// continues in a new log file if it supports it, such as RotateLogger, e.g. for
+// logrotate. On SIGTERM and SIGINT the signal is raised again once the logger is
+// flushed, which runs the default handler unless the signal is handled elsewhere.
+// Call the returned function to uninstall the handler, more calls have no effect.
+func HandleSignals() (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGUSR1)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-c:
+				l := DefaultLogger()
+				if f, ok := l.(Flusher); ok {
+					f.Flush()
+				}
+				if sig == syscall.SIGHUP || sig == syscall.SIGUSR1 {
+					if r, ok := l.(interface {
+						Rotate() error
+					}); ok {
+						r.Rotate()
+					}
+					continue
+				}
+				signal.Stop(c)
+				syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
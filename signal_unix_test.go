@@ -0,0 +1,51 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ylog
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignals(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ylog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := NewRotateLogger(dir, TRACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	old := DefaultLogger()
+	defer SetDefaultLogger(old)
+	SetDefaultLogger(l)
+
+	stop := HandleSignals()
+	defer stop()
+	for i, sig := range []syscall.Signal{syscall.SIGUSR1, syscall.SIGHUP} {
+		l.Info("before rotation")
+		if err := syscall.Kill(os.Getpid(), sig); err != nil {
+			t.Fatal(err)
+		}
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			files, err := l.LogFiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) == i+2 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%d log files after %v, want %d", len(files), sig, i+2)
+			}
+		}
+	}
+	stop()
+	stop()
+}
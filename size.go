@@ -0,0 +1,107 @@
+package ylog
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a size in bytes, such as a log file size limit, parsed by ParseSize. It is a
+// flag.Value and a pflag.Value, and is decoded from both the JSON numbers and strings,
+// e.g. 536870912 and "512MiB". The configuration libraries decoding maps, as viper,
+// decode the strings with a hook of encoding.TextUnmarshaler, e.g.
+// viper.DecodeHook(mapstructure.TextUnmarshallerHookFunc()).
+type Size int64
+
+// sizeUnits are the multiples of the size units, in lower case.
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kib": 1 << 10, "kb": 1e3,
+	"m": 1 << 20, "mib": 1 << 20, "mb": 1e6,
+	"g": 1 << 30, "gib": 1 << 30, "gb": 1e9,
+	"t": 1 << 40, "tib": 1 << 40, "tb": 1e12,
+}
+
+// ParseSize parses a size in bytes, a number optionally followed by a unit, e.g.
+// "100000", "512MB" or "1GiB". The units are case insensitive: B, KB, MB, GB and TB are
+// powers of 1000, KiB, MiB, GiB and TiB, as well as K, M, G and T, powers of 1024. The
+// number may be negative or have a fractional part, e.g. "1.5GiB", the size is then
+// truncated to a byte.
+func ParseSize(s string) (int64, error) {
+	t := strings.TrimSpace(s)
+	i := 0
+	for i < len(t) && ('0' <= t[i] && t[i] <= '9' || t[i] == '.' || i == 0 && t[i] == '-') {
+		i++
+	}
+	num, unit := t[:i], strings.ToLower(strings.TrimSpace(t[i:]))
+	mult, ok := sizeUnits[unit]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("ylog: invalid size %q", s)
+	}
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+		if n > math.MaxInt64/mult || n < math.MinInt64/mult {
+			return 0, fmt.Errorf("ylog: size %q out of range", s)
+		}
+		return n * mult, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ylog: invalid size %q", s)
+	}
+	f *= float64(mult)
+	if f >= math.MaxInt64 || f <= math.MinInt64 {
+		return 0, fmt.Errorf("ylog: size %q out of range", s)
+	}
+	return int64(f), nil
+}
+
+// String returns the size in the largest binary unit dividing it, e.g. "512MiB", or in
+// bytes, e.g. "100000".
+func (s Size) String() string {
+	for _, u := range []struct {
+		name string
+		mult int64
+	}{{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}} {
+		if s != 0 && int64(s)%u.mult == 0 {
+			return strconv.FormatInt(int64(s)/u.mult, 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(s), 10)
+}
+
+// Set sets the size parsed from v by ParseSize.
+func (s *Size) Set(v string) error {
+	n, err := ParseSize(v)
+	if err != nil {
+		return err
+	}
+	*s = Size(n)
+	return nil
+}
+
+// Type returns the type name of the size flags of pflag.
+func (s *Size) Type() string {
+	return "size"
+}
+
+// UnmarshalText sets the size parsed from text by ParseSize.
+func (s *Size) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
+
+// UnmarshalJSON sets the size from a JSON number of bytes or a JSON string parsed by
+// ParseSize.
+func (s *Size) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		v, err := strconv.Unquote(string(b))
+		if err != nil {
+			return fmt.Errorf("ylog: invalid size %s", b)
+		}
+		return s.Set(v)
+	}
+	if string(b) == "null" {
+		return nil
+	}
+	return s.Set(string(b))
+}
@@ -0,0 +1,62 @@
+package ylog
+
+import (
+	"encoding/json"
+	"flag"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+		ok   bool
+	}{
+		{"100000", 100000, true},
+		{"-1", -1, true},
+		{"512MB", 512e6, true},
+		{"512mb", 512e6, true},
+		{"512M", 512 << 20, true},
+		{"1GiB", 1 << 30, true},
+		{" 4 KiB ", 4096, true},
+		{"1.5GiB", 3 << 29, true},
+		{"10B", 10, true},
+		{"", 0, false},
+		{"MB", 0, false},
+		{"12PB", 0, false},
+		{"1.2.3", 0, false},
+		{"9000000TiB", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.s)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d, ok %v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	for s, want := range map[Size]string{DEFAULT_LOG_FILE_SIZE: "512MiB", 100000: "100000", 0: "0", 3 << 10: "3KiB"} {
+		if got := s.String(); got != want {
+			t.Errorf("Size(%d).String() = %q, want %q", int64(s), got, want)
+		}
+	}
+
+	var o Options
+	if err := json.Unmarshal([]byte(`{"log-size-limit": "1GiB"}`), &o); err != nil || o.LogSizeLimit != 1<<30 {
+		t.Errorf("decoded size %d, %v, want %d", o.LogSizeLimit, err, 1<<30)
+	}
+	if err := json.Unmarshal([]byte(`{"log-size-limit": 1024}`), &o); err != nil || o.LogSizeLimit != 1024 {
+		t.Errorf("decoded size %d, %v, want 1024", o.LogSizeLimit, err)
+	}
+	if err := json.Unmarshal([]byte(`{"log-size-limit": "big"}`), &o); err == nil {
+		t.Error("decoded an invalid size")
+	}
+
+	var size Size
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&size, "size", "")
+	if err := fs.Parse([]string{"-size", "512MB"}); err != nil || size != 512e6 {
+		t.Errorf("size flag set to %d, %v, want %d", size, err, int64(512e6))
+	}
+}
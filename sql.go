@@ -0,0 +1,346 @@
+package ylog
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the redacted query arguments in the logs of QueryLogger.
+const Redacted = "[REDACTED]"
+
+// RedactText is the default Redact function of QueryLogger, redacting the string and
+// []byte arguments, which may carry passwords or personal data, and keeping the others.
+func RedactText(arg driver.NamedValue) interface{} {
+	switch arg.Value.(type) {
+	case string, []byte:
+		return Redacted
+	}
+	return arg.Value
+}
+
+// QueryLogger logs the queries of database/sql through the drivers it wraps, with their
+// arguments, the number of rows affected by the statements and their duration, such as
+//
+//	exec duration=1.2ms rows=1 query="UPDATE users SET name = ? WHERE id = ?" args=["[REDACTED]" 42]
+//
+// The queries are logged at DEBUG level, the slow and failed ones at WARN level. Register
+// the wrapped driver, e.g.
+//
+//	sql.Register("logged-postgres", (&ylog.QueryLogger{Logger: l}).Wrap(&pq.Driver{}))
+//
+// or open the database with sql.OpenDB and a wrapped driver.Connector.
+type QueryLogger struct {
+	Logger        Logger                                  // logger of the queries
+	SlowThreshold time.Duration                           // duration from which the queries are slow, none if zero
+	Redact        func(arg driver.NamedValue) interface{} // logged value of the arguments, RedactText if nil
+}
+
+// Wrap returns a driver.Driver logging the queries sent through d.
+func (ql *QueryLogger) Wrap(d driver.Driver) driver.Driver {
+	return &sqlDriver{ql: ql, d: d}
+}
+
+// WrapConnector returns a driver.Connector logging the queries sent through c.
+func (ql *QueryLogger) WrapConnector(c driver.Connector) driver.Connector {
+	return &sqlConnector{ql: ql, c: c}
+}
+
+// log logs the query run by op since start, with args and the number of rows affected,
+// if not negative.
+func (ql *QueryLogger) log(op, query string, args []driver.NamedValue, rows int64, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		// run again another way
+		return
+	}
+	duration := time.Since(start)
+	level := DEBUG
+	if err != nil || ql.SlowThreshold > 0 && duration >= ql.SlowThreshold {
+		level = WARN
+	}
+	if logLevelOf(ql.Logger) > level {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s duration=%s", op, duration)
+	if rows >= 0 {
+		fmt.Fprintf(&b, " rows=%d", rows)
+	}
+	if query != "" {
+		fmt.Fprintf(&b, " query=%q", query)
+	}
+	if len(args) > 0 {
+		redact := ql.Redact
+		if redact == nil {
+			redact = RedactText
+		}
+		b.WriteString(" args=[")
+		for i, arg := range args {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if arg.Name != "" {
+				b.WriteString(arg.Name)
+				b.WriteByte('=')
+			}
+			switch v := redact(arg).(type) {
+			case string:
+				fmt.Fprintf(&b, "%q", v)
+			case []byte:
+				fmt.Fprintf(&b, "%q", v)
+			default:
+				fmt.Fprint(&b, v)
+			}
+		}
+		b.WriteByte(']')
+	}
+	if err != nil {
+		fmt.Fprintf(&b, " error=%q", err.Error())
+	}
+	outputTo(ql.Logger, 3, level, b.String())
+}
+
+type sqlDriver struct {
+	ql *QueryLogger
+	d  driver.Driver
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.d.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{ql: d.ql, c: c}, nil
+}
+
+func (d *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.d.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return d.ql.WrapConnector(c), nil
+	}
+	return &sqlConnector{ql: d.ql, c: dsnConnector{name: name, d: d.d}}, nil
+}
+
+// dsnConnector is the driver.Connector of the drivers without one.
+type dsnConnector struct {
+	name string
+	d    driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.d.Open(c.name)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.d
+}
+
+type sqlConnector struct {
+	ql *QueryLogger
+	c  driver.Connector
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{ql: c.ql, c: conn}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.ql.Wrap(c.c.Driver())
+}
+
+// sqlConn logs the queries run on a connection. It implements the optional interfaces
+// of driver.Conn, falling back as database/sql does if the wrapped one lacks them.
+type sqlConn struct {
+	ql *QueryLogger
+	c  driver.Conn
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if pc, ok := c.c.(driver.ConnPrepareContext); ok {
+		s, err = pc.PrepareContext(ctx, query)
+	} else {
+		s, err = c.c.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{ql: c.ql, s: s, query: query}, nil
+}
+
+func (c *sqlConn) Close() error {
+	return c.c.Close()
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.c.Begin()
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.c.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		return nil, fmt.Errorf("ylog: driver does not support transaction options")
+	}
+	return c.c.Begin()
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.c.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := ec.ExecContext(ctx, query, args)
+	c.ql.log("exec", query, args, rowsAffected(res, err), start, err)
+	return res, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.c.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	c.ql.log("query", query, args, -1, start, err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.c.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.c.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) CheckNamedValue(v *driver.NamedValue) error {
+	if nc, ok := c.c.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// sqlStmt logs the executions of a prepared statement.
+type sqlStmt struct {
+	ql    *QueryLogger
+	s     driver.Stmt
+	query string
+}
+
+func (s *sqlStmt) Close() error {
+	return s.s.Close()
+}
+
+func (s *sqlStmt) NumInput() int {
+	return s.s.NumInput()
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if ec, ok := s.s.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else if vs, e := positionalValues(args); e != nil {
+		err = e
+	} else {
+		res, err = s.s.Exec(vs)
+	}
+	s.ql.log("exec", s.query, args, rowsAffected(res, err), start, err)
+	return res, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.s.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else if vs, e := positionalValues(args); e != nil {
+		err = e
+	} else {
+		rows, err = s.s.Query(vs)
+	}
+	s.ql.log("query", s.query, args, -1, start, err)
+	return rows, err
+}
+
+func (s *sqlStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.s.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func (s *sqlStmt) CheckNamedValue(v *driver.NamedValue) error {
+	if nc, ok := s.s.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// rowsAffected returns the number of rows affected by the statement of res, -1 if unknown.
+func rowsAffected(res driver.Result, err error) int64 {
+	if err != nil || res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// namedValues returns the positional arguments vs as named ones.
+func namedValues(vs []driver.Value) []driver.NamedValue {
+	args := make([]driver.NamedValue, len(vs))
+	for i, v := range vs {
+		args[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return args
+}
+
+// positionalValues returns the arguments args as positional ones, for the drivers without
+// support of the named arguments.
+func positionalValues(args []driver.NamedValue) ([]driver.Value, error) {
+	vs := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("ylog: driver does not support the named argument %s", arg.Name)
+		}
+		vs[i] = arg.Value
+	}
+	return vs, nil
+}
@@ -0,0 +1,88 @@
+package ylog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, DEBUG)
+	ql := &QueryLogger{Logger: l, SlowThreshold: time.Second}
+	db := sql.OpenDB(ql.WrapConnector(dsnConnector{d: fakeDriver{}}))
+	defer db.Close()
+
+	if _, err := db.Exec("UPDATE users SET name = ? WHERE id = ?", "bob", 42); err != nil {
+		t.Fatal(err)
+	}
+	if want := `DEBUG|exec duration=`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+	if want := ` rows=2 query="UPDATE users SET name = ? WHERE id = ?" args=["[REDACTED]" 42]` + "\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if _, err := db.Query("SELECT sleep(?)", sql.Named("d", "slow")); err == nil {
+		t.Fatal("query succeeded, want an error")
+	}
+	if want := ` query="SELECT sleep(?)" args=[d="[REDACTED]"] error="slow query failed"` + "\n"; !strings.Contains(buf.String(), "WARN|query duration=") || !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	ql.Redact = func(arg driver.NamedValue) interface{} { return arg.Value }
+	stmt, err := db.Prepare("DELETE FROM users WHERE name = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if want := ` rows=2 query="DELETE FROM users WHERE name = ?" args=["bob"]` + "\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	l.SetLogLevel(WARN)
+	if _, err := db.ExecContext(context.Background(), "UPDATE users SET name = ?", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("logged %q below the log level", buf.String())
+	}
+}
+
+// fakeDriver is a database driver whose statements affect 2 rows and queries fail.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(2), nil
+}
+
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return nil, errors.New("slow query failed")
+}
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(2), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return nil, io.EOF }
@@ -0,0 +1,176 @@
+package ylog
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sseHeartbeatInterval is the interval of the heartbeat events sent to idle SSE clients.
+var sseHeartbeatInterval = 15 * time.Second
+
+// SSEServer is an http.Handler streaming the entries of a logger as Server-Sent Events,
+// see SSEHandler.
+type SSEServer struct {
+	l      Subscribable
+	cancel func()        // stops the recording of the recent entries, nil if none
+	done   chan struct{} // closed by Close
+	once   sync.Once     // closes done
+
+	mu      sync.Mutex              // protects the following fields
+	recent  []Entry                 // ring of the recent entries
+	next    int                     // index of the next recent entry in the ring, once it is full
+	clients map[chan Entry]struct{} // live entries of the clients, while recording
+}
+
+// SSEHandler returns an http.Handler streaming the entries of l as Server-Sent Events
+// (text/event-stream), for the environments where WebSocket is not available. Each
+// entry is sent as a message event carrying the JSON form of the entry, see
+// WebSocketHandler, and "heartbeat" events are sent while there is no entry to keep
+// the connection alive.
+//
+// If recent is positive, the handler keeps the last recent entries of l, and sends
+// them to the new clients followed by the live entries, without gap nor duplicate.
+// As for WebSocketHandler, the clients choose the entries with the "level" and "match"
+// query parameters. Call Close to stop the handler.
+func SSEHandler(l Subscribable, recent int) *SSEServer {
+	h := &SSEServer{l: l, done: make(chan struct{})}
+	if recent > 0 {
+		h.recent = make([]Entry, 0, recent)
+		h.clients = make(map[chan Entry]struct{})
+		var entries <-chan Entry
+		entries, h.cancel = l.Subscribe()
+		go h.record(entries)
+	}
+	return h
+}
+
+// Close stops recording the recent entries and ends the streams to the clients.
+// More calls have no effect.
+func (h *SSEServer) Close() error {
+	h.once.Do(func() {
+		if h.cancel != nil {
+			h.cancel()
+		}
+		close(h.done)
+	})
+	return nil
+}
+
+// record keeps the recent entries and forwards them to the clients.
+func (h *SSEServer) record(entries <-chan Entry) {
+	for e := range entries {
+		h.mu.Lock()
+		if len(h.recent) < cap(h.recent) {
+			h.recent = append(h.recent, e)
+		} else {
+			h.recent[h.next] = e
+			h.next = (h.next + 1) % len(h.recent)
+		}
+		for c := range h.clients {
+			select {
+			case c <- e:
+			default:
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// subscribe returns the recent entries, oldest first, and subscribes to the live ones
+// following them.
+func (h *SSEServer) subscribe() (recent []Entry, entries <-chan Entry, cancel func()) {
+	if h.clients == nil {
+		entries, cancel = h.l.Subscribe()
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	recent = make([]Entry, 0, len(h.recent))
+	recent = append(recent, h.recent[h.next:]...)
+	recent = append(recent, h.recent[:h.next]...)
+	c := make(chan Entry, SubscriberBufferSize)
+	h.clients[c] = struct{}{}
+	return recent, c, func() {
+		h.mu.Lock()
+		delete(h.clients, c)
+		h.mu.Unlock()
+	}
+}
+
+func (h *SSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStreamFilter(r.FormValue("level"), r.FormValue("match"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	recent, entries, cancel := h.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for i := range recent {
+		if _, err := writeSSEEntry(w, filter, &recent[i]); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	// the heartbeat is sent once the stream is idle for sseHeartbeatInterval
+	heartbeat := time.NewTimer(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.done:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, "event: heartbeat\ndata:\n\n"); err != nil {
+				return
+			}
+			heartbeat.Reset(sseHeartbeatInterval)
+		case e, ok := <-entries:
+			if !ok {
+				return
+			}
+			sent, err := writeSSEEntry(w, filter, &e)
+			if err != nil {
+				return
+			}
+			if !sent {
+				continue
+			}
+			if !heartbeat.Stop() {
+				<-heartbeat.C
+			}
+			heartbeat.Reset(sseHeartbeatInterval)
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSEEntry writes e as a message event if it is allowed by filter, and reports
+// whether it did.
+func writeSSEEntry(w http.ResponseWriter, filter *streamFilter, e *Entry) (sent bool, err error) {
+	if !filter.allow(e) {
+		return false, nil
+	}
+	b, err := marshalEntry(e)
+	if err != nil {
+		return false, nil
+	}
+	if _, err = fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+		return false, err
+	}
+	return true, nil
+}
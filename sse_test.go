@@ -0,0 +1,121 @@
+package ylog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSSEHandler(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	defer func(d time.Duration) { sseHeartbeatInterval = d }(sseHeartbeatInterval)
+	sseHeartbeatInterval = 100 * time.Millisecond
+
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	h := SSEHandler(l, 3)
+	defer h.Close()
+	l.Warn("a")
+	l.Warn("b")
+	l.Debug("c")
+	l.Warn("d")
+	// wait for the recent entries to be recorded
+	for {
+		h.mu.Lock()
+		n := h.next
+		h.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "?level=warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type is %q, want text/event-stream", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() (event, data string) {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				return
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data:"):
+				data = strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+			}
+		}
+	}
+	message := func(data string) string {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatal(err)
+		}
+		return e.Message
+	}
+
+	for _, want := range []string{"b", "d"} {
+		if event, data := readEvent(); event != "" || message(data) != want {
+			t.Fatalf("received event %q data %s, want recent message %q", event, data, want)
+		}
+	}
+	if event, _ := readEvent(); event != "heartbeat" {
+		t.Fatalf("received event %q, want heartbeat", event)
+	}
+	l.Debug("e")
+	l.Warn("f")
+	for {
+		event, data := readEvent()
+		if event == "heartbeat" {
+			continue
+		}
+		if got := message(data); got != "f" {
+			t.Fatalf("received message %q, want f", got)
+		}
+		break
+	}
+
+	// no heartbeat while entries are sent
+	go func() {
+		for i := 0; i < 20; i++ {
+			time.Sleep(10 * time.Millisecond)
+			l.Warn("busy")
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if event, data := readEvent(); event != "" || message(data) != "busy" {
+			t.Fatalf("received event %q data %s while busy", event, data)
+		}
+	}
+
+	// the stream ends once closed
+	h.Close()
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&l.subscribers.n); n != 0 {
+		t.Fatalf("%d subscribers left after Close", n)
+	}
+}
@@ -0,0 +1,74 @@
+package ylog
+
+// staticFieldsLogger attaches constant fields to the entries logged through it before
+// passing them to another Logger.
+type staticFieldsLogger struct {
+	loggerMethods
+	inner  Logger
+	fields []Field
+}
+
+// WithStaticFields returns a Logger passing the entries logged through it to inner with
+// the given fields, e.g. the service, environment, region and version of a component
+// of a multi-service binary. They are the Fields of the entries, before the ones of
+// the inner loggers and of the interceptors, and are written between the header and
+// the message by the text format, e.g. "service=billing|env=prod|charged".
+func WithStaticFields(inner Logger, fields []Field) Logger {
+	l := &staticFieldsLogger{inner: inner, fields: append([]Field(nil), fields...)}
+	l.loggerMethods = loggerMethods{l}
+	return l
+}
+
+func (l *staticFieldsLogger) lowestLevel() LogLevel {
+	return logLevelOf(l.inner)
+}
+
+func (l *staticFieldsLogger) output(skipdepth int, level LogLevel, s string) error {
+	return outputFieldsTo(l.inner, skipdepth+1, level, s, l.fields)
+}
+
+func (l *staticFieldsLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	all := make([]Field, 0, len(l.fields)+len(fields))
+	all = append(all, l.fields...)
+	all = append(all, fields...)
+	return outputFieldsTo(l.inner, skipdepth+1, level, s, all)
+}
+
+// groupLogger qualifies the keys of the fields of the entries logged through it with
+// the name of a group before passing them to another Logger.
+type groupLogger struct {
+	loggerMethods
+	inner  Logger
+	prefix string // name of the group followed by a dot
+}
+
+// WithGroup returns a Logger passing the entries logged through it to inner with the
+// keys of their fields qualified by the group name, as slog does, e.g.
+//
+//	l := WithStaticFields(WithGroup(inner, "http"), []Field{{"method", "GET"}})
+//	l.Info("served") // logs "http.method=GET|served"
+//
+// so that related fields are organized under a namespace. The fields attached by inner,
+// its own static fields or the interceptors, are not part of the group. The groups of
+// the nested loggers are nested.
+func WithGroup(inner Logger, name string) Logger {
+	l := &groupLogger{inner: inner, prefix: name + "."}
+	l.loggerMethods = loggerMethods{l}
+	return l
+}
+
+func (l *groupLogger) lowestLevel() LogLevel {
+	return logLevelOf(l.inner)
+}
+
+func (l *groupLogger) output(skipdepth int, level LogLevel, s string) error {
+	return outputTo(l.inner, skipdepth+1, level, s)
+}
+
+func (l *groupLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	grouped := make([]Field, len(fields))
+	for i, f := range fields {
+		grouped[i] = Field{l.prefix + f.Key, f.Value}
+	}
+	return outputFieldsTo(l.inner, skipdepth+1, level, s, grouped)
+}
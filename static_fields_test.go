@@ -0,0 +1,54 @@
+package ylog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithStaticFields(t *testing.T) {
+	var buf bytes.Buffer
+	inner := NewWriterLogger(&buf, TRACE)
+	inner.SetFlags(Lshortfile | Lloglevel)
+	var fields []Field
+	inner.AddInterceptor(func(e *Entry) bool {
+		fields = e.Fields
+		e.Fields = append(e.Fields, Field{"seq", 1})
+		return true
+	})
+	l := WithStaticFields(inner, []Field{{"service", "billing"}, {"env", "prod"}})
+
+	l.Info("charged")
+	WithPrefix(WithStaticFields(l, []Field{{"region", "eu"}}), "[refund] ").Warn("refunded")
+
+	want := "static_fields_test.go:20|INFO|service=billing|env=prod|seq=1|charged\n" +
+		"static_fields_test.go:21|WARN|service=billing|env=prod|region=eu|seq=1|[refund] refunded\n"
+	if buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+	if len(fields) != 3 || fields[2].Key != "region" {
+		t.Fatalf("intercepted fields %v, want the static fields", fields)
+	}
+
+	// the fields are prefixed to the content given to the other loggers
+	el := &errorLogger{}
+	WithStaticFields(el, []Field{{"service", "billing"}}).Error("failed")
+	if len(el.errors) != 1 || el.errors[0] != "service=billing|failed" {
+		t.Fatalf("logged %q, want the fields before the content", el.errors)
+	}
+}
+
+func TestWithGroup(t *testing.T) {
+	var buf bytes.Buffer
+	inner := NewWriterLogger(&buf, TRACE)
+	inner.SetFlags(Lloglevel)
+	l := WithGroup(WithStaticFields(inner, []Field{{"service", "shop"}}), "http")
+
+	WithStaticFields(l, []Field{{"method", "GET"}}).Info("served")
+	WithStaticFields(WithGroup(l, "req"), []Field{{"id", 7}}).Warn("slow")
+	l.Info("no fields")
+
+	want := "INFO|service=shop|http.method=GET|served\nWARN|service=shop|http.req.id=7|slow\nINFO|service=shop|no fields\n"
+	if buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+}
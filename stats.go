@@ -0,0 +1,200 @@
+package ylog
+
+import (
+	"time"
+)
+
+// Stats are the counters of a logger.
+type Stats struct {
+	Entries    map[string]uint64             `json:"entries"`     // number of entries output by log level
+	Rates      map[string]map[string]float64 `json:"rates"`       // entries per second by window, e.g. "1m", and log level
+	QueueDepth int                           `json:"queue_depth"` // number of writes queued by the AsyncWriter of the logger
+	QueueCap   int                           `json:"queue_cap"`   // max number of writes queued by the AsyncWriter of the logger
+	QueueHigh  int                           `json:"queue_high"`  // max number of writes queued so far by the AsyncWriter
+	Dropped    uint64                        `json:"dropped"`     // number of writes dropped by the AsyncWriter of the logger
+	Errors     uint64                        `json:"errors"`      // number of failed writes of the AsyncWriter of the logger
+	Latency    Histogram                     `json:"latency"`     // time spent outputting the entries, from lock wait to write
+}
+
+// Histogram is a histogram of durations.
+type Histogram struct {
+	Bounds []time.Duration `json:"bounds"` // upper bounds of the buckets but the last one
+	Counts []uint64        `json:"counts"` // number of durations by bucket
+	Count  uint64          `json:"count"`  // number of durations
+	Sum    time.Duration   `json:"sum"`    // sum of durations
+}
+
+// Quantile returns the upper bound of the bucket holding the q-quantile of the durations,
+// e.g. q = 0.99, or the largest bound if it is in the last bucket.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(h.Count))
+	var n uint64
+	for i, c := range h.Counts {
+		n += c
+		if n > rank && i < len(h.Bounds) {
+			return h.Bounds[i]
+		}
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// StatsReporter is implemented by the loggers reporting their counters,
+// such as WriterLogger and RotateLogger.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// statsWindows are the sliding windows of the entry rates.
+var statsWindows = []struct {
+	name    string
+	seconds int64
+}{
+	{"10s", 10},
+	{"1m", 60},
+	{"5m", 300},
+}
+
+const (
+	statsSeconds = 300       // seconds counted per second, the largest window
+	statsLevels  = FATAL + 2 // number of log levels, plus noLevel, counted from noLevel
+	statsOutput  = "OUTPUT"  // name of the log level of the entries output by Output
+)
+
+// latencyBounds are the bounds of the buckets of the output latency, from 1µs to about 1s.
+var latencyBounds = func() []time.Duration {
+	bounds := make([]time.Duration, latencyBuckets-1)
+	for i := range bounds {
+		bounds[i] = time.Microsecond << uint(i)
+	}
+	return bounds
+}()
+
+const latencyBuckets = 22
+
+// entryStats counts the entries output by a logger.
+type entryStats struct {
+	total   [statsLevels]uint64   // number of entries by log level
+	seconds [][statsLevels]uint32 // number of entries by second, ring indexed by unix time
+	last    int64                 // unix time of the last counted second
+
+	latency      [latencyBuckets]uint64 // number of outputs by latency bucket
+	latencyCount uint64                 // number of outputs
+	latencySum   time.Duration          // sum of the output latencies
+}
+
+func statsLevelName(level LogLevel) string {
+	if level == noLevel {
+		return statsOutput
+	}
+	return level.LogLevelName()
+}
+
+// record counts an entry with level output at t.
+func (s *entryStats) record(t time.Time, level LogLevel) {
+	i := level - noLevel
+	if i < 0 || i >= statsLevels {
+		return
+	}
+	s.total[i]++
+
+	sec := t.Unix()
+	if s.seconds == nil {
+		s.seconds = make([][statsLevels]uint32, statsSeconds)
+		s.last = sec
+	}
+	if sec > s.last {
+		// reset the seconds elapsed since the last one
+		for n := s.last + 1; n <= sec && n <= s.last+statsSeconds; n++ {
+			s.seconds[statsIndex(n)] = [statsLevels]uint32{}
+		}
+		s.last = sec
+	}
+	if sec > s.last-statsSeconds {
+		s.seconds[statsIndex(sec)][i]++
+	}
+}
+
+// statsIndex returns the index of the second sec in the ring of the seconds, the times
+// before 1970 included.
+func statsIndex(sec int64) int64 {
+	return (sec%statsSeconds + statsSeconds) % statsSeconds
+}
+
+// recordLatency counts an output which took d.
+func (s *entryStats) recordLatency(d time.Duration) {
+	i := 0
+	for i < len(latencyBounds) && d > latencyBounds[i] {
+		i++
+	}
+	s.latency[i]++
+	s.latencyCount++
+	s.latencySum += d
+}
+
+// fill fills the entry counters of st at now.
+func (s *entryStats) fill(st *Stats, now time.Time) {
+	st.Entries = make(map[string]uint64)
+	st.Rates = make(map[string]map[string]float64, len(statsWindows))
+	for i, n := range s.total {
+		if n != 0 {
+			st.Entries[statsLevelName(LogLevel(i)+noLevel)] = n
+		}
+	}
+
+	st.Latency = Histogram{
+		Bounds: append([]time.Duration(nil), latencyBounds...),
+		Counts: append([]uint64(nil), s.latency[:]...),
+		Count:  s.latencyCount,
+		Sum:    s.latencySum,
+	}
+
+	sec := now.Unix()
+	for _, w := range statsWindows {
+		rates := make(map[string]float64)
+		if s.seconds != nil {
+			// count the seconds of the window up to the last counted one
+			from, to := sec-w.seconds+1, sec
+			if min := s.last - statsSeconds + 1; from < min {
+				from = min
+			}
+			if to > s.last {
+				to = s.last
+			}
+			var counts [statsLevels]uint32
+			for n := from; n <= to; n++ {
+				for i, c := range s.seconds[statsIndex(n)] {
+					counts[i] += c
+				}
+			}
+			for i, c := range counts {
+				if c != 0 {
+					rates[statsLevelName(LogLevel(i)+noLevel)] = float64(c) / float64(w.seconds)
+				}
+			}
+		}
+		st.Rates[w.name] = rates
+	}
+}
+
+// Stats returns the counters of the entries output by the logger, and those of its
+// destination for output if it is an AsyncWriter.
+func (l *WriterLogger) Stats() Stats {
+	var st Stats
+	now := l.clock.Load().(clockHolder).Now()
+	l.mu.Lock()
+	l.stats.fill(&st, now)
+	out := l.out
+	l.mu.Unlock()
+
+	if w, ok := out.(*AsyncWriter); ok {
+		st.QueueDepth = w.QueueDepth()
+		st.QueueCap = w.QueueCapacity()
+		st.QueueHigh = w.HighWatermark()
+		st.Dropped = w.Dropped()
+		st.Errors = w.WriteErrors()
+	}
+	return st
+}
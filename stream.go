@@ -0,0 +1,87 @@
+package ylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Subscribable is implemented by the loggers whose entries can be observed,
+// such as WriterLogger and RotateLogger.
+type Subscribable interface {
+	Subscribe() (entries <-chan Entry, cancel func())
+}
+
+// streamFilter selects the entries streamed to a client.
+type streamFilter struct {
+	level LogLevel       // lowest log level streamed
+	match *regexp.Regexp // regular expression matching the message, nil matches all
+}
+
+// parseStreamFilter parses the log level name and the regular expression of a filter,
+// both optional.
+func parseStreamFilter(level, match string) (*streamFilter, error) {
+	f := &streamFilter{level: TRACE}
+	if level != "" {
+		var ok bool
+		if f.level, ok = LogLevelMap[strings.ToUpper(level)]; !ok {
+			return nil, fmt.Errorf("unknown log level %q", level)
+		}
+	}
+	if match != "" {
+		var err error
+		if f.match, err = regexp.Compile(match); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *streamFilter) allow(e *Entry) bool {
+	if e.Level != noLevel && e.Level < f.level {
+		return false
+	}
+	return f.match == nil || f.match.MatchString(e.Message)
+}
+
+// entryJSON is the JSON representation of an entry sent to the stream clients.
+type entryJSON struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level,omitempty"`
+	File    string                 `json:"file"`
+	Line    int                    `json:"line"`
+	Func    string                 `json:"func"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// marshalEntry returns the JSON representation of e.
+func marshalEntry(e *Entry) ([]byte, error) {
+	j := entryJSON{
+		Time:    e.Time,
+		File:    e.File,
+		Line:    e.Line,
+		Func:    e.Func,
+		Message: e.Message,
+	}
+	if e.Level != noLevel {
+		j.Level = e.Level.LogLevelName()
+	}
+	if len(e.Fields) > 0 {
+		j.Fields = make(map[string]interface{}, len(e.Fields))
+		for _, f := range e.Fields {
+			j.Fields[f.Key] = f.Value
+		}
+	}
+	b, err := json.Marshal(&j)
+	if err != nil {
+		// fields may not be marshalable, fall back to their string forms
+		for k, v := range j.Fields {
+			j.Fields[k] = fmt.Sprint(v)
+		}
+		b, err = json.Marshal(&j)
+	}
+	return b, err
+}
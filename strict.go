@@ -0,0 +1,69 @@
+package ylog
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// StrictEncoder is an Encoder of the entries in a strict variant of the text format,
+// which is machine parsable without loss: the package github.com/yplusplus/ylog/parse
+// reads back whatever it writes. The entries are written as
+//
+//	2024-01-02T15:04:05.123456789+08:00|42|ERROR|/src/app/main.go:42|main.charge|order=42|payment failed
+//
+// that is the time in RFC 3339 with nanoseconds, the sequence number, the log level name,
+// empty for the content without one, the file and line number, the function name, the
+// fields as "key=value", and the message last, separated by '|' whatever the fields.
+// The backslashes, separators, newlines and carriage returns of the elements are escaped
+// as `\\`, `\|`, `\n` and `\r`, as well as the '=' of the field keys as `\=`, so that an
+// entry is a single line. The field values are written in their string forms.
+type StrictEncoder struct{}
+
+// Encode appends e to buf in the strict text format.
+func (StrictEncoder) Encode(buf []byte, e *Entry) []byte {
+	buf = e.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, '|')
+	buf = strconv.AppendUint(buf, e.Seq, 10)
+	buf = append(buf, '|')
+	if e.Level != noLevel {
+		buf = append(buf, e.Level.LogLevelName()...)
+	}
+	buf = append(buf, '|')
+	buf = appendStrict(buf, e.File, false)
+	buf = append(buf, ':')
+	buf = strconv.AppendInt(buf, int64(e.Line), 10)
+	buf = append(buf, '|')
+	buf = appendStrict(buf, e.Func, false)
+	buf = append(buf, '|')
+	for _, f := range e.Fields {
+		buf = appendStrict(buf, f.Key, true)
+		buf = append(buf, '=')
+		if s, ok := f.Value.(string); ok {
+			buf = appendStrict(buf, s, false)
+		} else {
+			buf = appendStrict(buf, fmt.Sprint(f.Value), false)
+		}
+		buf = append(buf, '|')
+	}
+	buf = appendStrict(buf, e.Message, false)
+	return append(buf, '\n')
+}
+
+// appendStrict appends s to buf escaped for the strict text format, as well as its '='
+// if eq is set.
+func appendStrict(buf []byte, s string, eq bool) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' || c == '|' || eq && c == '=':
+			buf = append(buf, '\\', c)
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
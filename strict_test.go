@@ -0,0 +1,23 @@
+package ylog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStrictEncoder(t *testing.T) {
+	e := &Entry{
+		Time:    time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.UTC),
+		Seq:     42,
+		Level:   ERROR,
+		File:    "/src/app/main.go",
+		Line:    42,
+		Func:    "main.charge",
+		Message: "payment | failed\nretry",
+		Fields:  []Field{{"order", 42}, {"a=b", `c=d\`}},
+	}
+	want := `2024-01-02T15:04:05.123456789Z|42|ERROR|/src/app/main.go:42|main.charge|order=42|a\=b=c=d\\|payment \| failed\nretry` + "\n"
+	if got := string(StrictEncoder{}.Encode(nil, e)); got != want {
+		t.Errorf("encoded %q, want %q", got, want)
+	}
+}
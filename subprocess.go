@@ -0,0 +1,87 @@
+package ylog
+
+import (
+	"bufio"
+	"bytes"
+	"sync"
+)
+
+// LineWriter is an io.Writer splitting the content written to it into lines, logged one
+// by one at a log level, e.g. the output of a child process. The lines longer than
+// bufio.MaxScanTokenSize are split. Close logs the last line if it is not terminated.
+type LineWriter struct {
+	l      Logger
+	level  LogLevel
+	prefix string
+	detect bool // log the lines at the log level they start with, see DetectLevel
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewLineWriter returns a LineWriter logging the lines to l at level.
+func NewLineWriter(l Logger, level LogLevel) *LineWriter {
+	return &LineWriter{l: l, level: level}
+}
+
+// CommandOutput returns the LineWriters of the standard output and error of the child
+// process tagged tag, logging their lines to l at stdoutLevel and stderrLevel prefixed
+// with "child=tag|", as WithValues. Set them as the Stdout and Stderr of an exec.Cmd and
+// close them once it is waited for:
+//
+//	cmd := exec.Command("nginx", "-g", "daemon off;")
+//	stdout, stderr := ylog.CommandOutput(l, "nginx", ylog.INFO, ylog.ERROR)
+//	defer stdout.Close()
+//	defer stderr.Close()
+//	cmd.Stdout, cmd.Stderr = stdout, stderr
+//	err := cmd.Run()
+func CommandOutput(l Logger, tag string, stdoutLevel, stderrLevel LogLevel) (stdout, stderr *LineWriter) {
+	prefix := formatValues([]interface{}{"child", tag})
+	stdout = &LineWriter{l: l, level: stdoutLevel, prefix: prefix}
+	stderr = &LineWriter{l: l, level: stderrLevel, prefix: prefix}
+	return stdout, stderr
+}
+
+// Write logs the lines terminated in p, and keeps the rest until the next writes.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) >= bufio.MaxScanTokenSize {
+				w.logLine()
+			}
+			break
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.logLine()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close logs the last line if it is not terminated.
+func (w *LineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.logLine()
+	}
+	return nil
+}
+
+// logLine logs the line in buf, without its trailing '\r'.
+func (w *LineWriter) logLine() {
+	line := string(bytes.TrimSuffix(w.buf, []byte{'\r'}))
+	level := w.level
+	if w.detect {
+		if l, rest, ok := DetectLevel(line); ok {
+			level, line = l, rest
+		}
+	}
+	outputTo(w.l, 3, level, w.prefix+line)
+	w.buf = w.buf[:0]
+}
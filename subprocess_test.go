@@ -0,0 +1,47 @@
+package ylog
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+	w := NewLineWriter(l, WARN)
+	w.Write([]byte("first\r\nsec"))
+	w.Write([]byte("ond\n\nlast"))
+	if got, want := buf.String(), "WARN|first\nWARN|second\nWARN|\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+	w.Close()
+	if got, want := buf.String(), "WARN|first\nWARN|second\nWARN|\nWARN|last\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestCommandOutput(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip(err)
+	}
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+	stdout, stderr := CommandOutput(l, "child", INFO, ERROR)
+	cmd := exec.Command(sh, "-c", "echo out; echo err >&2")
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Close()
+	stderr.Close()
+	for _, want := range []string{"INFO|child=child|out\n", "ERROR|child=child|err\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("logged %q, want %q", buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ylog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SubscriberBufferSize is the number of entries buffered for each subscriber,
+// the entries are dropped for the subscriber while its buffer is full.
+const SubscriberBufferSize = 256
+
+// subscribers holds the subscribers of the entries output by a logger.
+// It is embedded by loggers to provide Subscribe.
+type subscribers struct {
+	n       int32  // number of subscribers, accessed atomically
+	dropped uint64 // number of entries dropped for the subscribers, accessed atomically
+
+	mu   sync.RWMutex // protects the following field
+	subs map[chan Entry]struct{}
+}
+
+// Subscribe returns a channel receiving the entries output by the logger from now on,
+// so that other parts of the program can observe them. The entries are dropped rather
+// than blocking the logger if the subscriber does not keep up. Call cancel to stop
+// receiving, which closes the channel.
+func (s *subscribers) Subscribe() (entries <-chan Entry, cancel func()) {
+	c := make(chan Entry, SubscriberBufferSize)
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[chan Entry]struct{})
+	}
+	s.subs[c] = struct{}{}
+	atomic.AddInt32(&s.n, 1)
+	s.mu.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, c)
+			atomic.AddInt32(&s.n, -1)
+			s.mu.Unlock()
+			close(c)
+		})
+	}
+}
+
+// SubscriberDropped returns the number of entries dropped for the subscribers which did
+// not keep up, counted once for each of them.
+func (s *subscribers) SubscriberDropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// publish sends e to the subscribers whose buffer is not full.
+func (s *subscribers) publish(e *Entry) {
+	if atomic.LoadInt32(&s.n) == 0 {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for c := range s.subs {
+		select {
+		case c <- *e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package ylog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Facility is a syslog facility, the part of the program logging, see RFC 5424.
+type Facility int
+
+// syslog facilities
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	_
+	_
+	_
+	_
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Severity is a syslog severity, see RFC 5424.
+type Severity int
+
+// syslog severities
+const (
+	SeverityEmergency Severity = iota
+	SeverityAlert
+	SeverityCritical
+	SeverityError
+	SeverityWarning
+	SeverityNotice
+	SeverityInfo
+	SeverityDebug
+)
+
+// DefaultSeverities are the syslog severities of the log levels by default. The content
+// given to Output, without log level, has the notice severity.
+var DefaultSeverities = map[LogLevel]Severity{
+	TRACE: SeverityDebug,
+	DEBUG: SeverityDebug,
+	WARN:  SeverityWarning,
+	ERROR: SeverityError,
+	INFO:  SeverityInfo,
+	FATAL: SeverityCritical,
+}
+
+// DefaultSDID is the SD-ID of the structured data element of the fields in the RFC 5424
+// messages by default, with the enterprise number reserved for documentation: set the
+// SDID of SyslogEncoder to one with the private enterprise number of the organization.
+const DefaultSDID = "fields@32473"
+
+// SyslogEncoder is an Encoder of the entries as syslog messages (RFC 3164), such as
+//
+//	<11>Jan  2 15:04:05 host app[42]: disk failed
+//
+// The fields are written before the message as "key=value|", as in the text format.
+// If RFC5424 is set, the messages follow RFC 5424 instead and the fields are written as
+// the parameters of a structured data element, so that they survive the syslog relays:
+//
+//	<11>1 2024-01-02T15:04:05.000000+08:00 host app 42 - [fields@32473 disk="sda"] disk failed
+type SyslogEncoder struct {
+	Facility   Facility              // facility of the messages, FacilityUser if zero
+	Severities map[LogLevel]Severity // severities of the log levels, DefaultSeverities if nil
+	Hostname   string                // host name, os.Hostname() if empty
+	Tag        string                // name of the program (APP-NAME), the base name of os.Args[0] if empty
+	RFC5424    bool                  // encode the messages as RFC 5424 ones
+	MsgID      string                // type of the RFC 5424 messages (MSGID), none if empty
+	SDID       string                // SD-ID of the element of the fields, DefaultSDID if empty
+}
+
+// hostname returns the host name of the messages.
+func (enc *SyslogEncoder) hostname() string {
+	if enc.Hostname != "" {
+		return enc.Hostname
+	}
+	return hostname
+}
+
+// tag returns the name of the program of the messages.
+func (enc *SyslogEncoder) tag() string {
+	if enc.Tag != "" {
+		return enc.Tag
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// priority returns the priority of the messages of the entries with level.
+func (enc *SyslogEncoder) priority(level LogLevel) int {
+	facility := enc.Facility
+	if facility == FacilityKern {
+		// the kernel facility is reserved to the kernel
+		facility = FacilityUser
+	}
+	severities := enc.Severities
+	if severities == nil {
+		severities = DefaultSeverities
+	}
+	severity, ok := severities[level]
+	if !ok {
+		severity = SeverityNotice
+	}
+	return int(facility)*8 + int(severity)
+}
+
+// Encode appends e to buf as a syslog message.
+func (enc *SyslogEncoder) Encode(buf []byte, e *Entry) []byte {
+	if enc.RFC5424 {
+		return enc.encodeRFC5424(buf, e)
+	}
+	buf = append(buf, '<')
+	itoa(&buf, enc.priority(e.Level), -1)
+	buf = append(buf, '>')
+	buf = e.Time.AppendFormat(buf, "Jan _2 15:04:05")
+	buf = append(buf, ' ')
+	buf = append(buf, enc.hostname()...)
+	buf = append(buf, ' ')
+	buf = append(buf, enc.tag()...)
+	buf = append(buf, '[')
+	itoa(&buf, os.Getpid(), -1)
+	buf = append(buf, "]: "...)
+	for _, f := range e.Fields {
+		buf = append(buf, f.Key...)
+		buf = append(buf, '=')
+		buf = appendFieldValue(buf, f.Value)
+		buf = append(buf, '|')
+	}
+	buf = append(buf, e.Message...)
+	return append(buf, '\n')
+}
+
+// encodeRFC5424 appends e to buf as an RFC 5424 message.
+func (enc *SyslogEncoder) encodeRFC5424(buf []byte, e *Entry) []byte {
+	buf = append(buf, '<')
+	itoa(&buf, enc.priority(e.Level), -1)
+	buf = append(buf, ">1 "...)
+	buf = e.Time.AppendFormat(buf, "2006-01-02T15:04:05.000000Z07:00")
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, enc.hostname(), 255)
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, enc.tag(), 48)
+	buf = append(buf, ' ')
+	itoa(&buf, os.Getpid(), -1)
+	buf = append(buf, ' ')
+	buf = appendSyslogName(buf, enc.MsgID, 32)
+	buf = append(buf, ' ')
+	if len(e.Fields) == 0 {
+		buf = append(buf, '-')
+	} else {
+		sdid := enc.SDID
+		if sdid == "" {
+			sdid = DefaultSDID
+		}
+		buf = append(buf, '[')
+		buf = append(buf, sdid...)
+		for _, f := range e.Fields {
+			buf = append(buf, ' ')
+			buf = appendSyslogName(buf, f.Key, 32)
+			buf = append(buf, `="`...)
+			for _, c := range []byte(fmt.Sprint(f.Value)) {
+				if c == '"' || c == '\\' || c == ']' {
+					buf = append(buf, '\\')
+				}
+				buf = append(buf, c)
+			}
+			buf = append(buf, '"')
+		}
+		buf = append(buf, ']')
+	}
+	if e.Message != "" {
+		buf = append(buf, ' ')
+		buf = append(buf, e.Message...)
+	}
+	return append(buf, '\n')
+}
+
+// appendSyslogName appends to buf name as a header field or parameter name of RFC 5424,
+// at most max printable characters, the nil value "-" if it is empty. The characters not
+// allowed in the parameter names are replaced by '_'.
+func appendSyslogName(buf []byte, name string, max int) []byte {
+	if name == "" {
+		return append(buf, '-')
+	}
+	if len(name) > max {
+		name = name[:max]
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// syslogSockets are the sockets of the local syslog daemon.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// DialSyslog connects to the syslog daemon at addr over network, e.g. "udp" and
+// "localhost:514", or to the local one if both are empty.
+func DialSyslog(network, addr string) (net.Conn, error) {
+	if network != "" || addr != "" {
+		return net.Dial(network, addr)
+	}
+	for _, path := range syslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("ylog: local syslog daemon not found")
+}
+
+// NewSyslogLogger returns a WriterLogger sending the entries with level to the syslog
+// daemon at addr over network, see DialSyslog, encoded by enc, or by the zero value of
+// SyslogEncoder if enc is nil.
+func NewSyslogLogger(network, addr string, level LogLevel, enc *SyslogEncoder) (*WriterLogger, error) {
+	conn, err := DialSyslog(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		enc = &SyslogEncoder{}
+	}
+	l := NewWriterLogger(conn, level)
+	l.SetEncoder(enc)
+	return l, nil
+}
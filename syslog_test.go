@@ -0,0 +1,67 @@
+package ylog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSyslogLogger(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	pc.SetDeadline(time.Now().Add(5 * time.Second))
+
+	enc := &SyslogEncoder{Hostname: "host", Tag: "app"}
+	l, err := NewSyslogLogger("udp", pc.LocalAddr().String(), DEBUG, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)}
+	l.SetClock(clock)
+	read := func() string {
+		b := make([]byte, 1024)
+		n, _, err := pc.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b[:n])
+	}
+
+	l.Error("disk failed")
+	if got, want := read(), fmt.Sprintf("<11>Jan  2 15:04:05 host app[%d]: disk failed\n", os.Getpid()); got != want {
+		t.Fatalf("sent %q, want %q", got, want)
+	}
+
+	enc.Facility = FacilityLocal3
+	enc.Severities = map[LogLevel]Severity{INFO: SeverityNotice}
+	l.Info("started")
+	if got, want := read(), fmt.Sprintf("<157>Jan  2 15:04:05 host app[%d]: started\n", os.Getpid()); got != want {
+		t.Fatalf("sent %q, want %q", got, want)
+	}
+}
+
+func TestSyslogEncoderRFC5424(t *testing.T) {
+	enc := &SyslogEncoder{Facility: FacilityDaemon, Hostname: "host", Tag: "app", RFC5424: true, MsgID: "disk"}
+	e := &Entry{
+		Time:    time.Date(2024, 1, 2, 15, 4, 5, 123456000, time.UTC),
+		Level:   WARN,
+		Message: "disk almost full",
+		Fields:  []Field{{"dev", "sda"}, {"bad key", `a "quoted\] value`}},
+	}
+	want := fmt.Sprintf(`<28>1 2024-01-02T15:04:05.123456Z host app %d disk [fields@32473 dev="sda" bad_key="a \"quoted\\\] value"] disk almost full`+"\n", os.Getpid())
+	if got := string(enc.Encode(nil, e)); got != want {
+		t.Fatalf("encoded %q, want %q", got, want)
+	}
+
+	enc.MsgID, enc.SDID = "", "ylog@12345"
+	e.Fields = nil
+	want = fmt.Sprintf("<28>1 2024-01-02T15:04:05.123456Z host app %d - - disk almost full\n", os.Getpid())
+	if got := string(enc.Encode(nil, e)); got != want {
+		t.Fatalf("encoded %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package ylog
+
+import (
+	"io"
+	"sync"
+)
+
+// TeeWriter writes each entry to several io.Writers, so that a logger formats its entries
+// once for all its destinations, e.g.
+//
+//	l := ylog.NewWriterLogger(ylog.NewTeeWriter(os.Stderr, rotateWriter, ylog.NewAsyncWriter(conn, 1024)), ylog.INFO)
+//
+// A failing destination is reported to the error handler, see SetErrorHandler, but
+// neither fails the write nor skips the following destinations. The destinations are
+// written in order by the writing goroutine, write the ones which may stall, such as a
+// network connection, through an AsyncWriter, which drops the entries rather than
+// blocking the others.
+type TeeWriter struct {
+	outs []io.Writer // destinations
+
+	mu           sync.Mutex                     // ensures atomic writes; protects the following field
+	errorHandler func(out io.Writer, err error) // called with the errors of the destinations
+}
+
+// NewTeeWriter returns a TeeWriter writing to outs.
+func NewTeeWriter(outs ...io.Writer) *TeeWriter {
+	return &TeeWriter{outs: append([]io.Writer(nil), outs...)}
+}
+
+// teeError is the error of a destination of a TeeWriter.
+type teeError struct {
+	out io.Writer
+	err error
+}
+
+// Write writes p to all the destinations, it never fails.
+func (w *TeeWriter) Write(p []byte) (int, error) {
+	return w.write(p, false, noLevel)
+}
+
+// WriteLevel writes p to all the destinations, passing level to the LevelWriters, such
+// as AsyncWriter, so that they handle it as an entry of level. It never fails.
+func (w *TeeWriter) WriteLevel(level LogLevel, p []byte) (int, error) {
+	return w.write(p, true, level)
+}
+
+// write writes p to all the destinations, with level to the LevelWriters if leveled.
+func (w *TeeWriter) write(p []byte, leveled bool, level LogLevel) (int, error) {
+	var errs []teeError
+	w.mu.Lock()
+	for _, out := range w.outs {
+		var (
+			n   int
+			err error
+		)
+		if lw, ok := out.(LevelWriter); ok && leveled {
+			n, err = lw.WriteLevel(level, p)
+		} else {
+			n, err = out.Write(p)
+		}
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			errs = append(errs, teeError{out, err})
+		}
+	}
+	handler := w.errorHandler
+	w.mu.Unlock()
+
+	if handler != nil {
+		for _, e := range errs {
+			handler(e.out, e.err)
+		}
+	}
+	return len(p), nil
+}
+
+// SetErrorHandler sets the function called with the destinations whose write failed and
+// their errors. It is called by the writing goroutine once the entry is written to all
+// the destinations, and must not write to w. Give nil to remove it.
+func (w *TeeWriter) SetErrorHandler(handler func(out io.Writer, err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errorHandler = handler
+}
+
+// Flush flushes the destinations which buffer, and returns the first error.
+func (w *TeeWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for _, out := range w.outs {
+		if f, ok := out.(Flusher); ok {
+			if ferr := f.Flush(); err == nil {
+				err = ferr
+			}
+		}
+	}
+	return err
+}
+
+// Close closes the destinations which are io.Closers, but os.Stdout and os.Stderr, and
+// returns the first error.
+func (w *TeeWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for _, out := range w.outs {
+		if c, ok := out.(io.Closer); ok && !isStdStream(out) {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// levelBuffer is a LevelWriter recording the log levels of the writes.
+type levelBuffer struct {
+	bytes.Buffer
+	levels []LogLevel
+}
+
+func (b *levelBuffer) WriteLevel(level LogLevel, p []byte) (int, error) {
+	b.levels = append(b.levels, level)
+	return b.Write(p)
+}
+
+func TestTeeWriter(t *testing.T) {
+	var first, last bytes.Buffer
+	leveled := &levelBuffer{}
+	errFailed := errors.New("failed")
+	failing := writerFunc(func(p []byte) (int, error) { return 0, errFailed })
+	w := NewTeeWriter(&first, failing, leveled, &last)
+	var failed []error
+	w.SetErrorHandler(func(out io.Writer, err error) {
+		if _, ok := out.(writerFunc); !ok {
+			t.Errorf("error %v reported for %T, want the failing writer", err, out)
+		}
+		failed = append(failed, err)
+	})
+
+	l := NewWriterLogger(w, TRACE)
+	l.SetFlags(Lnologlevel)
+	l.Warn("warned")
+	if err := l.Output(1, "output"); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []*bytes.Buffer{&first, &leveled.Buffer, &last} {
+		if got, want := b.String(), "warned\noutput\n"; got != want {
+			t.Errorf("wrote %q, want %q", got, want)
+		}
+	}
+	if want := []LogLevel{WARN, noLevel}; !reflect.DeepEqual(leveled.levels, want) {
+		t.Errorf("wrote the levels %v, want %v", leveled.levels, want)
+	}
+	if want := []error{errFailed, errFailed}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("reported %v, want %v", failed, want)
+	}
+
+	closer := &closeBuffer{}
+	if err := NewTeeWriter(closer, &first).Close(); err != nil || closer.closes != 1 {
+		t.Fatalf("Close() = %v with %d closes of the destination, want once", err, closer.closes)
+	}
+}
@@ -0,0 +1,113 @@
+package ylog
+
+import (
+	"os"
+	"strings"
+)
+
+// The template variants of the logging methods, e.g.
+//
+//	l.Infot("user {user} purchased {sku}", ylog.Int("user", 42), ylog.String("sku", "A1"))
+//
+// output the message "user 42 purchased A1" with the fields user=42 and sku=A1, so that
+// the text logs read as sentences while the encoders, such as JSONEncoder, keep the
+// values queryable. The placeholders are the keys of the fields in braces, written as in
+// the text format. The placeholders without field are kept as they are; write "{{" and
+// "}}" for literal braces.
+
+func (l *WriterLogger) Fatalt(template string, fields ...Field) {
+	l.outputFields(2, FATAL, renderTemplate(template, fields), fields)
+	os.Exit(1)
+}
+
+func (l *WriterLogger) Infot(template string, fields ...Field) {
+	l.outputFields(2, INFO, renderTemplate(template, fields), fields)
+}
+
+func (l *WriterLogger) Errort(template string, fields ...Field) {
+	if l.lowestLevel() <= ERROR {
+		l.outputFields(2, ERROR, renderTemplate(template, fields), fields)
+	}
+}
+
+func (l *WriterLogger) Warnt(template string, fields ...Field) {
+	if l.lowestLevel() <= WARN {
+		l.outputFields(2, WARN, renderTemplate(template, fields), fields)
+	}
+}
+
+func (m loggerMethods) Fatalt(template string, fields ...Field) {
+	outputTemplate(m.l, 2, FATAL, template, fields)
+	os.Exit(1)
+}
+
+func (m loggerMethods) Infot(template string, fields ...Field) {
+	outputTemplate(m.l, 2, INFO, template, fields)
+}
+
+func (m loggerMethods) Errort(template string, fields ...Field) {
+	if m.l.lowestLevel() <= ERROR {
+		outputTemplate(m.l, 2, ERROR, template, fields)
+	}
+}
+
+func (m loggerMethods) Warnt(template string, fields ...Field) {
+	if m.l.lowestLevel() <= WARN {
+		outputTemplate(m.l, 2, WARN, template, fields)
+	}
+}
+
+// outputTemplate outputs template rendered with fields, and the fields if l supports
+// them, with log level to l, skipdepth is counted as in Output.
+func outputTemplate(l depthLogger, skipdepth int, level LogLevel, template string, fields []Field) error {
+	s := renderTemplate(template, fields)
+	if fl, ok := l.(fieldLogger); ok {
+		return fl.outputFields(skipdepth+1, level, s, fields)
+	}
+	return l.output(skipdepth+1, level, s)
+}
+
+// renderTemplate returns template whose placeholders are replaced by the values of the
+// fields of their keys.
+func renderTemplate(template string, fields []Field) string {
+	if strings.IndexByte(template, '{') < 0 && strings.IndexByte(template, '}') < 0 {
+		return template
+	}
+	buf := make([]byte, 0, len(template)+16*len(fields))
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			// escaped brace
+			buf = append(buf, c)
+			i++
+			continue
+		}
+		if c != '{' {
+			buf = append(buf, c)
+			continue
+		}
+		end := strings.IndexByte(template[i+1:], '}')
+		if end < 0 {
+			buf = append(buf, template[i:]...)
+			break
+		}
+		key := template[i+1 : i+1+end]
+		if f, ok := lookupField(fields, key); ok {
+			buf = appendFieldValue(buf, f.Value)
+		} else {
+			buf = append(buf, template[i:i+2+end]...)
+		}
+		i += 1 + end
+	}
+	return string(buf)
+}
+
+// lookupField returns the last field of fields with key.
+func lookupField(fields []Field, key string) (Field, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == key {
+			return fields[i], true
+		}
+	}
+	return Field{}, false
+}
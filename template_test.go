@@ -0,0 +1,50 @@
+package ylog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	fields := []Field{Int("user", 42), String("sku", "A1")}
+	for template, want := range map[string]string{
+		"user {user} purchased {sku}": "user 42 purchased A1",
+		"no placeholder":              "no placeholder",
+		"{unknown} {user}":            "{unknown} 42",
+		"{{user}} {{{user}}}":         "{user} {42}",
+		"unclosed {user":              "unclosed {user",
+		"":                            "",
+	} {
+		if got := renderTemplate(template, fields); got != want {
+			t.Errorf("renderTemplate(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestWriterLoggerTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, WARN)
+	l.SetFlags(Lloglevel)
+	l.Infot("user {user} purchased {sku}", Int("user", 42), String("sku", "A1"))
+	l.Debugt("user {user} dropped", Int("user", 42))
+	WithStaticFields(l, []Field{{"shop", "eu"}}).(interface {
+		Warnt(template string, fields ...Field)
+	}).Warnt("stock of {sku} low", String("sku", "A1"))
+	l.SetEncoder(JSONEncoder{})
+	l.Infot("user {user} purchased {sku}", Int("user", 42), String("sku", "A1"))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("logged %q, want 3 lines", buf.String())
+	}
+	if got, want := lines[0], "INFO|user=42|sku=A1|user 42 purchased A1"; got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+	if got, want := lines[1], "WARN|shop=eu|sku=A1|stock of A1 low"; got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+	if want := `"message":"user 42 purchased A1","fields":{"user":42,"sku":"A1"}}`; !strings.HasSuffix(lines[2], want) {
+		t.Errorf("encoded %s, want the suffix %s", lines[2], want)
+	}
+}
@@ -0,0 +1,277 @@
+package ylog
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TB is the part of testing.TB used by TestingLogger, so that importing ylog does not
+// import the testing package.
+type TB interface {
+	Helper()
+	Log(args ...interface{})
+	Error(args ...interface{})
+	Fatal(args ...interface{})
+}
+
+// TestingLogger is a Logger forwarding the entries to a test, so that the code under
+// test logs into the test output, reported at the call site of the logging method.
+// Error marks the test failed unless SetFailOnError(false) is called, Fatal stops it.
+//
+// The entries are recorded, so that the tests assert what is logged in one line, e.g.
+//
+//	l.AssertLogged(t, ylog.ERROR, "connection refused")
+type TestingLogger struct {
+	levelHolder // log level
+
+	tb          TB
+	failOnError int32 // non zero to mark the test failed on Error, accessed atomically
+
+	mu      sync.Mutex // protects the following field
+	entries []Entry    // entries logged
+}
+
+// NewTestingLogger returns a TestingLogger forwarding all entries to tb.
+func NewTestingLogger(tb TB) *TestingLogger {
+	l := &TestingLogger{tb: tb, failOnError: 1}
+	l.SetLogLevel(TRACE)
+	return l
+}
+
+// SetFailOnError sets whether Error and Errorf mark the test failed.
+func (l *TestingLogger) SetFailOnError(fail bool) {
+	var v int32
+	if fail {
+		v = 1
+	}
+	atomic.StoreInt32(&l.failOnError, v)
+}
+
+// enabledAt reports whether the entry with log level from the caller of the logging
+// method is output, see SetPackageLevel and SetVModule.
+func (l *TestingLogger) enabledAt(level LogLevel) bool {
+	file, fn := "", ""
+	if len(l.packageLevels()) > 0 || l.vmodule() != nil {
+		if pc, f, _, ok := runtime.Caller(2); ok {
+			file, fn = f, runtime.FuncForPC(pc).Name()
+		}
+	}
+	return l.enabled(level, file, fn)
+}
+
+// output and outputFields log the entries of the loggers wrapping l, e.g. with static
+// fields, as the logging methods. The FATAL entries mark the test failed without
+// stopping it, since the other loggers do not exit on output.
+func (l *TestingLogger) output(skipdepth int, level LogLevel, s string) error {
+	l.tb.Helper()
+	return l.outputFields(skipdepth+1, level, s, nil)
+}
+
+func (l *TestingLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	l.tb.Helper()
+	file, fn := "", ""
+	if len(l.packageLevels()) > 0 || l.vmodule() != nil {
+		if pc, f, _, ok := runtime.Caller(skipdepth + 1); ok {
+			file, fn = f, runtime.FuncForPC(pc).Name()
+		}
+	}
+	if !l.enabled(level, file, fn) {
+		return nil
+	}
+	if level == FATAL {
+		l.tb.Error(l.record(level, s, fields))
+		return nil
+	}
+	l.log(level, s, fields...)
+	return nil
+}
+
+// record records the entry with level, message s and fields, and returns its text.
+func (l *TestingLogger) record(level LogLevel, s string, fields []Field) string {
+	s = strings.TrimSuffix(s, "\n")
+	l.mu.Lock()
+	l.entries = append(l.entries, Entry{Time: time.Now(), Level: level, Message: s, Fields: append([]Field(nil), fields...)})
+	l.mu.Unlock()
+	var b strings.Builder
+	b.WriteString(level.LogLevelName())
+	b.WriteByte('|')
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s=%v|", f.Key, f.Value)
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func (l *TestingLogger) log(level LogLevel, s string, fields ...Field) {
+	l.tb.Helper()
+	s = l.record(level, s, fields)
+	switch {
+	case level == FATAL:
+		l.tb.Fatal(s)
+	case level == ERROR && atomic.LoadInt32(&l.failOnError) != 0:
+		l.tb.Error(s)
+	default:
+		l.tb.Log(s)
+	}
+}
+
+// Entries returns the entries logged so far, oldest first.
+func (l *TestingLogger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// Reset forgets the entries logged so far.
+func (l *TestingLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// logged returns the entries with level whose message contains substr.
+func (l *TestingLogger) logged(level LogLevel, substr string) []Entry {
+	var found []Entry
+	for _, e := range l.Entries() {
+		if e.Level == level && strings.Contains(e.Message, substr) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// AssertLogged marks t failed unless an entry with level whose message contains substr
+// is logged, and reports whether there is one.
+func (l *TestingLogger) AssertLogged(t TB, level LogLevel, substr string) bool {
+	t.Helper()
+	if len(l.logged(level, substr)) > 0 {
+		return true
+	}
+	t.Error(fmt.Sprintf("no %s entry containing %q logged, got:%s", level.LogLevelName(), substr, l.dump()))
+	return false
+}
+
+// AssertNotLogged marks t failed if an entry with level whose message contains substr
+// is logged, and reports whether there is none.
+func (l *TestingLogger) AssertNotLogged(t TB, level LogLevel, substr string) bool {
+	t.Helper()
+	found := l.logged(level, substr)
+	if len(found) == 0 {
+		return true
+	}
+	t.Error(fmt.Sprintf("%s entry containing %q logged: %q", level.LogLevelName(), substr, found[0].Message))
+	return false
+}
+
+// AssertFieldEqual marks t failed unless an entry with a field of key whose value is
+// deeply equal to value is logged, and reports whether there is one.
+func (l *TestingLogger) AssertFieldEqual(t TB, key string, value interface{}) bool {
+	t.Helper()
+	var values []interface{}
+	for _, e := range l.Entries() {
+		for _, f := range e.Fields {
+			if f.Key != key {
+				continue
+			}
+			if reflect.DeepEqual(f.Value, value) {
+				return true
+			}
+			values = append(values, f.Value)
+		}
+	}
+	t.Error(fmt.Sprintf("no field %s=%#v logged, got the values %#v", key, value, values))
+	return false
+}
+
+// dump returns the entries logged so far, one per line.
+func (l *TestingLogger) dump() string {
+	var b strings.Builder
+	for _, e := range l.Entries() {
+		fmt.Fprintf(&b, "\n\t%s|%s", e.Level.LogLevelName(), e.Message)
+	}
+	if b.Len() == 0 {
+		return " nothing"
+	}
+	return b.String()
+}
+
+func (l *TestingLogger) Fatalf(format string, v ...interface{}) {
+	l.tb.Helper()
+	l.log(FATAL, fmt.Sprintf(format, v...))
+}
+
+func (l *TestingLogger) Fatal(v ...interface{}) {
+	l.tb.Helper()
+	l.log(FATAL, fmt.Sprintln(v...))
+}
+
+func (l *TestingLogger) Infof(format string, v ...interface{}) {
+	l.tb.Helper()
+	l.log(INFO, fmt.Sprintf(format, v...))
+}
+
+func (l *TestingLogger) Info(v ...interface{}) {
+	l.tb.Helper()
+	l.log(INFO, fmt.Sprintln(v...))
+}
+
+func (l *TestingLogger) Errorf(format string, v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(ERROR) {
+		l.log(ERROR, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *TestingLogger) Error(v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(ERROR) {
+		l.log(ERROR, fmt.Sprintln(v...))
+	}
+}
+
+func (l *TestingLogger) Warnf(format string, v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(WARN) {
+		l.log(WARN, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *TestingLogger) Warn(v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(WARN) {
+		l.log(WARN, fmt.Sprintln(v...))
+	}
+}
+
+func (l *TestingLogger) Tracef(format string, v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(TRACE) {
+		l.log(TRACE, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *TestingLogger) Trace(v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(TRACE) {
+		l.log(TRACE, fmt.Sprintln(v...))
+	}
+}
+
+func (l *TestingLogger) Debugf(format string, v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(DEBUG) {
+		l.log(DEBUG, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *TestingLogger) Debug(v ...interface{}) {
+	l.tb.Helper()
+	if l.enabledAt(DEBUG) {
+		l.log(DEBUG, fmt.Sprintln(v...))
+	}
+}
@@ -0,0 +1,78 @@
+package ylog
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// recordingTB is a TB recording the calls of its output methods.
+type recordingTB struct {
+	calls []string
+}
+
+func (tb *recordingTB) Helper() {}
+func (tb *recordingTB) Log(args ...interface{}) {
+	tb.calls = append(tb.calls, "Log "+fmt.Sprint(args...))
+}
+func (tb *recordingTB) Error(args ...interface{}) {
+	tb.calls = append(tb.calls, "Error "+fmt.Sprint(args...))
+}
+func (tb *recordingTB) Fatal(args ...interface{}) {
+	tb.calls = append(tb.calls, "Fatal "+fmt.Sprint(args...))
+}
+
+func TestTestingLogger(t *testing.T) {
+	tb := &recordingTB{}
+	l := NewTestingLogger(tb)
+
+	l.Debugf("step %d", 1)
+	l.Error("broken")
+	l.SetFailOnError(false)
+	l.Error("tolerated")
+	l.SetLogLevel(ERROR)
+	l.Warn("dropped")
+	l.SetPackageLevel("github.com/yplusplus/ylog", DEBUG)
+	l.Debug("package")
+	l.Fatal("stop")
+
+	want := []string{"Log DEBUG|step 1", "Error ERROR|broken", "Log ERROR|tolerated", "Log DEBUG|package", "Fatal FATAL|stop"}
+	if !reflect.DeepEqual(tb.calls, want) {
+		t.Fatalf("calls %q, want %q", tb.calls, want)
+	}
+}
+
+func TestTestingLoggerAssertions(t *testing.T) {
+	tb := &recordingTB{}
+	l := NewTestingLogger(tb)
+	l.SetFailOnError(false)
+
+	l.Errorf("dial: %s", "connection refused")
+	WithStaticFields(l, []Field{{"status", 503}, {"path", "/pay"}}).Warn("retrying")
+	if len(l.Entries()) != 2 {
+		t.Fatalf("recorded %d entries, want 2", len(l.Entries()))
+	}
+
+	asserted := tb.calls
+	if !l.AssertLogged(tb, ERROR, "connection refused") || !l.AssertNotLogged(tb, ERROR, "timeout") ||
+		!l.AssertFieldEqual(tb, "status", 503) || len(tb.calls) != len(asserted) {
+		t.Errorf("assertions failed on the entries logged: %q", tb.calls[len(asserted):])
+	}
+
+	if l.AssertLogged(tb, WARN, "refused") || l.AssertNotLogged(tb, WARN, "retry") || l.AssertFieldEqual(tb, "status", "503") {
+		t.Error("assertions passed on the entries not logged")
+	}
+	want := []string{
+		"Error no WARN entry containing \"refused\" logged, got:\n\tERROR|dial: connection refused\n\tWARN|retrying",
+		"Error WARN entry containing \"retry\" logged: \"retrying\"",
+		"Error no field status=\"503\" logged, got the values []interface {}{503}",
+	}
+	if got := tb.calls[len(asserted):]; !reflect.DeepEqual(got, want) {
+		t.Errorf("assertions reported %q, want %q", got, want)
+	}
+
+	l.Reset()
+	if l.AssertLogged(tb, ERROR, "connection refused") {
+		t.Error("AssertLogged passed after Reset")
+	}
+}
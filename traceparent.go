@@ -0,0 +1,70 @@
+package ylog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the trace and parent IDs.
+const TraceparentHeader = "traceparent"
+
+// ParseTraceparent parses a W3C traceparent header value of the form
+//
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+//
+// and returns its trace ID and parent ID. ok is false if s is malformed.
+func ParseTraceparent(s string) (traceID, parentID string, ok bool) {
+	s = strings.TrimSpace(s)
+	// version-traceid-parentid-flags, future versions may append more fields
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return "", "", false
+	}
+	version, traceID, parentID, flags := s[0:2], s[3:35], s[36:52], s[53:55]
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(s) != 55) ||
+		(len(s) > 55 && s[55] != '-') {
+		return "", "", false
+	}
+	if !isLowerHex(traceID) || isAllZero(traceID) ||
+		!isLowerHex(parentID) || isAllZero(parentID) || !isLowerHex(flags) {
+		return "", "", false
+	}
+	return traceID, parentID, true
+}
+
+// WithTraceparent returns a copy of ctx carrying the "trace_id" and "parent_id" values
+// of the traceparent header value, see WithValues. ctx is returned as is if the
+// traceparent is malformed.
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	traceID, parentID, ok := ParseTraceparent(traceparent)
+	if !ok {
+		return ctx
+	}
+	return WithValues(ctx, "trace_id", traceID, "parent_id", parentID)
+}
+
+// TraceparentMiddleware returns an http.Handler which adds the trace and parent IDs
+// of the TraceparentHeader of each request to its context before calling next,
+// see WithTraceparent.
+func TraceparentMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tp := r.Header.Get(TraceparentHeader); tp != "" {
+			r = r.WithContext(WithTraceparent(r.Context(), tp))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAllZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
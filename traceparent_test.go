@@ -0,0 +1,61 @@
+package ylog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		s                 string
+		traceID, parentID string
+		ok                bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", "", "", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", "", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", "", "", false},
+		{"garbage", "", "", false},
+	}
+	for _, tt := range tests {
+		traceID, parentID, ok := ParseTraceparent(tt.s)
+		if traceID != tt.traceID || parentID != tt.parentID || ok != tt.ok {
+			t.Errorf("ParseTraceparent(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.s, traceID, parentID, ok, tt.traceID, tt.parentID, tt.ok)
+		}
+	}
+}
+
+func TestTraceparentMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+	h := RequestIDMiddleware(l, TraceparentMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handled")
+	})))
+
+	for _, tt := range []struct {
+		traceparent string
+		want        string
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"INFO|request_id=abc|trace_id=4bf92f3577b34da6a3ce929d0e0e4736|parent_id=00f067aa0ba902b7|handled\n"},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", "INFO|request_id=abc|handled\n"},
+		{"", "INFO|request_id=abc|handled\n"},
+	} {
+		buf.Reset()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, "abc")
+		if tt.traceparent != "" {
+			req.Header.Set(TraceparentHeader, tt.traceparent)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("traceparent %q: logged %q, want %q", tt.traceparent, got, tt.want)
+		}
+	}
+}
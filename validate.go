@@ -0,0 +1,125 @@
+package ylog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Validator checks an entry against a logging standard, it returns a description of the
+// violation, or "" if e complies, e.g. RequireFields("request_id").
+type Validator func(e *Entry) string
+
+// ValidationAction is the action of an EntryValidator on the entries violating its
+// validators.
+type ValidationAction int
+
+const (
+	ValidationAnnotate ValidationAction = iota // keep the entries with a "violations" field
+	ValidationReject                           // drop the entries, but the FATAL ones
+	ValidationWarn                             // keep the entries and log a WARN entry of the violations
+)
+
+// EntryValidator enforces logging standards mechanically: it runs validators on the
+// entries of a logger before they are encoded, e.g.
+//
+//	v := ylog.NewEntryValidator(l, ylog.ValidationWarn, ylog.RequireMessage(),
+//		ylog.RequireFields("request_id"), ylog.MatchFieldKeys(regexp.MustCompile(`^[a-z_]+$`)))
+//	l.AddInterceptor(v.Intercept)
+//
+// and takes the action on the entries violating them. The violations of an entry are
+// joined by "; " in the "violations" field of the entry, or of the WARN entry logged to
+// the logger of the warnings, along with the "file" and "line" of the caller of the
+// entry, which is told apart by this field and not validated.
+type EntryValidator struct {
+	l          Logger // logger of the warnings
+	action     ValidationAction
+	validators []Validator
+	violations uint64 // number of entries violating the validators, accessed atomically
+}
+
+// NewEntryValidator returns an EntryValidator taking action on the entries violating
+// validators, logging the warnings of ValidationWarn to l.
+func NewEntryValidator(l Logger, action ValidationAction, validators ...Validator) *EntryValidator {
+	return &EntryValidator{l: l, action: action, validators: append([]Validator(nil), validators...)}
+}
+
+// Intercept validates e and takes the action on it: it is an Interceptor, see
+// AddInterceptor.
+func (v *EntryValidator) Intercept(e *Entry) bool {
+	for _, f := range e.Fields {
+		if f.Key == "violations" {
+			return true
+		}
+	}
+	var violations []string
+	for _, validator := range v.validators {
+		if s := validator(e); s != "" {
+			violations = append(violations, s)
+		}
+	}
+	if len(violations) == 0 {
+		return true
+	}
+	atomic.AddUint64(&v.violations, 1)
+	field := Field{"violations", strings.Join(violations, "; ")}
+	switch v.action {
+	case ValidationReject:
+		return false
+	case ValidationWarn:
+		outputFieldsTo(v.l, 1, WARN, "ylog: entry violates the logging standards",
+			[]Field{field, {"file", e.File}, {"line", e.Line}})
+	default:
+		e.Fields = append(e.Fields, field)
+	}
+	return true
+}
+
+// Violations returns the number of entries violating the validators.
+func (v *EntryValidator) Violations() uint64 {
+	return atomic.LoadUint64(&v.violations)
+}
+
+// RequireMessage returns a Validator of the entries with a non empty message.
+func RequireMessage() Validator {
+	return func(e *Entry) string {
+		if strings.TrimSpace(e.Message) == "" {
+			return "empty message"
+		}
+		return ""
+	}
+}
+
+// RequireFields returns a Validator of the entries with fields of all keys.
+func RequireFields(keys ...string) Validator {
+	return func(e *Entry) string {
+		var missing []string
+	next:
+		for _, key := range keys {
+			for _, f := range e.Fields {
+				if f.Key == key {
+					continue next
+				}
+			}
+			missing = append(missing, key)
+		}
+		if len(missing) > 0 {
+			return "missing fields " + strings.Join(missing, ", ")
+		}
+		return ""
+	}
+}
+
+// MatchFieldKeys returns a Validator of the entries whose field keys all match re, e.g.
+// a naming convention as `^[a-z][a-z0-9_]*$`.
+func MatchFieldKeys(re *regexp.Regexp) Validator {
+	return func(e *Entry) string {
+		for _, f := range e.Fields {
+			if !re.MatchString(f.Key) {
+				return fmt.Sprintf("field key %q does not match %s", f.Key, re)
+			}
+		}
+		return ""
+	}
+}
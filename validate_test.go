@@ -0,0 +1,43 @@
+package ylog
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEntryValidator(t *testing.T) {
+	validators := []Validator{RequireMessage(), RequireFields("request_id", "user"), MatchFieldKeys(regexp.MustCompile(`^[a-z_]+$`))}
+	tests := []struct {
+		action ValidationAction
+		want   string
+	}{
+		{ValidationAnnotate, "WARN|request_id=7|user=ann|ok\n" +
+			"WARN|userID=ann|violations=empty message; missing fields request_id, user; field key \"userID\" does not match ^[a-z_]+$| \n"},
+		{ValidationReject, "WARN|request_id=7|user=ann|ok\n"},
+		{ValidationWarn, "WARN|request_id=7|user=ann|ok\n" +
+			"WARN|violations=empty message; missing fields request_id, user; field key \"userID\" does not match ^[a-z_]+$|file=validate_test.go|line=35|ylog: entry violates the logging standards\n" +
+			"WARN|userID=ann| \n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		l := NewWriterLogger(&buf, TRACE)
+		l.SetFlags(Lloglevel)
+		l.AddInterceptor(func(e *Entry) bool {
+			e.File = e.File[strings.LastIndexByte(e.File, '/')+1:]
+			return true
+		})
+		v := NewEntryValidator(l, tt.action, validators...)
+		l.AddInterceptor(v.Intercept)
+
+		WithStaticFields(l, []Field{{"request_id", 7}, {"user", "ann"}}).Warn("ok")
+		WithStaticFields(l, []Field{{"userID", "ann"}}).Warn(" ")
+		if buf.String() != tt.want {
+			t.Errorf("action %d logged %q, want %q", tt.action, buf.String(), tt.want)
+		}
+		if v.Violations() != 1 {
+			t.Errorf("action %d counted %d violations, want 1", tt.action, v.Violations())
+		}
+	}
+}
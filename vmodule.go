@@ -0,0 +1,118 @@
+package ylog
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// vmoduleRule is a pattern of source files and the log level of their entries.
+type vmoduleRule struct {
+	pattern string
+	level   LogLevel
+}
+
+// vmodule is a compiled vmodule spec, see SetVModule.
+type vmodule struct {
+	spec  string
+	rules []vmoduleRule
+	cache sync.Map // file path -> vmoduleLevel
+}
+
+// vmoduleLevel is the log level of a file, ok is false if no pattern matches it.
+type vmoduleLevel struct {
+	level LogLevel
+	ok    bool
+}
+
+// parseVModule compiles the vmodule spec.
+func parseVModule(spec string) (*vmodule, error) {
+	v := &vmodule{spec: spec}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndexByte(item, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("ylog: invalid vmodule %q, want pattern=LEVEL", item)
+		}
+		pattern, name := strings.TrimSuffix(strings.TrimSpace(item[:i]), ".go"), strings.TrimSpace(item[i+1:])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("ylog: invalid vmodule pattern %q: %v", pattern, err)
+		}
+		level, ok := LogLevelMap[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("ylog: unknown log level %q", name)
+		}
+		v.rules = append(v.rules, vmoduleRule{pattern: pattern, level: level})
+	}
+	return v, nil
+}
+
+// levelFor returns the log level of the entries logged from file, ok is false if no
+// pattern matches it.
+func (v *vmodule) levelFor(file string) (LogLevel, bool) {
+	if l, ok := v.cache.Load(file); ok {
+		return l.(vmoduleLevel).level, l.(vmoduleLevel).ok
+	}
+	var l vmoduleLevel
+	name := strings.TrimSuffix(file, ".go")
+	for _, rule := range v.rules {
+		// match as many trailing path elements as the pattern has
+		s := name
+		for i, n := len(name), strings.Count(rule.pattern, "/"); i >= 0; i-- {
+			if i == 0 || name[i-1] == '/' {
+				s = name[i:]
+				if n == 0 {
+					break
+				}
+				n--
+			}
+		}
+		if ok, _ := path.Match(rule.pattern, s); ok {
+			l = vmoduleLevel{rule.level, true}
+			break
+		}
+	}
+	v.cache.Store(file, l)
+	return l.level, l.ok
+}
+
+// SetVModule sets the log levels for the entries logged from the source files matching
+// patterns, overriding the package levels and the log level of the logger, e.g.
+// "handler=TRACE,db/*=DEBUG". The spec is a comma separated list of "pattern=LEVEL"
+// whose patterns, in the syntax of path.Match, are matched against as many trailing
+// path elements of the file paths, without the ".go" extension, as they have: "handler"
+// matches ".../server/handler.go" and "db/*" the files of the db directories. The first
+// matching pattern wins. Give "" to remove them. The spec is compiled before being
+// swapped with the previous one, the entries being output are filtered by either.
+func (h *levelHolder) SetVModule(spec string) error {
+	v, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.vmodules.Store(v)
+	h.updateLowest()
+	return nil
+}
+
+// VModule returns the vmodule spec set by SetVModule.
+func (h *levelHolder) VModule() string {
+	if v := h.vmodule(); v != nil {
+		return v.spec
+	}
+	return ""
+}
+
+// vmodule returns the compiled vmodule spec, nil if there is none.
+func (h *levelHolder) vmodule() *vmodule {
+	v, _ := h.vmodules.Load().(*vmodule)
+	if v == nil || len(v.rules) == 0 {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,70 @@
+package ylog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetVModule(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, WARN)
+	l.SetFlags(Lshortfile | Lloglevel)
+	if err := l.SetVModule("vmodule_test=TRACE,ylog/writer_logger=DEBUG"); err != nil {
+		t.Fatal(err)
+	}
+	if l.lowestLevel() != TRACE || l.VModule() != "vmodule_test=TRACE,ylog/writer_logger=DEBUG" {
+		t.Fatalf("lowest level %v, vmodule %q", l.lowestLevel().LogLevelName(), l.VModule())
+	}
+	l.Trace("tracing")
+	if want := "vmodule_test.go:21|TRACE|tracing\n"; buf.String() != want {
+		t.Fatalf("logged %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := l.SetVModule("vmodule*=ERROR"); err != nil {
+		t.Fatal(err)
+	}
+	l.SetPackageLevel("github.com/yplusplus/ylog", TRACE)
+	l.Warn("dropped")
+	if buf.Len() != 0 {
+		t.Fatalf("logged %q, want the vmodule to override the package level", buf.String())
+	}
+
+	for _, spec := range []string{"vmodule_test", "=DEBUG", "vmodule_test=VERBOSE", "[=DEBUG"} {
+		if err := l.SetVModule(spec); err == nil {
+			t.Errorf("SetVModule(%q) succeeded, want an error", spec)
+		}
+	}
+	if l.VModule() != "vmodule*=ERROR" {
+		t.Fatalf("vmodule %q after invalid specs, want it kept", l.VModule())
+	}
+	l.SetVModule("")
+	if l.VModule() != "" || l.lowestLevel() != TRACE {
+		t.Fatalf("vmodule %q, lowest level %v after removal", l.VModule(), l.lowestLevel().LogLevelName())
+	}
+}
+
+func TestVModuleLevelFor(t *testing.T) {
+	v, err := parseVModule("handler=TRACE, db/*=DEBUG, */cmd/main.go=ERROR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		file  string
+		level LogLevel
+		ok    bool
+	}{
+		{"/src/app/server/handler.go", TRACE, true},
+		{"/src/app/db/conn.go", DEBUG, true},
+		{"/src/app/db/sub/conn.go", 0, false},
+		{"/src/app/cmd/main.go", ERROR, true},
+		{"handler_test.go", 0, false},
+	} {
+		if level, ok := v.levelFor(tc.file); level != tc.level || ok != tc.ok {
+			t.Errorf("levelFor(%q) = %v, %v, want %v, %v", tc.file, level, ok, tc.level, tc.ok)
+		}
+	}
+}
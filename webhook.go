@@ -0,0 +1,106 @@
+package ylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultWebhookCooldown is the duration the alerts of a fingerprint are dropped by a
+// WebhookSender after one is posted, by default.
+const DefaultWebhookCooldown = 10 * time.Minute
+
+// WebhookTemplate returns the JSON body posted to a webhook for an alert.
+type WebhookTemplate func(a *Alert) ([]byte, error)
+
+// WebhookSender is an AlertSender posting the alerts to a webhook, e.g. an incoming
+// webhook of Slack or Mattermost, so that the small teams see the fatal conditions at
+// once. After an alert is posted, the others of its fingerprint are dropped for a
+// cool-down.
+type WebhookSender struct {
+	URL      string          // URL of the webhook
+	Template WebhookTemplate // body of the alerts, JSONWebhook if nil
+	Cooldown time.Duration   // cool-down of the fingerprints, DefaultWebhookCooldown if zero
+	Client   *http.Client    // client of the requests, http.DefaultClient if nil
+
+	cooldown alertCooldown
+}
+
+// SendAlert posts a to the webhook, or returns ErrRateLimited.
+func (s *WebhookSender) SendAlert(a *Alert) error {
+	d := s.Cooldown
+	if d <= 0 {
+		d = DefaultWebhookCooldown
+	}
+	if !s.cooldown.allow(a.Fingerprint, time.Now(), d) {
+		return ErrRateLimited
+	}
+	template := s.Template
+	if template == nil {
+		template = JSONWebhook
+	}
+	b, err := template(a)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(s.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("ylog: webhook: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// JSONWebhook is the WebhookTemplate of the generic webhooks, posting the fingerprint
+// and the JSON form of the entry of the alerts, see WebSocketHandler, e.g.
+//
+//	{"fingerprint":"8c1f...","entry":{"time":"...","level":"ERROR",...}}
+func JSONWebhook(a *Alert) ([]byte, error) {
+	e, err := marshalEntry(&a.Entry)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Fingerprint string          `json:"fingerprint"`
+		Entry       json.RawMessage `json:"entry"`
+	}{a.Fingerprint, e})
+}
+
+// SlackWebhook is the WebhookTemplate of the incoming webhooks of Slack, posting the
+// alerts as messages such as
+//
+//	*ERROR* payment failed
+//	`main.charge` main.go:42 order=42
+func SlackWebhook(a *Alert) ([]byte, error) {
+	return json.Marshal(map[string]string{"text": alertText(a, "*")})
+}
+
+// MattermostWebhook is the WebhookTemplate of the incoming webhooks of Mattermost,
+// posting the alerts as SlackWebhook in the Markdown of Mattermost.
+func MattermostWebhook(a *Alert) ([]byte, error) {
+	return json.Marshal(map[string]string{"text": alertText(a, "**"), "username": "ylog"})
+}
+
+// alertText returns the text of the messages of a, whose log level is emphasized by bold.
+func alertText(a *Alert, bold string) string {
+	var b strings.Builder
+	b.WriteString(bold + a.Level.LogLevelName() + bold + " " + a.Message + "\n")
+	b.WriteString("`" + a.Func + "` " + a.File[strings.LastIndexByte(a.File, '/')+1:] + fmt.Sprintf(":%d", a.Line))
+	for _, f := range a.Fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	return b.String()
+}
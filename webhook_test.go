@@ -0,0 +1,49 @@
+package ylog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookSender(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+
+	s := &WebhookSender{URL: srv.URL, Template: SlackWebhook}
+	a := &Alert{
+		Entry:       Entry{Level: FATAL, File: "/src/main.go", Line: 42, Func: "main.charge", Message: "payment failed", Fields: []Field{{"order", 42}}},
+		Fingerprint: "payments",
+	}
+	if err := s.SendAlert(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendAlert(a); err != ErrRateLimited {
+		t.Fatalf("sent in the cool-down: %v", err)
+	}
+	a.Fingerprint = "orders"
+	s.Template = nil
+	if err := s.SendAlert(a); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("posted %d alerts, want 2", len(bodies))
+	}
+	if want := `{"text":"*FATAL* payment failed\n` + "`main.charge`" + ` main.go:42 order=42"}`; bodies[0] != want {
+		t.Fatalf("posted %s, want %s", bodies[0], want)
+	}
+	var generic struct {
+		Fingerprint string
+		Entry       struct{ Message string }
+	}
+	if err := json.Unmarshal([]byte(bodies[1]), &generic); err != nil || generic.Fingerprint != "orders" || generic.Entry.Message != "payment failed" {
+		t.Fatalf("posted %s: %v", bodies[1], err)
+	}
+}
@@ -0,0 +1,281 @@
+package ylog
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes, see RFC 6455
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xa
+)
+
+// wsMaxPayload is the max size of the messages accepted from the clients.
+const wsMaxPayload = 64 * 1024
+
+// wsWriteTimeout is the max duration of the write of a frame, the connection is closed
+// if the client does not read it in time.
+var wsWriteTimeout = 10 * time.Second
+
+// wsGUID is appended to the client key to make the accept key of the handshake.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketHandler returns an http.Handler streaming the entries of l to WebSocket
+// clients, one JSON text message per entry such as
+//
+//	{"time":"2024-01-02T15:04:05.123456+08:00","level":"WARN","file":"/src/app/main.go","line":42,"func":"main.main","message":"disk almost full"}
+//
+// The client chooses the streamed entries with the "level" and "match" query parameters,
+// the lowest log level and a regular expression matching the message, and may change
+// them at any time by sending a text message such as {"level":"DEBUG","match":"order"}.
+//
+// Only the handshakes without an Origin header or from the same origin are accepted, so
+// that other web sites cannot read the entries from the browsers of the users, see
+// WebSocketHandlerWithOrigin.
+func WebSocketHandler(l Subscribable) http.Handler {
+	return WebSocketHandlerWithOrigin(l, nil)
+}
+
+// WebSocketHandlerWithOrigin is like WebSocketHandler but accepts the handshakes for which
+// checkOrigin returns true. Give nil to accept the same origin only.
+func WebSocketHandlerWithOrigin(l Subscribable, checkOrigin func(r *http.Request) bool) http.Handler {
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseStreamFilter(r.FormValue("level"), r.FormValue("match"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !checkOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		conn, err := wsUpgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		entries, cancel := l.Subscribe()
+		defer cancel()
+
+		var (
+			mu     sync.Mutex // protects filter
+			closed = make(chan struct{})
+		)
+		// read the filters and control frames sent by the client
+		go func() {
+			defer close(closed)
+			for {
+				op, payload, err := conn.readFrame()
+				if err != nil {
+					return
+				}
+				switch op {
+				case wsOpText:
+					var req struct {
+						Level string `json:"level"`
+						Match string `json:"match"`
+					}
+					if json.Unmarshal(payload, &req) != nil {
+						continue
+					}
+					if f, err := parseStreamFilter(req.Level, req.Match); err == nil {
+						mu.Lock()
+						filter = f
+						mu.Unlock()
+					}
+				case wsOpPing:
+					conn.writeFrame(wsOpPong, payload)
+				case wsOpClose:
+					conn.writeFrame(wsOpClose, nil)
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case e, ok := <-entries:
+				if !ok {
+					return
+				}
+				mu.Lock()
+				f := filter
+				mu.Unlock()
+				if !f.allow(&e) {
+					continue
+				}
+				b, err := marshalEntry(&e)
+				if err != nil {
+					continue
+				}
+				if conn.writeFrame(wsOpText, b) != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// sameOrigin reports whether the request has no Origin header or one matching its host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// wsConn is a server side WebSocket connection.
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+
+	mu sync.Mutex // serializes the writes
+}
+
+// wsUpgrade performs the server side WebSocket handshake.
+func wsUpgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != "GET" || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "websocket handshake expected", http.StatusBadRequest)
+		return nil, errors.New("not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("http.ResponseWriter is not an http.Hijacker")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// headerContains reports whether the comma separated values of the header contain token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readFrame reads a frame sent by the client, which must be masked.
+// Fragmented messages are not supported and fail.
+func (c *wsConn) readFrame() (op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.rw, head[:]); err != nil {
+		return
+	}
+	op = head[0] & 0x0f
+	if head[0]&0x80 == 0 || op == 0 {
+		return 0, nil, errors.New("fragmented client frame")
+	}
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked client frame")
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.rw, b[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.rw, b[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if n > wsMaxPayload {
+		return 0, nil, errors.New("client frame too large")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.rw, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.rw, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
+
+// writeFrame writes an unfragmented, unmasked frame within wsWriteTimeout.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+
+	head := make([]byte, 2, 10)
+	head[0] = 0x80 | op
+	switch n := len(payload); {
+	case n < 126:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = append(head, byte(n>>8), byte(n))
+	default:
+		head[1] = 127
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(n))
+		head = append(head, b[:]...)
+	}
+	c.rw.Write(head)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// Close closes the underlying connection.
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
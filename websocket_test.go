@@ -0,0 +1,177 @@
+package ylog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// wsClientFrame returns a masked text frame carrying payload.
+func wsClientFrame(payload string) []byte {
+	mask := []byte{1, 2, 3, 4}
+	b := []byte{0x80 | wsOpText, 0x80 | byte(len(payload))}
+	b = append(b, mask...)
+	for i := 0; i < len(payload); i++ {
+		b = append(b, payload[i]^mask[i%4])
+	}
+	return b
+}
+
+// wsReadMessage reads an unmasked server frame.
+func wsReadMessage(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		io.ReadFull(r, b[:])
+		n = int(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		io.ReadFull(r, b[:])
+		n = int(binary.BigEndian.Uint64(b[:]))
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(r, p); err != nil {
+		t.Fatal(err)
+	}
+	return head[0] & 0x0f, p
+}
+
+// wsDial sends a WebSocket handshake for path to the server at url.
+func wsDial(t *testing.T, url, path, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET "+path+" HTTP/1.1\r\n"+
+		"Host: localhost\r\n"+
+		"Origin: "+origin+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		conn.Close()
+		t.Fatal(err)
+	}
+	return conn, r, resp
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	srv := httptest.NewServer(WebSocketHandler(l))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET returned %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	conn, r, resp := wsDial(t, srv.URL, "/?level=warn", "http://localhost")
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake returned %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	// the example of RFC 6455
+	if got, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Fatalf("Sec-WebSocket-Accept is %q, want %q", got, want)
+	}
+
+	// wait for the subscription
+	for atomic.LoadInt32(&l.subscribers.n) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Debug("cache miss")
+	l.Warn("disk almost full")
+	op, p := wsReadMessage(t, r)
+	var e struct {
+		Level   string `json:"level"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(p, &e); err != nil {
+		t.Fatal(err)
+	}
+	if op != wsOpText || e.Level != "WARN" || e.Message != "disk almost full" {
+		t.Fatalf("received opcode %d message %s, want the WARN entry", op, p)
+	}
+
+	conn.Write(wsClientFrame(`{"level":"DEBUG","match":"^order"}`))
+	// the filter is updated asynchronously, log until it is
+	for paid := false; !paid; {
+		l.Debug("cache miss")
+		l.Debug("order 42 paid")
+		l.Warn("order 43 shipped")
+		for {
+			_, p = wsReadMessage(t, r)
+			if strings.Contains(string(p), "cache miss") {
+				t.Fatalf("received %s, want only the entries matching the filter", p)
+			}
+			paid = paid || strings.Contains(string(p), "order 42 paid")
+			if strings.Contains(string(p), "order 43 shipped") {
+				break
+			}
+		}
+	}
+}
+
+func TestWebSocketHandlerOrigin(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	srv := httptest.NewServer(WebSocketHandler(l))
+	defer srv.Close()
+
+	conn, _, resp := wsDial(t, srv.URL, "/", "http://evil.example")
+	conn.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross origin handshake returned %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	allowed := httptest.NewServer(WebSocketHandlerWithOrigin(l, func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "http://evil.example"
+	}))
+	defer allowed.Close()
+	conn, _, resp = wsDial(t, allowed.URL, "/", "http://evil.example")
+	conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("allowed origin handshake returned %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+}
+
+func TestWebSocketHandlerFragmented(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	srv := httptest.NewServer(WebSocketHandler(l))
+	defer srv.Close()
+
+	conn, r, _ := wsDial(t, srv.URL, "/", "")
+	defer conn.Close()
+	frame := wsClientFrame(`{"level":"DEBUG"}`)
+	frame[0] &^= 0x80 // not the final fragment
+	conn.Write(frame)
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Fatalf("read %v after a fragmented frame, want the connection closed", err)
+	}
+}
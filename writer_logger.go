@@ -1,10 +1,13 @@
 package ylog
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,142 +15,412 @@ import (
 
 // WriterLogger outputs the log to an io.Writer
 type WriterLogger struct {
-	level LogLevel // log level
+	levelHolder              // log level
+	filters                  // entry filters
+	subscribers              // entry subscribers
+	clock       atomic.Value // clockHolder, source of the time of the entries
+	callerEvery int32        // resolve the callers once in callerEvery entries, see SetCallerSampling, accessed atomically
+	callers     sync.Map     // program counter -> *callerInfo, callers resolved by caller sampling
+	flags       int32        // flags of the text format, accessed atomically
 
-	mu    sync.Mutex // ensures atomic writes; protects the following fields
-	buf   []byte     // buffer
-	out   io.Writer  // destination for output
-	flags int        // properties
+	mu         sync.Mutex // ensures atomic writes; protects the following fields
+	buf        []byte     // buffer
+	bufSize    int        // initial size of buf
+	maxBufSize int        // max size of buf kept for the next entry
+	out        io.Writer  // destination for output
+	format     textFormat // text format of the entries, but for its flags
+	enc        Encoder    // encoder of the entries, nil for the text format of flags
+	stats      entryStats // entry counters
+	seq        uint64     // sequence number of the last entry
+	closed     bool       // whether Close was called
 }
 
+// NewWriterLogger returns a logger writing the entries from level to out, which it
+// closes with itself if it is an io.WriteCloser, see Close.
 func NewWriterLogger(out io.Writer, level LogLevel) *WriterLogger {
-	return &WriterLogger{out: out, level: level, flags: LdefaultFlags}
+	l := &WriterLogger{out: out, flags: LdefaultFlags, bufSize: DEFAULT_BUFFER_SIZE, maxBufSize: DEFAULT_BUFFER_SIZE}
+	l.SetLogLevel(level)
+	l.SetClock(SystemClock)
+	return l
+}
+
+// clockHolder holds a Clock in an atomic.Value, which requires a consistent concrete type.
+type clockHolder struct {
+	Clock
+}
+
+// SetClock sets the source of the time of the entries.
+func (l *WriterLogger) SetClock(clock Clock) {
+	l.clock.Store(clockHolder{clock})
 }
 
 // Output outputs content to log file
 func (l *WriterLogger) Output(skipdepth int, s string) error {
+	return l.output(skipdepth+1, noLevel, s)
+}
+
+// output outputs content with log level to log file
+func (l *WriterLogger) output(skipdepth int, level LogLevel, s string) error {
+	return l.outputFields(skipdepth+1, level, s, nil)
+}
+
+// OutputContext is like Output for content with log level, but waits to queue it to an
+// AsyncWriter under backpressure until ctx is done, in which case it is dropped, see
+// AsyncWriter.WriteLevelContext, so that the callers on a request deadline are not
+// stalled. The other writers do not wait for ctx.
+func (l *WriterLogger) OutputContext(ctx context.Context, skipdepth int, level LogLevel, s string) error {
+	return l.outputContext(ctx, skipdepth+1, level, s, nil)
+}
+
+// outputFields outputs content with log level and fields to log file
+func (l *WriterLogger) outputFields(skipdepth int, level LogLevel, s string, fields []Field) error {
+	return l.outputContext(context.Background(), skipdepth+1, level, s, fields)
+}
+
+// outputContext outputs content with log level and fields to log file, waiting to queue
+// it to an AsyncWriter until ctx is done.
+func (l *WriterLogger) outputContext(ctx context.Context, skipdepth int, level LogLevel, s string, fields []Field) error {
+	return l.outputMessage(ctx, skipdepth+1, level, s, nil, fields)
+}
+
+// outputln outputs the operands of v with log level to log file, formatted as by
+// fmt.Sprintln. They are appended to the buffer of the entry, without the copy of the
+// message, unless the filters, the subscribers, the encoder or the separator read it.
+func (l *WriterLogger) outputln(skipdepth int, level LogLevel, v []interface{}) error {
+	if l.readMessage() {
+		return l.output(skipdepth+1, level, fmt.Sprintln(v...))
+	}
+	if v == nil {
+		// outputMessage tells the operands apart from the message by v != nil
+		v = []interface{}{}
+	}
+	return l.outputMessage(context.Background(), skipdepth+1, level, "", v, nil)
+}
+
+// OutputEntry outputs e as built by the caller, e.g. an adapter of another logging
+// library, a code generator or a replay tool, with its own time, caller and fields. The
+// zero Time is replaced by the current time, and an empty File by the caller of
+// OutputEntry. e goes through the filters and the interceptors as the other entries,
+// and is numbered in their sequence, its Seq is ignored. It never exits, even for FATAL
+// entries.
+func (l *WriterLogger) OutputEntry(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = l.clock.Load().(clockHolder).Now()
+	}
+	if e.Level != FATAL && !l.allowMessage(e.Message) {
+		return nil
+	}
+	if e.File == "" {
+		e.File, e.Line, e.Func = l.caller(2)
+	}
+	// the interceptors append to a copy of the fields
+	e.Fields = e.Fields[:len(e.Fields):len(e.Fields)]
+	return l.outputEntry(context.Background(), e, nil)
+}
+
+// outputMessage outputs the message s, or the operands of v formatted as by fmt.Sprintln
+// if v is not nil, with log level and fields to log file, waiting to queue it to an
+// AsyncWriter until ctx is done.
+func (l *WriterLogger) outputMessage(ctx context.Context, skipdepth int, level LogLevel, s string, v []interface{}, fields []Field) error {
 	// get time early
-	now := time.Now()
-
-	var file string
-	var line int
-	var fn string
-	pc, file, line, ok := runtime.Caller(skipdepth)
-	if !ok {
-		file = "????"
-		line = 0
-		fn = "unknown"
-	} else {
-		f := runtime.FuncForPC(pc)
-		fn = f.Name()
+	now := l.clock.Load().(clockHolder).Now()
+
+	if level != FATAL && !l.allowMessage(s) {
+		return nil
 	}
 
+	// the interceptors append to a copy of the fields
+	e := Entry{Time: now, Level: level, Message: strings.TrimSuffix(s, "\n"), Fields: fields[:len(fields):len(fields)]}
+	e.File, e.Line, e.Func = l.caller(skipdepth + 1)
+	return l.outputEntry(ctx, e, v)
+}
+
+// outputEntry outputs e, whose message is the operands of v formatted as by fmt.Sprintln
+// if v is not nil, to log file once the filters of its caller and the interceptors let
+// it, waiting to queue it to an AsyncWriter until ctx is done.
+func (l *WriterLogger) outputEntry(ctx context.Context, e Entry, v []interface{}) error {
+	if !l.enabled(e.Level, e.File, e.Func) || (e.Level != FATAL && !l.allowCaller(e.File, e.Func)) {
+		return nil
+	}
+	e, ok := l.intercept(e)
+	if !ok || !l.enabled(e.Level, e.File, e.Func) {
+		return nil
+	}
+	start := time.Now()
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	// number and publish in the order of the writes
+	l.seq++
+	e.Seq = l.seq
+	direct := v != nil && l.enc == nil && l.format.sep == 0 && atomic.LoadInt32(&l.subscribers.n) == 0
+	if v != nil && !direct {
+		e.Message = strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+	}
+	l.publish(&e)
 
-	if l.buf == nil || cap(l.buf) > DEFAULT_BUFFER_SIZE {
-		l.buf = make([]byte, 0, DEFAULT_BUFFER_SIZE)
+	if l.buf == nil || cap(l.buf) > l.maxBufSize {
+		l.buf = make([]byte, 0, l.bufSize)
 	} else {
 		l.buf = l.buf[:0]
 	}
 
-	formatHeader(&l.buf, l.flags, now, file, line, fn)
-	l.buf = append(l.buf, s...)
-	if len(s) == 0 || s[len(s)-1] != '\n' {
-		l.buf = append(l.buf, '\n')
+	l.format.flags = int(atomic.LoadInt32(&l.flags))
+	switch {
+	case direct:
+		// the newline of fmt.Appendln ends the entry
+		formatHeader(&l.buf, &l.format, e.Time, e.Seq, e.File, e.Line, e.Func, e.Level)
+		l.buf = fmt.Appendln(l.buf, v...)
+	case l.enc != nil:
+		l.buf = l.enc.Encode(l.buf, &e)
+	default:
+		formatEntry(&l.buf, &l.format, &e)
 	}
 
-	_, err := l.out.Write(l.buf)
+	var (
+		err     error
+		written <-chan struct{} // closed once a non droppable entry is written
+		full    *AsyncWriter    // AsyncWriter whose queue is full
+		pending []byte          // entry waiting for room in the queue of full
+	)
+	switch w := l.out.(type) {
+	case *AsyncWriter:
+		// wait for the write without holding the lock
+		var queued bool
+		if written, queued, err = w.queueLevel(ctx, e.Level, l.buf, false); !queued {
+			full, pending = w, append([]byte(nil), l.buf...)
+		}
+	case LevelWriter:
+		_, err = w.WriteLevel(e.Level, l.buf)
+	default:
+		_, err = l.out.Write(l.buf)
+	}
+	l.stats.record(e.Time, e.Level)
+	l.stats.recordLatency(time.Since(start))
+	l.mu.Unlock()
 
+	if full != nil {
+		// wait for room without holding the lock either, so that the other callers do
+		// not wait for ctx, but for their own; the entries of the callers waiting
+		// together may be written out of their sequence
+		written, _, err = full.queueLevel(ctx, e.Level, pending, true)
+	}
+	if written != nil {
+		<-written
+	}
 	return err
 }
 
-// SetLogLevel sets log level for the logger
-func (l *WriterLogger) SetLogLevel(level LogLevel) {
-	atomic.StoreInt32((*int32)(&l.level), int32(level))
+// callerInfo is a caller resolved by caller sampling.
+type callerInfo struct {
+	file string
+	line int
+	fn   string
+	hits uint32 // number of entries from the caller, accessed atomically
+}
+
+// caller returns the file name, line number and function name of the caller skipdepth
+// frames up, counted as in runtime.Caller.
+func (l *WriterLogger) caller(skipdepth int) (file string, line int, fn string) {
+	n := atomic.LoadInt32(&l.callerEvery)
+	if n <= 1 {
+		pc, file, line, ok := runtime.Caller(skipdepth)
+		if !ok {
+			return "????", 0, "unknown"
+		}
+		return file, line, runtime.FuncForPC(pc).Name()
+	}
+
+	var pcs [1]uintptr
+	if runtime.Callers(skipdepth+1, pcs[:]) == 0 {
+		return "????", 0, "unknown"
+	}
+	if v, ok := l.callers.Load(pcs[0]); ok {
+		c := v.(*callerInfo)
+		if atomic.AddUint32(&c.hits, 1)%uint32(n) != 0 {
+			return c.file, c.line, c.fn
+		}
+	}
+	f, _ := runtime.CallersFrames(pcs[:]).Next()
+	l.callers.Store(pcs[0], &callerInfo{file: f.File, line: f.Line, fn: f.Function})
+	return f.File, f.Line, f.Function
+}
+
+// SetCallerSampling sets the logger to resolve the file name, line number and function
+// name of the callers only once in n entries from each call site, reusing the ones
+// resolved last for the others, which saves most of the cost of the caller information
+// while keeping it correct but for the call sites sharing a program counter, such as the
+// inlined ones. Give 0 or 1 to resolve them for each entry.
+func (l *WriterLogger) SetCallerSampling(n int) {
+	atomic.StoreInt32(&l.callerEvery, int32(n))
 }
 
-// LogLevel returns log level for the logger
-func (l *WriterLogger) LogLevel() LogLevel {
-	return LogLevel(atomic.LoadInt32((*int32)(&l.level)))
+// Writer returns the destination for output of the logger
+func (l *WriterLogger) Writer() io.Writer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.out
+}
+
+// SetOutput sets the destination for output of the logger.
+// It is safe to call concurrently with logging, the entries being output go entirely
+// either to the previous or the new destination.
+func (l *WriterLogger) SetOutput(out io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = out
+}
+
+// Flush flushes the destination for output if it buffers, such as a bufio.Writer.
+func (l *WriterLogger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if f, ok := l.out.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close flushes the destination for output, then closes it if it is an io.Closer, such as
+// an os.File, a RotateWriter or a GzipWriter, so that the logger owns the destination
+// given to NewWriterLogger or SetOutput. os.Stdout and os.Stderr are not closed, being
+// shared by the program. More calls have no effect.
+func (l *WriterLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	var err error
+	if f, ok := l.out.(Flusher); ok {
+		err = f.Flush()
+	}
+	if c, ok := l.out.(io.Closer); ok && !isStdStream(l.out) {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// isStdStream reports whether w is os.Stdout or os.Stderr.
+func isStdStream(w io.Writer) bool {
+	return w == io.Writer(os.Stdout) || w == io.Writer(os.Stderr)
+}
+
+// Drain drains the destination for output if it supports it, such as AsyncWriter,
+// otherwise flushes it. It returns early with ctx.Err() if ctx is done.
+func (l *WriterLogger) Drain(ctx context.Context) error {
+	out := l.Writer()
+	if d, ok := out.(interface {
+		Drain(ctx context.Context) error
+	}); ok {
+		return d.Drain(ctx)
+	}
+	return l.Flush()
+}
+
+// SetBufferSize sets the initial size of the buffer formatting the entries, and the max
+// size it may grow to while still being kept for the next entries, DEFAULT_BUFFER_SIZE
+// for both by default. Raising them saves the allocations for large entries.
+// Give non positive sizes to keep the defaults.
+func (l *WriterLogger) SetBufferSize(size, maxSize int) {
+	if size <= 0 {
+		size = DEFAULT_BUFFER_SIZE
+	}
+	if maxSize <= 0 {
+		maxSize = DEFAULT_BUFFER_SIZE
+	}
+	if maxSize < size {
+		maxSize = size
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bufSize, l.maxBufSize = size, maxSize
+	l.buf = nil
 }
 
 // Flags returns the flags for the logger
 func (l *WriterLogger) Flags() int {
+	return int(atomic.LoadInt32(&l.flags))
+}
+
+// Flags sets the flags for the logger.
+// They are stored atomically, the readers and the entries being output do not wait for
+// the writes, and take either the previous or the new flags.
+func (l *WriterLogger) SetFlags(flags int) {
+	atomic.StoreInt32(&l.flags, int32(flags))
+}
+
+// SetCallerRoot sets the root of the file names written by Lshortfile, e.g. the module
+// path "github.com/us/app" or the directory of its sources, so that they are relative to
+// it, such as "pkg/server/handler.go", rather than reduced to their final element, which
+// is ambiguous across packages. The files out of root keep their final element only.
+// Give "" to restore it for all the files; Llongfile writes the full file names.
+func (l *WriterLogger) SetCallerRoot(root string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.flags
+	// the file names of the callers have forward slashes on Windows too
+	l.format.root = filepath.ToSlash(root)
 }
 
-// Flags sets the flags for the logger
-func (l *WriterLogger) SetFlags(flags int) {
+// SetSeparator sets the separator of the header properties, the fields and the message,
+// e.g. '\t', ' ' or ',' for the tools splitting on them, instead of '|'. Once set, even
+// to '|', the separator and '\\' are escaped with '\\' in the file and function names,
+// the fields and the messages, so that the entries split unambiguously. With ' ', the
+// date and the time of Ldate with Ltime are two properties. Give 0 to restore '|'
+// without escaping.
+func (l *WriterLogger) SetSeparator(sep byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format.sep = sep
+}
+
+// SetEncoder sets the encoder of the entries, which replaces the text format of the
+// flags. Give nil to restore it.
+func (l *WriterLogger) SetEncoder(enc Encoder) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.flags = flags
+	l.enc = enc
 }
 
 func (l *WriterLogger) Fatalf(format string, v ...interface{}) {
-	l.Output(2, "FATAL|"+fmt.Sprintf(format, v...))
+	l.output(2, FATAL, fmt.Sprintf(format, v...))
 	os.Exit(1)
 }
 
 func (l *WriterLogger) Fatal(v ...interface{}) {
-	l.Output(2, "FATAL|"+fmt.Sprintln(v...))
+	l.outputln(2, FATAL, v)
 	os.Exit(1)
 }
 
 func (l *WriterLogger) Infof(format string, v ...interface{}) {
-	l.Output(2, "INFO|"+fmt.Sprintf(format, v...))
+	l.output(2, INFO, fmt.Sprintf(format, v...))
 }
 
 func (l *WriterLogger) Info(v ...interface{}) {
-	l.Output(2, "INFO|"+fmt.Sprintln(v...))
+	l.outputln(2, INFO, v)
 }
 
 func (l *WriterLogger) Errorf(format string, v ...interface{}) {
-	if l.LogLevel() <= ERROR {
-		l.Output(2, "ERROR|"+fmt.Sprintf(format, v...))
+	if l.lowestLevel() <= ERROR {
+		l.output(2, ERROR, fmt.Sprintf(format, v...))
 	}
 }
 
 func (l *WriterLogger) Error(v ...interface{}) {
-	if l.LogLevel() <= ERROR {
-		l.Output(2, "ERROR|"+fmt.Sprintln(v...))
+	if l.lowestLevel() <= ERROR {
+		l.outputln(2, ERROR, v)
 	}
 }
 
 func (l *WriterLogger) Warnf(format string, v ...interface{}) {
-	if l.LogLevel() <= WARN {
-		l.Output(2, "WARN|"+fmt.Sprintf(format, v...))
+	if l.lowestLevel() <= WARN {
+		l.output(2, WARN, fmt.Sprintf(format, v...))
 	}
 }
 
 func (l *WriterLogger) Warn(v ...interface{}) {
-	if l.LogLevel() <= WARN {
-		l.Output(2, "WARN|"+fmt.Sprintln(v...))
-	}
-}
-
-func (l *WriterLogger) Tracef(format string, v ...interface{}) {
-	if l.LogLevel() <= TRACE {
-		l.Output(2, "TRACE|"+fmt.Sprintf(format, v...))
-	}
-}
-
-func (l *WriterLogger) Trace(v ...interface{}) {
-	if l.LogLevel() <= TRACE {
-		l.Output(2, "TRACE|"+fmt.Sprintln(v...))
-	}
-}
-
-func (l *WriterLogger) Debugf(format string, v ...interface{}) {
-	if l.LogLevel() <= DEBUG {
-		l.Output(2, "DEBUG|"+fmt.Sprintf(format, v...))
-	}
-}
-
-func (l *WriterLogger) Debug(v ...interface{}) {
-	if l.LogLevel() <= DEBUG {
-		l.Output(2, "DEBUG|"+fmt.Sprintln(v...))
+	if l.lowestLevel() <= WARN {
+		l.outputln(2, WARN, v)
 	}
 }
@@ -0,0 +1,537 @@
+package ylog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterLoggerMessageFilter(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lloglevel)
+
+	if err := l.SetMessageFilter("", "deprecated|noisy"); err != nil {
+		t.Fatal(err)
+	}
+	l.Warn("api is deprecated")
+	l.Warn("disk almost full")
+	if err := l.SetMessageFilter("^order", ""); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("order 42 paid")
+	l.Info("user 7 logged in")
+	// as Fatal, without exiting
+	l.output(1, FATAL, "disk failed\n")
+	if err := l.SetMessageFilter("(", ""); err == nil {
+		t.Fatal("SetMessageFilter accepted an invalid regular expression")
+	}
+
+	want := "WARN|disk almost full\nINFO|order 42 paid\nFATAL|disk failed\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+	if include, exclude := l.MessageFilter(); include != "^order" || exclude != "" {
+		t.Fatalf("MessageFilter() = %q, %q", include, exclude)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		path, pattern string
+		want          bool
+	}{
+		{"github.com/us/app/pkg/server", "pkg/server", true},
+		{"github.com/us/app/pkg/server", "github.com/us/app", true},
+		{"/src/app/pkg/server/handler.go", "pkg/server", true},
+		{"/src/app/pkg/server/handler.go", "server/handler.go", true},
+		{"github.com/us/app/pkg/servers", "pkg/server", false},
+		{"github.com/us/app/xpkg/server", "pkg/server", false},
+		{"github.com/us/app", "", false},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestWriterLoggerCallerFilter(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lloglevel)
+
+	l.SetCallerFilter(nil, []string{"github.com/yplusplus/ylog"})
+	l.Info("dropped")
+	l.SetCallerFilter([]string{"writer_logger_test.go"}, nil)
+	l.Info("kept")
+	l.SetCallerFilter([]string{"vendor/foo"}, nil)
+	l.Info("dropped")
+	// as Fatal, without exiting
+	l.output(1, FATAL, "disk failed\n")
+
+	if got, want := pkgPath("github.com/us/app/pkg/server.(*Handler).ServeHTTP"), "github.com/us/app/pkg/server"; got != want {
+		t.Errorf("pkgPath() = %q, want %q", got, want)
+	}
+	if got, want := buf.String(), "INFO|kept\nFATAL|disk failed\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerInterceptor(t *testing.T) {
+	if DebugStripped {
+		t.Skip("the Debug and Trace methods are stripped")
+	}
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, DEBUG)
+	l.SetFlags(Lloglevel)
+
+	l.AddInterceptor(func(e *Entry) bool {
+		e.Fields = append(e.Fields, Field{"region", "eu"})
+		return true
+	})
+	l.AddInterceptor(func(e *Entry) bool {
+		switch e.Message {
+		case "secret", "fatal secret":
+			return false
+		case "fatal noisy":
+			e.Level = TRACE
+		case "noisy":
+			e.Level = TRACE
+		case "slow":
+			e.Level = WARN
+		}
+		return true
+	})
+	l.Info("secret")
+	l.Error("noisy")
+	l.Debug("slow")
+	// as Fatal, without exiting
+	l.output(1, FATAL, "fatal secret\n")
+	l.output(1, FATAL, "fatal noisy\n")
+	l.ClearInterceptors()
+	l.Info("plain")
+
+	want := "WARN|region=eu|slow\nFATAL|region=eu|fatal secret\nFATAL|region=eu|fatal noisy\nINFO|plain\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerSetOutput(t *testing.T) {
+	var first, second bytes.Buffer
+	l := NewWriterLogger(&first, TRACE)
+	l.SetFlags(Lloglevel)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			l.Info("entry")
+		}
+	}()
+	l.SetOutput(&second)
+	<-done
+
+	if l.Writer() != &second {
+		t.Fatal("Writer() is not the destination set by SetOutput")
+	}
+	if n := bytes.Count(first.Bytes(), []byte("INFO|entry\n")) + bytes.Count(second.Bytes(), []byte("INFO|entry\n")); n != 100 {
+		t.Fatalf("%d entries output, want 100", n)
+	}
+}
+
+func TestWriterLoggerSubscribe(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, DEBUG)
+
+	entries, cancel := l.Subscribe()
+	l.Trace("dropped")
+	l.Warnf("disk %d%% full", 90)
+	cancel()
+	cancel()
+	l.Info("unobserved")
+
+	e, ok := <-entries
+	if !ok || e.Level != WARN || e.Message != "disk 90% full" || e.File == "" || e.Time.IsZero() {
+		t.Fatalf("received %+v, %v", e, ok)
+	}
+	if e, ok := <-entries; ok {
+		t.Fatalf("received %+v after cancel", e)
+	}
+}
+
+func TestWriterLoggerSubscriberDropped(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, DEBUG)
+	entries, cancel := l.Subscribe()
+	defer cancel()
+
+	for i := 0; i < SubscriberBufferSize+3; i++ {
+		l.Info("entry", i)
+	}
+	if n := l.SubscriberDropped(); n != 3 {
+		t.Fatalf("SubscriberDropped() = %d, want 3", n)
+	}
+	for i := 0; i < SubscriberBufferSize; i++ {
+		if e := <-entries; e.Message != fmt.Sprintf("entry %d", i) {
+			t.Fatalf("received %q as entry %d", e.Message, i)
+		}
+	}
+}
+
+func TestWriterLoggerStats(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)}
+	l := NewWriterLogger(ioutil.Discard, DEBUG)
+	l.SetClock(clock)
+
+	for i := 0; i < 60; i++ {
+		l.Warn("slow query")
+		l.Trace("dropped")
+		clock.Add(time.Second)
+	}
+	for i := 0; i < 10; i++ {
+		l.Error("timeout")
+		l.Error("timeout")
+		clock.Add(time.Second)
+	}
+	l.Output(1, "raw")
+
+	st := l.Stats()
+	if want := map[string]uint64{"WARN": 60, "ERROR": 20, "OUTPUT": 1}; !reflect.DeepEqual(st.Entries, want) {
+		t.Fatalf("entries %v, want %v", st.Entries, want)
+	}
+	// the last 10s hold 2 errors per second and the raw entry, the last minute also 49 warnings
+	if r := st.Rates["10s"]; r["ERROR"] != 1.8 || r["OUTPUT"] != 0.1 || r["WARN"] != 0 {
+		t.Fatalf("10s rates %v", r)
+	}
+	if r := st.Rates["1m"]; r["ERROR"] != 20.0/60 || r["WARN"] != 49.0/60 {
+		t.Fatalf("1m rates %v", r)
+	}
+	if r := st.Rates["5m"]; r["WARN"] != 60.0/300 {
+		t.Fatalf("5m rates %v", r)
+	}
+
+	var n uint64
+	for _, c := range st.Latency.Counts {
+		n += c
+	}
+	if st.Latency.Count != 81 || n != 81 || st.Latency.Quantile(0.99) <= 0 {
+		t.Fatalf("latency %+v", st.Latency)
+	}
+
+	clock.Add(time.Hour)
+	if st = l.Stats(); len(st.Rates["5m"]) != 0 || st.Entries["WARN"] != 60 {
+		t.Fatalf("stats %+v an hour later", st)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := Histogram{
+		Bounds: []time.Duration{time.Millisecond, 10 * time.Millisecond},
+		Counts: []uint64{90, 9, 1},
+		Count:  100,
+	}
+	for _, tt := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.5, time.Millisecond},
+		{0.95, 10 * time.Millisecond},
+		{0.999, 10 * time.Millisecond},
+	} {
+		if got := h.Quantile(tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestWriterLoggerNoLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(0)
+	l.Warn("with level")
+	l.SetFlags(Lnologlevel)
+	l.Warn("without level")
+
+	if got, want := buf.String(), "WARN|with level\nwithout level\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerBufferSize(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	l.SetBufferSize(64, 1024)
+	l.Info("small")
+	if c := cap(l.buf); c != 64 {
+		t.Fatalf("buffer size %d, want 64", c)
+	}
+	l.Info(strings.Repeat("x", 512))
+	kept := cap(l.buf)
+	l.Info("small")
+	if c := cap(l.buf); c != kept || c <= 512 {
+		t.Fatalf("buffer size %d after a large entry, want %d kept", c, kept)
+	}
+	l.Info(strings.Repeat("x", 2048))
+	l.Info("small")
+	if c := cap(l.buf); c != 64 {
+		t.Fatalf("buffer size %d after a too large entry, want 64", c)
+	}
+}
+
+func TestWriterLoggerCallerRoot(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lshortfile)
+	_, file, line, _ := runtime.Caller(0)
+	dir := path.Dir(file)
+	l.SetCallerRoot(path.Dir(dir))
+	l.Info("relative")
+	l.SetCallerRoot("github.com/elsewhere")
+	l.Info("short")
+
+	want := fmt.Sprintf("%s/writer_logger_test.go:%d|INFO|relative\nwriter_logger_test.go:%d|INFO|short\n", path.Base(dir), line+3, line+5)
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct{ file, root, want string }{
+		{"/src/app/pkg/server/handler.go", "app", "pkg/server/handler.go"},
+		{"/src/app/pkg/server/handler.go", "/src/app/", "pkg/server/handler.go"},
+		{"/src/app/pkg/app/handler.go", "app", "handler.go"},
+		{"/src/myapp/handler.go", "app", ""},
+		{"/src/app/handler.go", "", ""},
+	} {
+		if got := relFile(tc.file, tc.root); got != tc.want {
+			t.Errorf("relFile(%q, %q) = %q, want %q", tc.file, tc.root, got, tc.want)
+		}
+	}
+}
+
+func TestShortFuncName(t *testing.T) {
+	for _, tc := range []struct{ fn, want string }{
+		{"github.com/org/repo/pkg/server.(*Handler).ServeHTTP", "Handler.ServeHTTP"},
+		{"github.com/org/repo/pkg/server.Handler.ServeHTTP.func1", "Handler.ServeHTTP.func1"},
+		{"main.main", "main"},
+		{"unknown", "unknown"},
+	} {
+		if got := shortFuncName(tc.fn); got != tc.want {
+			t.Errorf("shortFuncName(%q) = %q, want %q", tc.fn, got, tc.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lfuncname | Lshortfunc)
+	l.Info("short")
+	if got, want := buf.String(), "TestShortFuncName|INFO|short\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerSequence(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lsequence | Lloglevel)
+	l.AddInterceptor(func(e *Entry) bool {
+		return e.Message != "dropped"
+	})
+	l.Info("first")
+	l.Debug("dropped")
+	l.Output(1, "raw")
+	l.Warn("last")
+	if got, want := buf.String(), "1|INFO|first\n2|raw\n3|WARN|last\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerPid(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lpid | Lsequence)
+	l.Info("started")
+	if got, want := buf.String(), fmt.Sprintf("%d|1|INFO|started\n", os.Getpid()); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerHostname(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lhostname | Lpid)
+	l.Info("started")
+	name, _ := os.Hostname()
+	if got, want := buf.String(), fmt.Sprintf("%s|%d|INFO|started\n", name, os.Getpid()); got != want && name != "" {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerSeparator(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lsequence)
+	l.SetSeparator('\t')
+	l.Infof("a\tb|c\\d")
+	l.SetSeparator(0)
+	l.Infof("a\tb|c\\d")
+	if got, want := buf.String(), "1\tINFO\ta\\\tb|c\\\\d\n2|INFO|a\tb|c\\d\n"; got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerSeparatorHeader(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Ldate | Ltime | Llongfile)
+	l.SetSeparator(' ')
+	l.OutputEntry(Entry{Time: time.Date(2023, 6, 7, 8, 9, 10, 0, time.Local), Level: INFO, File: "/my app/main.go", Line: 42, Message: "started"})
+	l.SetFlags(Ldate | Llongfile)
+	l.OutputEntry(Entry{Time: time.Date(2023, 6, 7, 8, 9, 10, 0, time.Local), Level: INFO, File: "/my app/main.go", Line: 42, Message: "started"})
+	want := "20230607 08:09:10 /my\\ app/main.go:42 INFO started\n20230607 /my\\ app/main.go:42 INFO started\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerCallerSampling(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lshortfile | Lshortfunc)
+	l.SetCallerSampling(10)
+	_, _, line, _ := runtime.Caller(0)
+	for i := 0; i < 25; i++ {
+		l.Info("sampled")
+	}
+	l.Info("other")
+	want := strings.Repeat(fmt.Sprintf("writer_logger_test.go:%d|TestWriterLoggerCallerSampling|INFO|sampled\n", line+2), 25) +
+		fmt.Sprintf("writer_logger_test.go:%d|TestWriterLoggerCallerSampling|INFO|other\n", line+4)
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
+
+func TestWriterLoggerOperands(t *testing.T) {
+	args := [][]interface{}{nil, {"a"}, {"a", 1, "b"}, {1, 2}, {"x\n"}, {"a|b"}}
+	modes := map[string]func(l *WriterLogger){
+		"direct":     func(l *WriterLogger) {},
+		"separator":  func(l *WriterLogger) { l.SetSeparator('\t') },
+		"encoder":    func(l *WriterLogger) { l.SetEncoder(messageEncoder{}) },
+		"subscriber": func(l *WriterLogger) { l.Subscribe() },
+		"filter":     func(l *WriterLogger) { l.AddInterceptor(func(e *Entry) bool { return true }) },
+	}
+	for name, setup := range modes {
+		var got, want bytes.Buffer
+		l := NewWriterLogger(&got, TRACE)
+		l.SetFlags(Lsequence)
+		l.SetClock(&fakeClock{now: time.Unix(0, 0)})
+		setup(l)
+		for _, v := range args {
+			l.Info(v...)
+		}
+		l.SetOutput(&want)
+		l.seq = 0
+		for _, v := range args {
+			l.Infof("%s", fmt.Sprintln(v...))
+		}
+		if got.String() != want.String() {
+			t.Errorf("%s: logged %q, want %q", name, got.String(), want.String())
+		}
+	}
+}
+
+func TestWriterLoggerOperandsAllocs(t *testing.T) {
+	l := NewWriterLogger(ioutil.Discard, TRACE)
+	l.SetFlags(Lsequence)
+	v := []interface{}{"request", "served"}
+	// the operands cost no more than a message formatted beforehand
+	want := testing.AllocsPerRun(100, func() { l.Output(1, "request served") })
+	if n := testing.AllocsPerRun(100, func() { l.Info(v...) }); n > want {
+		t.Errorf("Info allocates %v times, want %v", n, want)
+	}
+}
+
+// messageEncoder is an Encoder of the messages of the entries only.
+type messageEncoder struct{}
+
+func (messageEncoder) Encode(buf []byte, e *Entry) []byte {
+	buf = append(buf, e.Message...)
+	return append(buf, '\n')
+}
+
+func TestWriterLoggerFlagsDuringWrite(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	l := NewWriterLogger(writerFunc(func(p []byte) (int, error) {
+		close(entered)
+		<-release
+		return len(p), nil
+	}), TRACE)
+	go l.Info("blocked")
+	<-entered
+	defer close(release)
+
+	// the flags are neither read nor set under the lock of the write
+	done := make(chan struct{})
+	go func() {
+		l.SetFlags(Lsequence)
+		if got := l.Flags(); got != Lsequence {
+			t.Errorf("Flags() = %d, want %d", got, Lsequence)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flags waited for the write")
+	}
+}
+
+func TestWriterLoggerClose(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotateWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewWriterLogger(w, TRACE)
+	l.Info("entry")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.f != nil {
+		t.Fatalf("log file %s still open after Close", w.f.Name())
+	}
+
+	// the standard streams stay open
+	l = NewWriterLogger(os.Stderr, TRACE)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		t.Fatalf("stderr closed: %v", err)
+	}
+}
+
+func TestWriterLoggerOutputEntry(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, ERROR)
+	l.SetFlags(Ldate | Ltime | Lshortfile | Lshortfunc | Lsequence | Lloglevel)
+	l.SetClock(&fakeClock{now: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)})
+
+	// replayed
+	l.OutputEntry(Entry{Time: time.Date(2023, 6, 7, 8, 9, 10, 0, time.Local), Level: WARN, Message: "dropped"})
+	l.OutputEntry(Entry{Time: time.Date(2023, 6, 7, 8, 9, 10, 0, time.Local), Level: ERROR, File: "/src/app/main.go", Line: 42,
+		Func: "main.charge", Message: "replayed", Fields: []Field{{"order", 7}}, Seq: 99})
+	_, _, line, _ := runtime.Caller(0)
+	OutputEntry(l, Entry{Level: INFO, Message: "built"})
+
+	want := "20230607 08:09:10|1|main.go:42|charge|ERROR|order=7|replayed\n" +
+		fmt.Sprintf("20240102 15:04:05|2|writer_logger_test.go:%d|TestWriterLoggerOutputEntry|INFO|built\n", line+1)
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %q, want %q", got, want)
+	}
+}
@@ -1 +1,64 @@
 package ylog
+
+import (
+	"os"
+)
+
+// std is the logger used by the package level helpers.
+var std Logger = NewWriterLogger(os.Stderr, TRACE)
+
+// DefaultLogger returns the logger used by the package level helpers.
+func DefaultLogger() Logger {
+	return std
+}
+
+// SetDefaultLogger sets the logger used by the package level helpers.
+// It is not safe to call concurrently with logging, call it during initialization.
+func SetDefaultLogger(l Logger) {
+	std = l
+}
+
+// CheckErr logs err at ERROR level, prefixed by context, if err is not nil.
+// It reports whether err is not nil.
+func CheckErr(err error, context string) bool {
+	if err == nil {
+		return false
+	}
+	logErr(ERROR, err, context)
+	return true
+}
+
+// WarnOnErr logs err at WARN level, prefixed by context, if err is not nil.
+// It reports whether err is not nil.
+func WarnOnErr(err error, context string) bool {
+	if err == nil {
+		return false
+	}
+	logErr(WARN, err, context)
+	return true
+}
+
+// Must logs err at FATAL level and exits if err is not nil.
+func Must(err error) {
+	if err == nil {
+		return
+	}
+	logErr(FATAL, err, "")
+}
+
+// logErr outputs err on behalf of the caller of the exported helpers.
+func logErr(level LogLevel, err error, context string) {
+	s := err.Error()
+	if context != "" {
+		s = context + ": " + s
+	}
+
+	if level < INFO && logLevelOf(std) > level {
+		return
+	}
+	// skip logErr and the exported helper
+	outputTo(std, 3, level, s)
+	if level == FATAL {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCheckErr(t *testing.T) {
+	var buf bytes.Buffer
+	old := DefaultLogger()
+	defer SetDefaultLogger(old)
+	l := NewWriterLogger(&buf, TRACE)
+	l.SetFlags(Lshortfile)
+	SetDefaultLogger(l)
+
+	if CheckErr(nil, "open") {
+		t.Fatal("CheckErr(nil) returned true")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("CheckErr(nil) logged %q", buf.String())
+	}
+
+	if !CheckErr(errors.New("no such file"), "open") {
+		t.Fatal("CheckErr(err) returned false")
+	}
+	got := buf.String()
+	if !strings.HasPrefix(got, "ylog_test.go:") || !strings.HasSuffix(got, "|ERROR|open: no such file\n") {
+		t.Fatalf("CheckErr(err) logged %q", got)
+	}
+
+	buf.Reset()
+	l.SetLogLevel(ERROR)
+	if !WarnOnErr(errors.New("retrying"), "") {
+		t.Fatal("WarnOnErr(err) returned false")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("WarnOnErr below log level logged %q", buf.String())
+	}
+}
+
+func TestLogStartup(t *testing.T) {
+	old := DefaultLogger()
+	defer SetDefaultLogger(old)
+	l := NewWriterLogger(ioutil.Discard, ERROR)
+	entries, cancel := l.Subscribe()
+	defer cancel()
+	SetDefaultLogger(l)
+
+	LogStartup()
+	e := <-entries
+	fields := make(map[string]interface{})
+	for _, f := range e.Fields {
+		fields[f.Key] = f.Value
+	}
+	if !strings.HasSuffix(e.File, "ylog_test.go") || e.Message != "startup" || fields["pid"] != pid || fields["host"] != hostname {
+		t.Fatalf("logged %+v", e)
+	}
+	if config, _ := fields["config"].(string); !strings.Contains(config, `"log-level":"ERROR"`) {
+		t.Fatalf("logged the config %q", config)
+	}
+}